@@ -8,6 +8,13 @@ import (
 
 type arrayContainer struct {
 	content []uint16
+
+	// the number of bitmaps sharing the container, beyond the first
+	shares int32 `msg:"-"`
+}
+
+func (ac *arrayContainer) sharesCount() *int32 {
+	return &ac.shares
 }
 
 func (ac *arrayContainer) String() string {
@@ -28,6 +35,33 @@ func (ac *arrayContainer) getShortIterator() shortIterable {
 	return &shortIterator{ac.content, 0}
 }
 
+func (ac *arrayContainer) getReverseIterator() reverseShortIterable {
+	return &reverseIterator{ac.content, len(ac.content) - 1}
+}
+
+func (ac *arrayContainer) iterate(hs uint32, cb func(x uint32) bool) bool {
+	for _, v := range ac.content {
+		if !cb(hs | uint32(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (ac *arrayContainer) iterateRuns(cb func(start, last uint16) bool) bool {
+	for i := 0; i < len(ac.content); {
+		j := i
+		for j+1 < len(ac.content) && ac.content[j+1] == ac.content[j]+1 {
+			j++
+		}
+		if !cb(ac.content[i], ac.content[j]) {
+			return false
+		}
+		i = j + 1
+	}
+	return true
+}
+
 func (ac *arrayContainer) minimum() uint16 {
 	return ac.content[0] // assume not empty
 }
@@ -285,7 +319,7 @@ func (ac *arrayContainer) iremove(x uint16) bool {
 }
 
 func (ac *arrayContainer) remove(x uint16) container {
-	out := &arrayContainer{make([]uint16, len(ac.content))}
+	out := &arrayContainer{content: make([]uint16, len(ac.content))}
 	copy(out.content, ac.content[:])
 
 	loc := binarySearch(out.content, x)
@@ -355,11 +389,8 @@ func (ac *arrayContainer) iorBitmap(bc2 *bitmapContainer) container {
 }
 
 func (ac *arrayContainer) iorRun16(rc *runContainer16) container {
-	bc1 := ac.toBitmapContainer()
-	bc2 := rc.toBitmapContainer()
-	bc1.iorBitmap(bc2)
-	*ac = *newArrayContainerFromBitmap(bc1)
-	return ac
+	// the union may not fit in an array container, it is returned in the best form
+	return newRunContainer16TakeOwnership(rc.orArrayRuns(ac)).toEfficientContainer()
 }
 
 func (ac *arrayContainer) lazyIOR(a container) container {
@@ -523,10 +554,18 @@ func (ac *arrayContainer) iand(a container) container {
 }
 
 func (ac *arrayContainer) iandRun16(rc *runContainer16) container {
-	bc1 := ac.toBitmapContainer()
-	bc2 := newBitmapContainerFromRun(rc)
-	bc2.iandBitmap(bc1)
-	*ac = *newArrayContainerFromBitmap(bc2)
+	pos, rlepos := 0, 0
+	for _, v := range ac.content {
+		rlepos = rc.advanceUntilRun(rlepos, v)
+		if rlepos == len(rc.iv) {
+			break
+		}
+		if v >= rc.iv[rlepos].start {
+			ac.content[pos] = v
+			pos++
+		}
+	}
+	ac.content = ac.content[:pos]
 	return ac
 }
 
@@ -598,9 +637,15 @@ func (ac *arrayContainer) andNot(a container) container {
 }
 
 func (ac *arrayContainer) andNotRun16(rc *runContainer16) container {
-	acb := ac.toBitmapContainer()
-	rcb := rc.toBitmapContainer()
-	return acb.andNotBitmap(rcb)
+	answer := newArrayContainerCapacity(len(ac.content))
+	rlepos := 0
+	for _, v := range ac.content {
+		rlepos = rc.advanceUntilRun(rlepos, v)
+		if rlepos == len(rc.iv) || v < rc.iv[rlepos].start {
+			answer.content = append(answer.content, v)
+		}
+	}
+	return answer
 }
 
 func (ac *arrayContainer) iandNot(a container) container {
@@ -616,10 +661,15 @@ func (ac *arrayContainer) iandNot(a container) container {
 }
 
 func (ac *arrayContainer) iandNotRun16(rc *runContainer16) container {
-	rcb := rc.toBitmapContainer()
-	acb := ac.toBitmapContainer()
-	acb.iandNotBitmapSurely(rcb)
-	*ac = *(acb.toArrayContainer())
+	pos, rlepos := 0, 0
+	for _, v := range ac.content {
+		rlepos = rc.advanceUntilRun(rlepos, v)
+		if rlepos == len(rc.iv) || v < rc.iv[rlepos].start {
+			ac.content[pos] = v
+			pos++
+		}
+	}
+	ac.content = ac.content[:pos]
 	return ac
 }
 
@@ -837,7 +887,7 @@ func (ac *arrayContainer) selectInt(x uint16) int {
 }
 
 func (ac *arrayContainer) clone() container {
-	ptr := arrayContainer{make([]uint16, len(ac.content))}
+	ptr := arrayContainer{content: make([]uint16, len(ac.content))}
 	copy(ptr.content, ac.content[:])
 	return &ptr
 }
@@ -261,7 +261,7 @@ func TestArrayContainerIaddRangeNearMax068(t *testing.T) {
 
 	Convey("arrayContainer iaddRange should work near MaxUint16", t, func() {
 
-		iv := []interval16{{65525, 65527}, {65530, 65530}, {65534, 65535}}
+		iv := []interval16{newInterval16Range(65525, 65527), newInterval16Range(65530, 65530), newInterval16Range(65534, 65535)}
 		rc := newRunContainer16TakeOwnership(iv)
 
 		ac2 := rc.toArrayContainer()
@@ -283,7 +283,7 @@ func TestArrayContainerEtc070(t *testing.T) {
 
 	Convey("arrayContainer rarely exercised code paths should get some coverage", t, func() {
 
-		iv := []interval16{{65525, 65527}, {65530, 65530}, {65534, 65535}}
+		iv := []interval16{newInterval16Range(65525, 65527), newInterval16Range(65530, 65530), newInterval16Range(65534, 65535)}
 		rc := newRunContainer16TakeOwnership(iv)
 		ac := rc.toArrayContainer()
 
@@ -349,3 +349,32 @@ func TestArrayContainerEtc070(t *testing.T) {
 		So(ac10.numberOfRuns(), ShouldEqual, 1)
 	})
 }
+
+func TestArrayContainerIorRun16(t *testing.T) {
+
+	Convey("arrayContainer iorRun16 should not hold more values than an array container may", t, func() {
+		ac := newArrayContainer()
+		for i := 0; i < 100; i++ {
+			ac.iadd(uint16(3 * i))
+		}
+		rc := newRunContainer16Range(1000, 10999)
+
+		union := ac.iorRun16(rc)
+		So(union.getCardinality(), ShouldEqual, 10100)
+		if a, ok := union.(*arrayContainer); ok {
+			So(len(a.content), ShouldBeLessThanOrEqualTo, arrayDefaultMaxSize)
+		}
+		So(union.contains(297), ShouldBeTrue)
+		So(union.contains(298), ShouldBeFalse)
+		So(union.contains(10999), ShouldBeTrue)
+
+		// a small union stays an array
+		ac = newArrayContainer()
+		ac.iadd(1)
+		ac.iadd(5)
+		union = ac.iorRun16(newRunContainer16Range(2, 3))
+		_, isArray := union.(*arrayContainer)
+		So(isArray, ShouldBeTrue)
+		So(union.getCardinality(), ShouldEqual, 4)
+	})
+}
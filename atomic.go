@@ -0,0 +1,57 @@
+package roaring
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicBitmap holds the current version of a bitmap that is replaced
+// as a whole, while many goroutines read it. Readers Load the current
+// version without locking, and go on reading it even after a newer one
+// has been published; a version no reader holds any more is reclaimed
+// by the garbage collector. Writers build a new version with Update, or
+// publish one built on their own with Store.
+//
+// The zero value holds an empty version.
+type AtomicBitmap struct {
+	current atomic.Value // *PersistentBitmap
+	// mu serializes the writers, so that no update is lost
+	mu sync.Mutex
+}
+
+// NewAtomicBitmap returns a holder whose current version has the values of rb
+func NewAtomicBitmap(rb *Bitmap) *AtomicBitmap {
+	ab := &AtomicBitmap{}
+	ab.current.Store(NewPersistentBitmap(rb))
+	return ab
+}
+
+// Load returns the current version
+func (ab *AtomicBitmap) Load() *PersistentBitmap {
+	if pb, ok := ab.current.Load().(*PersistentBitmap); ok {
+		return pb
+	}
+	return persist(NewBitmap())
+}
+
+// Store publishes pb as the current version
+func (ab *AtomicBitmap) Store(pb *PersistentBitmap) {
+	ab.mu.Lock()
+	ab.current.Store(pb)
+	ab.mu.Unlock()
+}
+
+// Update calls delta on a copy of the current version, and publishes
+// the result as the new current version, which it returns. The copy
+// shares the containers of the current version, copying only those
+// that delta modifies. Calls to Update and Store are serialized, while
+// readers go on loading the previous version until Update returns.
+func (ab *AtomicBitmap) Update(delta func(rb *Bitmap)) *PersistentBitmap {
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	rb := ab.Load().ToBitmap()
+	delta(rb)
+	pb := persist(rb)
+	ab.current.Store(pb)
+	return pb
+}
@@ -0,0 +1,68 @@
+package roaring
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAtomicBitmap(t *testing.T) {
+
+	Convey("updates should publish new versions and leave the old ones unchanged", t, func() {
+		var empty AtomicBitmap
+		So(empty.Load().IsEmpty(), ShouldBeTrue)
+
+		ab := NewAtomicBitmap(iteratorTestBitmap())
+		v0 := ab.Load()
+		v1 := ab.Update(func(rb *Bitmap) {
+			rb.Add(17)
+			rb.RemoveRange(0, 4)
+		})
+		So(ab.Load(), ShouldEqual, v1)
+		So(v0.ToBitmap().Equals(iteratorTestBitmap()), ShouldBeTrue)
+		So(v1.Contains(17), ShouldBeTrue)
+		So(v1.Contains(3), ShouldBeFalse)
+		So(v0.Contains(3), ShouldBeTrue)
+
+		ab.Store(v0)
+		So(ab.Load(), ShouldEqual, v0)
+	})
+}
+
+func TestConcurrentAtomicBitmap(t *testing.T) {
+	ab := NewAtomicBitmap(NewBitmap())
+	const versions = 200
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// version i holds [0, 1000*i) and nothing else
+				pb := ab.Load()
+				card := pb.GetCardinality()
+				if card%1000 != 0 || (card > 0 && (!pb.Contains(uint32(card-1)) || pb.Contains(uint32(card)))) {
+					t.Errorf("inconsistent version of cardinality %d", card)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= versions; i++ {
+		ab.Update(func(rb *Bitmap) {
+			rb.AddRange(uint64(1000*(i-1)), uint64(1000*i))
+		})
+	}
+	close(done)
+	wg.Wait()
+	if card := ab.Load().GetCardinality(); card != 1000*versions {
+		t.Errorf("got cardinality %d, expected %d", card, 1000*versions)
+	}
+}
@@ -305,6 +305,28 @@ func BenchmarkIterateRoaring(b *testing.B) {
 	}
 }
 
+// go test -bench BenchmarkIterate -run -
+func BenchmarkIterateManyRoaring(b *testing.B) {
+	b.StopTimer()
+	r := rand.New(rand.NewSource(0))
+	s := NewBitmap()
+	sz := 150000
+	initsize := 65000
+	for i := 0; i < initsize; i++ {
+		s.Add(uint32(r.Int31n(int32(sz))))
+	}
+	buf := make([]uint32, 256)
+	i := s.ManyIterator()
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		c9 = uint(0)
+		i.Reset(s)
+		for n := i.NextMany(buf); n > 0; n = i.NextMany(buf) {
+			c9 += uint(n)
+		}
+	}
+}
+
 // go test -bench BenchmarkSparseIterate -run -
 func BenchmarkSparseIterateRoaring(b *testing.B) {
 	b.StopTimer()
@@ -533,3 +555,175 @@ func BenchmarkXorLopsided(b *testing.B) {
 		s.Clone().Xor(x2)
 	}
 }
+
+// runHeavyBitmaps returns a bitmap made of run containers, and bitmaps
+// made of array and of bitmap containers with the same keys
+func runHeavyBitmaps() (runs, arrays, bitmaps *Bitmap) {
+	r := rand.New(rand.NewSource(0))
+	runs, arrays, bitmaps = NewBitmap(), NewBitmap(), NewBitmap()
+	for k := uint64(0); k < 64; k++ {
+		for start := k<<16 + uint64(r.Intn(500)); start < (k+1)<<16-1000; start += uint64(1000 + r.Intn(2000)) {
+			runs.AddRange(start, start+uint64(r.Intn(1000)))
+		}
+		for i := 0; i < 1000; i++ {
+			arrays.Add(uint32(k<<16) + uint32(r.Intn(1<<16)))
+		}
+		for i := 0; i < 20000; i++ {
+			bitmaps.Add(uint32(k<<16) + uint32(r.Intn(1<<16)))
+		}
+	}
+	runs.RunOptimize()
+	return runs, arrays, bitmaps
+}
+
+// go test -bench BenchmarkRunHeavy -benchmem -run -
+func BenchmarkRunHeavyArray(b *testing.B) {
+	runs, arrays, _ := runHeavyBitmaps()
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		And(runs, arrays)
+		Or(runs, arrays)
+		Xor(runs, arrays)
+		AndNot(runs, arrays)
+		runs.AndCardinality(arrays)
+	}
+}
+
+func BenchmarkRunHeavyBitmap(b *testing.B) {
+	runs, _, bitmaps := runHeavyBitmaps()
+	b.ResetTimer()
+	for j := 0; j < b.N; j++ {
+		And(runs, bitmaps)
+		Or(runs, bitmaps)
+		Xor(runs, bitmaps)
+		AndNot(runs, bitmaps)
+		runs.AndCardinality(bitmaps)
+	}
+}
+
+// go test -bench BenchmarkSelect -run -
+func BenchmarkSelectRoaring(b *testing.B) {
+	b.StopTimer()
+	s := NewBitmap()
+	for i := uint32(0); i < 100000; i++ {
+		s.AddRange(uint64(i)<<16, uint64(i)<<16+10)
+	}
+	r := rand.New(rand.NewSource(0))
+	card := s.GetCardinality()
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		s.Select(uint32(r.Int63n(int64(card))))
+	}
+}
+
+func BenchmarkSelectRankIndex(b *testing.B) {
+	b.StopTimer()
+	s := NewBitmap()
+	for i := uint32(0); i < 100000; i++ {
+		s.AddRange(uint64(i)<<16, uint64(i)<<16+10)
+	}
+	r := rand.New(rand.NewSource(0))
+	card := s.GetCardinality()
+	ri := NewRankIndex(s)
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		ri.Select(uint32(r.Int63n(int64(card))))
+	}
+}
+
+// denseKeysBitmap holds two values in each of 60000 containers
+func denseKeysBitmap() *Bitmap {
+	s := NewBitmap()
+	for key := uint32(0); key < 60000; key++ {
+		s.AddMany([]uint32{key << 16, key<<16 + 1})
+	}
+	return s
+}
+
+// go test -bench BenchmarkDenseKeys -run -
+func BenchmarkDenseKeysContainsBinarySearch(b *testing.B) {
+	b.StopTimer()
+	s := denseKeysBitmap()
+	s.highlowcontainer.index = nil
+	r := rand.New(rand.NewSource(0))
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		s.Contains(uint32(r.Int63n(1 << 32)))
+	}
+}
+
+func BenchmarkDenseKeysContainsKeyIndex(b *testing.B) {
+	b.StopTimer()
+	s := denseKeysBitmap()
+	r := rand.New(rand.NewSource(0))
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		s.Contains(uint32(r.Int63n(1 << 32)))
+	}
+}
+
+func BenchmarkDenseKeysAddRemoveBinarySearch(b *testing.B) {
+	b.StopTimer()
+	s := denseKeysBitmap()
+	s.highlowcontainer.index = nil
+	r := rand.New(rand.NewSource(0))
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		x := uint32(r.Int63n(60000))<<16 + 2
+		s.Add(x)
+		s.Remove(x)
+	}
+}
+
+func BenchmarkDenseKeysAddRemoveKeyIndex(b *testing.B) {
+	b.StopTimer()
+	s := denseKeysBitmap()
+	r := rand.New(rand.NewSource(0))
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		x := uint32(r.Int63n(60000))<<16 + 2
+		s.Add(x)
+		s.Remove(x)
+	}
+}
+
+// dense bitmaps, whose intersections are made of bitmap containers
+func poolBenchmarkBitmaps() (*Bitmap, *Bitmap) {
+	r := rand.New(rand.NewSource(0))
+	s1, s2 := NewBitmap(), NewBitmap()
+	for i := 0; i < 1000000; i++ {
+		s1.Add(uint32(r.Int31n(1 << 22)))
+		s2.Add(uint32(r.Int31n(1 << 22)))
+	}
+	return s1, s2
+}
+
+// go test -bench BenchmarkAndPool -run - -benchmem
+func BenchmarkAndPoolNone(b *testing.B) {
+	b.StopTimer()
+	s1, s2 := poolBenchmarkBitmaps()
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		And(s1, s2)
+	}
+}
+
+func BenchmarkAndPoolRelease(b *testing.B) {
+	b.StopTimer()
+	s1, s2 := poolBenchmarkBitmaps()
+	pool := NewContainerPool()
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		pool.And(s1, s2).Release()
+	}
+}
+
+func BenchmarkAndPoolInto(b *testing.B) {
+	b.StopTimer()
+	s1, s2 := poolBenchmarkBitmaps()
+	dst := NewBitmap()
+	b.StartTimer()
+	for j := 0; j < b.N; j++ {
+		AndInto(dst, s1, s2)
+	}
+}
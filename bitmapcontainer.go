@@ -2,6 +2,7 @@ package roaring
 
 import (
 	"fmt"
+	"math/bits"
 	"unsafe"
 )
 
@@ -10,6 +11,13 @@ import (
 type bitmapContainer struct {
 	cardinality int
 	bitmap      []uint64
+
+	// the number of bitmaps sharing the container, beyond the first
+	shares int32 `msg:"-"`
+}
+
+func (bc *bitmapContainer) sharesCount() *int32 {
+	return &bc.shares
 }
 
 func (bc bitmapContainer) String() string {
@@ -109,6 +117,14 @@ func (bcsi *bitmapContainerShortIterator) next() uint16 {
 func (bcsi *bitmapContainerShortIterator) hasNext() bool {
 	return bcsi.i >= 0
 }
+func (bcsi *bitmapContainerShortIterator) peekNext() uint16 {
+	return uint16(bcsi.i)
+}
+func (bcsi *bitmapContainerShortIterator) advanceIfNeeded(minval uint16) {
+	if bcsi.i >= 0 && bcsi.i < int(minval) {
+		bcsi.i = bcsi.ptr.NextSetBit(int(minval))
+	}
+}
 func newBitmapContainerShortIterator(a *bitmapContainer) *bitmapContainerShortIterator {
 	return &bitmapContainerShortIterator{a, a.NextSetBit(0)}
 }
@@ -116,13 +132,116 @@ func (bc *bitmapContainer) getShortIterator() shortIterable {
 	return newBitmapContainerShortIterator(bc)
 }
 
+// bitmapContainerManyIterator decodes a bitmap container
+// word by word, extracting the set bits of each word in turn
+type bitmapContainerManyIterator struct {
+	ptr    *bitmapContainer
+	base   int    // index of the current word
+	bitset uint64 // bits of the current word left to decode
+}
+
+func (bcmi *bitmapContainerManyIterator) reset(bc *bitmapContainer) {
+	bcmi.ptr = bc
+	bcmi.base = 0
+	bcmi.bitset = bc.bitmap[0]
+}
+
+func (bcmi *bitmapContainerManyIterator) nextMany(hs uint32, buf []uint32) int {
+	n := 0
+	base, bitset := bcmi.base, bcmi.bitset
+	for n < len(buf) {
+		if bitset == 0 {
+			base++
+			if base >= len(bcmi.ptr.bitmap) {
+				break
+			}
+			bitset = bcmi.ptr.bitmap[base]
+			continue
+		}
+		buf[n] = hs | uint32(base*64+bits.TrailingZeros64(bitset))
+		bitset &= bitset - 1
+		n++
+	}
+	bcmi.base, bcmi.bitset = base, bitset
+	return n
+}
+
+type reverseBitmapContainerShortIterator struct {
+	ptr *bitmapContainer
+	i   int
+}
+
+func (bcsi *reverseBitmapContainerShortIterator) next() uint16 {
+	j := bcsi.i
+	bcsi.i = bcsi.ptr.PrevSetBit(bcsi.i - 1)
+	return uint16(j)
+}
+func (bcsi *reverseBitmapContainerShortIterator) hasNext() bool {
+	return bcsi.i >= 0
+}
+func (bcsi *reverseBitmapContainerShortIterator) advanceIfNeeded(maxval uint16) {
+	if bcsi.i > int(maxval) {
+		bcsi.i = bcsi.ptr.PrevSetBit(int(maxval))
+	}
+}
+func (bc *bitmapContainer) getReverseIterator() reverseShortIterable {
+	return &reverseBitmapContainerShortIterator{bc, bc.PrevSetBit(len(bc.bitmap)*64 - 1)}
+}
+
+func (bc *bitmapContainer) iterate(hs uint32, cb func(x uint32) bool) bool {
+	for k, w := range bc.bitmap {
+		for w != 0 {
+			if !cb(hs | uint32(k*64+bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+// iterateRuns finds the runs a word at a time, as
+// newRunContainer16FromBitmapContainer does
+func (bc *bitmapContainer) iterateRuns(cb func(start, last uint16) bool) bool {
+	longCtr := 0
+	curWord := bc.bitmap[0]
+	for {
+		// potentially multiword advance to first 1 bit
+		for curWord == 0 && longCtr < len(bc.bitmap)-1 {
+			longCtr++
+			curWord = bc.bitmap[longCtr]
+		}
+		if curWord == 0 {
+			return true
+		}
+		runStart := countTrailingZerosDeBruijn(curWord) + 64*longCtr
+		// stuff 1s into number's LSBs
+		curWordWith1s := curWord | (curWord - 1)
+
+		// find the next 0, potentially in a later word
+		for curWordWith1s == maxWord && longCtr < len(bc.bitmap)-1 {
+			longCtr++
+			curWordWith1s = bc.bitmap[longCtr]
+		}
+		if curWordWith1s == maxWord {
+			// a final unterminated run of 1s
+			return cb(uint16(runStart), uint16(wordSizeInBits+longCtr*64-1))
+		}
+		runEnd := countTrailingZerosDeBruijn(^curWordWith1s) + longCtr*64
+		if !cb(uint16(runStart), uint16(runEnd-1)) {
+			return false
+		}
+		// now, zero out everything right of runEnd.
+		curWord = curWordWith1s & (curWordWith1s + 1)
+	}
+}
+
 func (bc *bitmapContainer) getSizeInBytes() int {
 	return len(bc.bitmap) * 8 // + bcBaseBytes
 }
 
 func (bc *bitmapContainer) serializedSizeInBytes() int {
-	return bc.Msgsize()
-	//return len(bc.bitmap) * 8 // +  bcBaseBytes
+	return len(bc.bitmap) * 8
 }
 
 const bcBaseBytes = int(unsafe.Sizeof(bitmapContainer{}))
@@ -241,7 +360,7 @@ func (bc *bitmapContainer) getCardinality() int {
 }
 
 func (bc *bitmapContainer) clone() container {
-	ptr := bitmapContainer{bc.cardinality, make([]uint64, len(bc.bitmap))}
+	ptr := bitmapContainer{cardinality: bc.cardinality, bitmap: make([]uint64, len(bc.bitmap))}
 	copy(ptr.bitmap, bc.bitmap[:])
 	return &ptr
 }
@@ -326,7 +445,7 @@ func (bc *bitmapContainer) ior(a container) container {
 			return x.clone()
 		}
 		for i := range x.iv {
-			bc.iaddRange(int(x.iv[i].start), int(x.iv[i].last)+1)
+			bc.iaddRange(int(x.iv[i].start), int(x.iv[i].last())+1)
 		}
 		if bc.isFull() {
 			return newRunContainer16Range(0, MaxUint16)
@@ -349,10 +468,10 @@ func (bc *bitmapContainer) lazyIOR(a container) container {
 		}
 		// TODO : implement efficient in-place lazy OR to bitmap
 		for i := range x.iv {
-			setBitmapRange(bc.bitmap, int(x.iv[i].start), int(x.iv[i].last)+1)
-			//bc.iaddRange(int(x.iv[i].start), int(x.iv[i].last)+1)
+			setBitmapRange(bc.bitmap, int(x.iv[i].start), int(x.iv[i].last())+1)
+			//bc.iaddRange(int(x.iv[i].start), int(x.iv[i].last())+1)
 		}
-		//bc.computeCardinality()
+		bc.cardinality = invalidCardinality
 		return bc
 	}
 	panic("unsupported container type")
@@ -427,6 +546,14 @@ func (bc *bitmapContainer) computeCardinality() {
 	bc.cardinality = int(popcntSlice(bc.bitmap))
 }
 
+// repairCardinality computes the cardinality of bc if a lazy
+// operation left it unknown
+func (bc *bitmapContainer) repairCardinality() {
+	if bc.cardinality == invalidCardinality {
+		bc.computeCardinality()
+	}
+}
+
 func (bc *bitmapContainer) iorArray(ac *arrayContainer) container {
 	for k := range ac.content {
 		vc := ac.content[k]
@@ -612,8 +739,18 @@ func (bc *bitmapContainer) iand(a container) container {
 }
 
 func (bc *bitmapContainer) iandRun16(rc *runContainer16) container {
-	rcb := newBitmapContainerFromRun(rc)
-	return bc.iandBitmap(rcb)
+	bc.repairCardinality()
+	// clear the gaps between the runs
+	start := 0
+	for i := range rc.iv {
+		bc.cardinality += resetBitmapRangeAndCardinalityChange(bc.bitmap, start, int(rc.iv[i].start))
+		start = int(rc.iv[i].last()) + 1
+	}
+	bc.cardinality += resetBitmapRangeAndCardinalityChange(bc.bitmap, start, MaxUint16+1)
+	if bc.cardinality <= arrayDefaultMaxSize {
+		return newArrayContainerFromBitmap(bc)
+	}
+	return bc
 }
 
 func (bc *bitmapContainer) iandArray(ac *arrayContainer) container {
@@ -653,11 +790,11 @@ func (bc *bitmapContainer) getCardinalityInRange(start, end uint) int {
 	endword := (end - 1) / 64
 	const allones = ^uint64(0)
 	if firstword == endword {
-		return int(popcount(bc.bitmap[firstword] & ((allones << (start % 64)) & (allones >> (64 - (end % 64))))))
+		return int(popcount(bc.bitmap[firstword] & ((allones << (start % 64)) & (allones >> (uint(-end) % 64)))))
 	}
 	answer := popcount(bc.bitmap[firstword] & (allones << (start % 64)))
 	answer += popcntSlice(bc.bitmap[firstword+1 : endword])
-	answer += popcount(bc.bitmap[endword] & (allones >> (64 - (end % 64))))
+	answer += popcount(bc.bitmap[endword] & (allones >> (uint(-end) % 64)))
 	return int(answer)
 }
 
@@ -725,8 +862,15 @@ func (bc *bitmapContainer) andNot(a container) container {
 }
 
 func (bc *bitmapContainer) andNotRun16(rc *runContainer16) container {
-	rcb := rc.toBitmapContainer()
-	return bc.andNotBitmap(rcb)
+	answer := bc.clone().(*bitmapContainer)
+	answer.repairCardinality()
+	for i := range rc.iv {
+		answer.cardinality += resetBitmapRangeAndCardinalityChange(answer.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	if answer.cardinality <= arrayDefaultMaxSize {
+		return answer.toArrayContainer()
+	}
+	return answer
 }
 
 func (bc *bitmapContainer) iandNot(a container) container {
@@ -749,8 +893,11 @@ func (bc *bitmapContainer) iandNotArray(ac *arrayContainer) container {
 }
 
 func (bc *bitmapContainer) iandNotRun16(rc *runContainer16) container {
-	rcb := rc.toBitmapContainer()
-	return bc.iandNotBitmapSurely(rcb)
+	bc.repairCardinality()
+	for i := range rc.iv {
+		bc.cardinality += resetBitmapRangeAndCardinalityChange(bc.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	return bc
 }
 
 func (bc *bitmapContainer) andNotArray(value2 *arrayContainer) container {
@@ -858,6 +1005,29 @@ func (bc *bitmapContainer) NextSetBit(i int) int {
 	return -1
 }
 
+// PrevSetBit returns the largest set bit not larger than i, or -1 if there is none
+func (bc *bitmapContainer) PrevSetBit(i int) int {
+	if i < 0 {
+		return -1
+	}
+	x := i / 64
+	if x >= len(bc.bitmap) {
+		x = len(bc.bitmap) - 1
+		i = x*64 + 63
+	}
+	w := bc.bitmap[x] << uint(63-i%64)
+	if w != 0 {
+		return i - bits.LeadingZeros64(w)
+	}
+	x--
+	for ; x >= 0; x-- {
+		if bc.bitmap[x] != 0 {
+			return x*64 + 63 - bits.LeadingZeros64(bc.bitmap[x])
+		}
+	}
+	return -1
+}
+
 // reference the java implementation
 // https://github.com/RoaringBitmap/RoaringBitmap/blob/master/src/main/java/org/roaringbitmap/BitmapContainer.java#L875-L892
 //
@@ -906,14 +1076,14 @@ func (bc *bitmapContainer) toEfficientContainer() container {
 func newBitmapContainerFromRun(rc *runContainer16) *bitmapContainer {
 
 	if len(rc.iv) == 1 {
-		return newBitmapContainerwithRange(int(rc.iv[0].start), int(rc.iv[0].last))
+		return newBitmapContainerwithRange(int(rc.iv[0].start), int(rc.iv[0].last()))
 	}
 
 	bc := newBitmapContainer()
 	for i := range rc.iv {
-		setBitmapRange(bc.bitmap, int(rc.iv[i].start), int(rc.iv[i].last)+1)
-		bc.cardinality += int(rc.iv[i].last) + 1 - int(rc.iv[i].start)
-		//bc.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last)+1)
+		setBitmapRange(bc.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+		bc.cardinality += int(rc.iv[i].last()) + 1 - int(rc.iv[i].start)
+		//bc.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last())+1)
 	}
 	//bc.computeCardinality()
 	return bc
@@ -0,0 +1,310 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Operation selects the comparison made by BSI.CompareValue
+type Operation int
+
+const (
+	// EQ selects the values equal to the given value
+	EQ Operation = iota
+	// NEQ selects the values different from the given value
+	NEQ
+	// LT selects the values smaller than the given value
+	LT
+	// LE selects the values smaller than or equal to the given value
+	LE
+	// GT selects the values greater than the given value
+	GT
+	// GE selects the values greater than or equal to the given value
+	GE
+	// RANGE selects the values within [value, end], bounds included
+	RANGE
+)
+
+// BSI is a bit-sliced index, which associates an unsigned integer
+// value with each column (a uint32, typically a row identifier).
+// Slice i holds the columns whose value has bit i set, and the
+// existence bitmap holds the columns that have a value at all, so
+// that comparisons, sums and top-k queries reduce to a few bitmap
+// operations per bit of the values.
+// See O'Neil and Quass, "Improved Query Performance with Variant Indexes".
+type BSI struct {
+	eBM    *Bitmap   // existence bitmap
+	slices []*Bitmap // slices[i] holds the columns whose value has bit i set
+}
+
+// NewBSI creates a new empty bit-sliced index
+func NewBSI() *BSI {
+	return &BSI{eBM: NewBitmap()}
+}
+
+// BitCount returns the number of bit slices of the index, which
+// is the number of bits of the largest value ever stored
+func (b *BSI) BitCount() int {
+	return len(b.slices)
+}
+
+// GetCardinality returns the number of columns that have a value
+func (b *BSI) GetCardinality() uint64 {
+	return b.eBM.GetCardinality()
+}
+
+// GetExistenceBitmap returns the columns that have a value; it must not be modified
+func (b *BSI) GetExistenceBitmap() *Bitmap {
+	return b.eBM
+}
+
+// SetValue associates value with column, replacing any previous value
+func (b *BSI) SetValue(column uint32, value uint64) {
+	for len(b.slices) < bits.Len64(value) {
+		b.slices = append(b.slices, NewBitmap())
+	}
+	for i, bm := range b.slices {
+		if value&(1<<uint(i)) != 0 {
+			bm.Add(column)
+		} else {
+			bm.Remove(column)
+		}
+	}
+	b.eBM.Add(column)
+}
+
+// GetValue returns the value of column, and whether column has a value
+func (b *BSI) GetValue(column uint32) (uint64, bool) {
+	if !b.eBM.Contains(column) {
+		return 0, false
+	}
+	value := uint64(0)
+	for i, bm := range b.slices {
+		if bm.Contains(column) {
+			value |= 1 << uint(i)
+		}
+	}
+	return value, true
+}
+
+// ClearValue removes the value of column, if any
+func (b *BSI) ClearValue(column uint32) {
+	if !b.eBM.CheckedRemove(column) {
+		return
+	}
+	for _, bm := range b.slices {
+		bm.Remove(column)
+	}
+}
+
+// candidates returns the columns with a value that are also in foundSet;
+// a nil foundSet stands for all the columns
+func (b *BSI) candidates(foundSet *Bitmap) *Bitmap {
+	if foundSet == nil {
+		return b.eBM.Clone()
+	}
+	return And(b.eBM, foundSet)
+}
+
+// compare splits the candidates into the columns whose value is
+// smaller than, equal to and greater than value
+func (b *BSI) compare(value uint64, foundSet *Bitmap) (lt, eq, gt *Bitmap) {
+	lt, eq, gt = NewBitmap(), b.candidates(foundSet), NewBitmap()
+	n := len(b.slices)
+	if bits.Len64(value) > n {
+		// value is larger than anything we store
+		return eq, NewBitmap(), gt
+	}
+	for i := n - 1; i >= 0 && !eq.IsEmpty(); i-- {
+		if value&(1<<uint(i)) != 0 {
+			lt.Or(AndNot(eq, b.slices[i]))
+			eq.And(b.slices[i])
+		} else {
+			gt.Or(And(eq, b.slices[i]))
+			eq.AndNot(b.slices[i])
+		}
+	}
+	return lt, eq, gt
+}
+
+// CompareValue returns the columns of foundSet whose value satisfies
+// the comparison op with value; end is only used by RANGE, which
+// selects the values within [value, end]. A nil foundSet stands for
+// all the columns.
+func (b *BSI) CompareValue(op Operation, value, end uint64, foundSet *Bitmap) *Bitmap {
+	switch op {
+	case EQ:
+		_, eq, _ := b.compare(value, foundSet)
+		return eq
+	case NEQ:
+		lt, _, gt := b.compare(value, foundSet)
+		lt.Or(gt)
+		return lt
+	case LT:
+		lt, _, _ := b.compare(value, foundSet)
+		return lt
+	case LE:
+		lt, eq, _ := b.compare(value, foundSet)
+		lt.Or(eq)
+		return lt
+	case GT:
+		_, _, gt := b.compare(value, foundSet)
+		return gt
+	case GE:
+		_, eq, gt := b.compare(value, foundSet)
+		gt.Or(eq)
+		return gt
+	case RANGE:
+		if value > end {
+			return NewBitmap()
+		}
+		_, eq, gt := b.compare(value, foundSet)
+		gt.Or(eq)
+		lt, eq, _ := b.compare(end, gt)
+		lt.Or(eq)
+		return lt
+	}
+	panic(fmt.Sprintf("unknown BSI operation %d", op))
+}
+
+// Sum returns the sum of the values of the columns of foundSet, and
+// the number of such columns that have a value. A nil foundSet stands
+// for all the columns. The sum wraps around if it exceeds 64 bits.
+func (b *BSI) Sum(foundSet *Bitmap) (sum uint64, count uint64) {
+	cand := b.candidates(foundSet)
+	for i, bm := range b.slices {
+		sum += bm.AndCardinality(cand) << uint(i)
+	}
+	return sum, cand.GetCardinality()
+}
+
+// MinMax returns the smallest and largest values of the columns of
+// foundSet; ok is false if none of them has a value. A nil foundSet
+// stands for all the columns.
+func (b *BSI) MinMax(foundSet *Bitmap) (min, max uint64, ok bool) {
+	cand := b.candidates(foundSet)
+	if cand.IsEmpty() {
+		return 0, 0, false
+	}
+	lows, highs := cand, cand
+	for i := len(b.slices) - 1; i >= 0; i-- {
+		if x := AndNot(lows, b.slices[i]); !x.IsEmpty() {
+			lows = x
+		} else {
+			min |= 1 << uint(i)
+		}
+		if x := And(highs, b.slices[i]); !x.IsEmpty() {
+			highs = x
+			max |= 1 << uint(i)
+		}
+	}
+	return min, max, true
+}
+
+// TopK returns the k columns of foundSet with the largest values, or
+// all of them if fewer than k have a value. Among columns with equal
+// values, the smallest column identifiers are kept. A nil foundSet
+// stands for all the columns.
+func (b *BSI) TopK(k uint64, foundSet *Bitmap) *Bitmap {
+	// the columns of g are surely in the result, those of e might be
+	g, e := NewBitmap(), b.candidates(foundSet)
+	for i := len(b.slices) - 1; i >= 0; i-- {
+		x := Or(g, And(e, b.slices[i]))
+		card := x.GetCardinality()
+		if card > k {
+			e.And(b.slices[i])
+		} else if card < k {
+			g = x
+			e.AndNot(b.slices[i])
+		} else {
+			g = x
+			e = NewBitmap()
+			break
+		}
+	}
+	// all the columns of e have the same value: keep the first ones
+	for it := e.Iterator(); it.HasNext() && g.GetCardinality() < k; {
+		g.Add(it.Next())
+	}
+	return g
+}
+
+// GetSerializedSizeInBytes computes the serialized size in bytes
+// of the index. It should correspond to the number of bytes
+// written when invoking WriteTo.
+func (b *BSI) GetSerializedSizeInBytes() uint64 {
+	size := 4 + b.eBM.GetSerializedSizeInBytes()
+	for _, bm := range b.slices {
+		size += bm.GetSerializedSizeInBytes()
+	}
+	return size
+}
+
+// WriteTo writes a serialized version of the index to stream: the
+// number of slices as a little-endian uint32, then the existence
+// bitmap and the slices, from the least significant bit up, each in
+// the portable format (see Bitmap.WriteTo).
+func (b *BSI) WriteTo(stream io.Writer) (int64, error) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(b.slices)))
+	n, err := stream.Write(buf[:])
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	for _, bm := range append([]*Bitmap{b.eBM}, b.slices...) {
+		n, err := bm.WriteTo(stream)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ReadFrom reads an index written by WriteTo from stream, replacing
+// the content of b
+func (b *BSI) ReadFrom(stream io.Reader) (int64, error) {
+	var buf [4]byte
+	n, err := io.ReadFull(stream, buf[:])
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("error in BSI.ReadFrom: could not read the number of slices: %s", err)
+	}
+	count := binary.LittleEndian.Uint32(buf[:])
+	if count > 64 {
+		return read, fmt.Errorf("error in BSI.ReadFrom: values have at most 64 bits, got %d slices", count)
+	}
+	bitmaps := make([]*Bitmap, count+1)
+	for i := range bitmaps {
+		bitmaps[i] = NewBitmap()
+		n, err := bitmaps[i].ReadFrom(stream)
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	for i, bm := range bitmaps[1:] {
+		if bm.AndCardinality(bitmaps[0]) != bm.GetCardinality() {
+			return read, fmt.Errorf("error in BSI.ReadFrom: slice %d has columns missing from the existence bitmap", i)
+		}
+	}
+	b.eBM, b.slices = bitmaps[0], bitmaps[1:]
+	return read, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface for the index
+func (b *BSI) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface for the index
+func (b *BSI) UnmarshalBinary(data []byte) error {
+	_, err := b.ReadFrom(bytes.NewReader(data))
+	return err
+}
@@ -0,0 +1,165 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBSI(t *testing.T) {
+
+	Convey("BSI queries should match a brute force scan of the values", t, func() {
+		r := rand.New(rand.NewSource(42))
+		b := NewBSI()
+		values := make(map[uint32]uint64)
+		for i := 0; i < 5000; i++ {
+			col := uint32(r.Intn(200000))
+			v := uint64(r.Intn(1000))
+			if i%100 == 0 {
+				v = 1<<40 + uint64(r.Intn(5))
+			}
+			b.SetValue(col, v)
+			values[col] = v
+		}
+		// overwrite and clear some values
+		for col := range values {
+			if col%7 == 0 {
+				b.SetValue(col, 3)
+				values[col] = 3
+			} else if col%11 == 0 {
+				b.ClearValue(col)
+				delete(values, col)
+			}
+		}
+		So(b.GetCardinality(), ShouldEqual, len(values))
+		So(b.BitCount(), ShouldEqual, 41)
+		for col, v := range values {
+			got, ok := b.GetValue(col)
+			So(ok, ShouldBeTrue)
+			So(got, ShouldEqual, v)
+		}
+		_, ok := b.GetValue(200001)
+		So(ok, ShouldBeFalse)
+
+		foundSet := NewBitmap()
+		foundSet.AddRange(0, 120000)
+		scan := func(keep func(v uint64) bool, fs *Bitmap) *Bitmap {
+			ans := NewBitmap()
+			for col, v := range values {
+				if (fs == nil || fs.Contains(col)) && keep(v) {
+					ans.Add(col)
+				}
+			}
+			return ans
+		}
+		for _, fs := range []*Bitmap{nil, foundSet} {
+			for _, value := range []uint64{0, 3, 500, 999, 1000, 1 << 40, 1<<40 + 2, 1 << 50} {
+				So(b.CompareValue(EQ, value, 0, fs).Equals(scan(func(v uint64) bool { return v == value }, fs)), ShouldBeTrue)
+				So(b.CompareValue(NEQ, value, 0, fs).Equals(scan(func(v uint64) bool { return v != value }, fs)), ShouldBeTrue)
+				So(b.CompareValue(LT, value, 0, fs).Equals(scan(func(v uint64) bool { return v < value }, fs)), ShouldBeTrue)
+				So(b.CompareValue(LE, value, 0, fs).Equals(scan(func(v uint64) bool { return v <= value }, fs)), ShouldBeTrue)
+				So(b.CompareValue(GT, value, 0, fs).Equals(scan(func(v uint64) bool { return v > value }, fs)), ShouldBeTrue)
+				So(b.CompareValue(GE, value, 0, fs).Equals(scan(func(v uint64) bool { return v >= value }, fs)), ShouldBeTrue)
+				end := value + 250
+				So(b.CompareValue(RANGE, value, end, fs).Equals(scan(func(v uint64) bool { return v >= value && v <= end }, fs)), ShouldBeTrue)
+			}
+			So(b.CompareValue(RANGE, 10, 9, fs).IsEmpty(), ShouldBeTrue)
+
+			var sum, count uint64
+			min, max := ^uint64(0), uint64(0)
+			for col, v := range values {
+				if fs == nil || fs.Contains(col) {
+					sum += v
+					count++
+					if v < min {
+						min = v
+					}
+					if v > max {
+						max = v
+					}
+				}
+			}
+			gotSum, gotCount := b.Sum(fs)
+			So(gotSum, ShouldEqual, sum)
+			So(gotCount, ShouldEqual, count)
+			gotMin, gotMax, ok := b.MinMax(fs)
+			So(ok, ShouldBeTrue)
+			So(gotMin, ShouldEqual, min)
+			So(gotMax, ShouldEqual, max)
+		}
+		_, _, ok = b.MinMax(BitmapOf(300000))
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("BSI TopK should return the columns with the largest values", t, func() {
+		b := NewBSI()
+		vals := []uint64{5, 9, 1, 9, 7, 9, 0, 3}
+		for col, v := range vals {
+			b.SetValue(uint32(col), v)
+		}
+		So(b.TopK(1, nil).ToArray(), ShouldResemble, []uint32{1})
+		So(b.TopK(3, nil).ToArray(), ShouldResemble, []uint32{1, 3, 5})
+		So(b.TopK(4, nil).ToArray(), ShouldResemble, []uint32{1, 3, 4, 5})
+		So(b.TopK(5, BitmapOf(0, 1, 2, 6)).ToArray(), ShouldResemble, []uint32{0, 1, 2, 6})
+		So(b.TopK(2, BitmapOf(0, 2, 6, 7)).ToArray(), ShouldResemble, []uint32{0, 7})
+		So(b.TopK(100, nil).GetCardinality(), ShouldEqual, len(vals))
+		So(b.TopK(0, nil).IsEmpty(), ShouldBeTrue)
+
+		r := rand.New(rand.NewSource(7))
+		b = NewBSI()
+		all := make([]uint64, 3000)
+		for col := range all {
+			all[col] = uint64(r.Intn(100))
+			b.SetValue(uint32(col), all[col])
+		}
+		sorted := append([]uint64(nil), all...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] > sorted[j] })
+		for _, k := range []uint64{1, 10, 100, 1000} {
+			top := b.TopK(k, nil)
+			So(top.GetCardinality(), ShouldEqual, k)
+			it := top.Iterator()
+			for it.HasNext() {
+				So(all[it.Next()], ShouldBeGreaterThanOrEqualTo, sorted[k-1])
+			}
+		}
+	})
+
+	Convey("BSI serialization should round trip", t, func() {
+		b := NewBSI()
+		for col := uint32(0); col < 100000; col += 3 {
+			b.SetValue(col, uint64(col)*uint64(col))
+		}
+		var buf bytes.Buffer
+		n, err := b.WriteTo(&buf)
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, buf.Len())
+		So(b.GetSerializedSizeInBytes(), ShouldEqual, buf.Len())
+
+		b2 := NewBSI()
+		b2.SetValue(1, 1)
+		So(b2.UnmarshalBinary(buf.Bytes()), ShouldBeNil)
+		So(b2.BitCount(), ShouldEqual, b.BitCount())
+		So(b2.GetExistenceBitmap().Equals(b.GetExistenceBitmap()), ShouldBeTrue)
+		v, ok := b2.GetValue(300)
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 90000)
+		_, ok = b2.GetValue(1)
+		So(ok, ShouldBeFalse)
+
+		data := buf.Bytes()
+		for _, i := range []int{0, 3, 4, 20, len(data) - 1} {
+			So(NewBSI().UnmarshalBinary(data[:i]), ShouldNotBeNil)
+		}
+
+		// a slice may only hold columns of the existence bitmap
+		var bad bytes.Buffer
+		binary.Write(&bad, binary.LittleEndian, uint32(1))
+		BitmapOf(1).WriteTo(&bad)
+		BitmapOf(1, 2).WriteTo(&bad)
+		So(NewBSI().UnmarshalBinary(bad.Bytes()), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,9 @@
+//go:build !roaringdebug
+// +build !roaringdebug
+
+package roaring
+
+// debugCardinality makes the cached cardinality of bitmaps be checked
+// against a full recount each time it is set or read. It is enabled by
+// building with the roaringdebug tag.
+const debugCardinality = false
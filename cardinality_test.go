@@ -0,0 +1,125 @@
+package roaring
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCachedCardinality(t *testing.T) {
+
+	Convey("modifications should keep the cached cardinality up to date", t, func() {
+		r := rand.New(rand.NewSource(22))
+		rb := BitmapOf(1) // a modification fills the cache
+		other := NewBitmap()
+		for i := 0; i < 3000; i++ {
+			x := uint32(r.Int63n(6 << 16))
+			y := uint64(x) + uint64(r.Int63n(1<<17))
+			switch r.Intn(13) {
+			case 0:
+				rb.Add(x)
+			case 1:
+				rb.CheckedAdd(x)
+			case 2:
+				rb.Remove(x)
+			case 3:
+				rb.CheckedRemove(x)
+			case 4:
+				rb.AddMany([]uint32{x, x + 1, x + 70000, x + 2})
+			case 5:
+				rb.AddRange(uint64(x), y)
+			case 6:
+				rb.RemoveRange(uint64(x), y)
+			case 7:
+				rb.Flip(uint64(x), y)
+			case 8:
+				other.AddRange(uint64(x), y)
+				rb.And(other)
+			case 9:
+				rb.Or(BitmapOf(x, x+5))
+			case 10:
+				rb.Xor(BitmapOf(x, x+1<<16))
+			case 11:
+				rb.AndNot(BitmapOf(x, uint32(y)))
+			case 12:
+				rb.RunOptimize()
+			}
+			card, ok := rb.highlowcontainer.cachedCardinality()
+			So(ok, ShouldBeTrue)
+			if card != rb.highlowcontainer.countCardinality() {
+				So(card, ShouldEqual, rb.highlowcontainer.countCardinality())
+			}
+		}
+		rb.Clear()
+		So(rb.GetCardinality(), ShouldEqual, 0)
+		rb.Add(3)
+		So(rb.Clone().GetCardinality(), ShouldEqual, 1)
+	})
+
+	Convey("reading a bitmap should not fill its cache", t, func() {
+		var buf bytes.Buffer
+		_, err := BitmapOf(1, 2, 3, 4).WriteToMsgpack(&buf)
+		So(err, ShouldBeNil)
+		rb := NewBitmap()
+		_, err = rb.ReadFromMsgpack(&buf)
+		So(err, ShouldBeNil)
+		So(rb.GetCardinality(), ShouldEqual, 4)
+		_, ok := rb.highlowcontainer.cachedCardinality()
+		So(ok, ShouldBeFalse)
+		rb.Add(5)
+		So(rb.GetCardinality(), ShouldEqual, 5)
+		_, ok = rb.highlowcontainer.cachedCardinality()
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("the results of operations should start out counted", t, func() {
+		r := rand.New(rand.NewSource(22))
+		bitmaps := make([]*Bitmap, 4)
+		for i := range bitmaps {
+			bitmaps[i] = NewBitmap()
+			for j := 0; j < 20000; j++ {
+				bitmaps[i].Add(uint32(r.Int63n(5 << 16)))
+			}
+			bitmaps[i].AddRange(uint64(i)<<16, uint64(i)<<16+30000)
+			bitmaps[i].RunOptimize()
+		}
+		x1, x2 := bitmaps[0], bitmaps[1]
+		buf, err := x1.ToBytes()
+		So(err, ShouldBeNil)
+		fromBuffer, err := FromBuffer(buf)
+		So(err, ShouldBeNil)
+		readFrom := NewBitmap()
+		_, err = readFrom.ReadFrom(bytes.NewReader(buf))
+		So(err, ShouldBeNil)
+		parOr, err := ParOr(context.Background(), 2, bitmaps...)
+		So(err, ShouldBeNil)
+		into := NewBitmap()
+		OrInto(into, x1, x2)
+		p := NewContainerPool()
+		results := []*Bitmap{
+			Or(x1, x2), And(x1, x2), Xor(x1, x2), AndNot(x1, x2),
+			p.Or(x1, x2), p.And(x1, x2), p.Xor(x1, x2), p.AndNot(x1, x2),
+			FastOr(bitmaps...), HeapOr(bitmaps...), FastAnd(bitmaps...),
+			FastXor(bitmaps...), FastAndNot(bitmaps...), HeapXor(bitmaps...),
+			Threshold(2, bitmaps...), Flip(x1, 1000, 3<<16),
+			parOr, fromBuffer, readFrom, into,
+		}
+		for _, rb := range results {
+			card, ok := rb.highlowcontainer.cachedCardinality()
+			So(ok, ShouldBeTrue)
+			So(card, ShouldEqual, rb.highlowcontainer.countCardinality())
+		}
+	})
+
+	Convey("modifications the cache does not follow should invalidate it", t, func() {
+		rb := BitmapOf(1, 2, 3)
+		rb.Add(4)
+		buf, _ := BitmapOf(7, 8).ToBytes()
+		_, err := rb.ReadFrom(bytes.NewReader(buf))
+		So(err, ShouldBeNil)
+		So(rb.GetCardinality(), ShouldEqual, rb.highlowcontainer.countCardinality())
+	})
+}
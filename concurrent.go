@@ -0,0 +1,160 @@
+package roaring
+
+import (
+	"sort"
+	"sync"
+)
+
+// ConcurrentBitmap is a bitmap that many goroutines can read and modify
+// at once. Each container, holding the values sharing their high 16 bits,
+// has its own lock, so that writers to different containers never wait
+// for one another. Snapshot returns a consistent, plain *Bitmap copy.
+type ConcurrentBitmap struct {
+	// mu is held for reading while a container is used, and for
+	// writing when a container is created or a snapshot is taken
+	mu         sync.RWMutex
+	containers map[uint16]*lockedContainer
+}
+
+// lockedContainer is a container with its own lock
+type lockedContainer struct {
+	mu sync.RWMutex
+	c  container
+}
+
+// writable returns the container, copying it first if a snapshot shares it
+func (lc *lockedContainer) writable() container {
+	lc.c = writableContainer(lc.c)
+	return lc.c
+}
+
+// NewConcurrentBitmap creates an empty ConcurrentBitmap
+func NewConcurrentBitmap() *ConcurrentBitmap {
+	return &ConcurrentBitmap{containers: make(map[uint16]*lockedContainer)}
+}
+
+// NewConcurrentBitmapFrom creates a ConcurrentBitmap holding the values of rb
+func NewConcurrentBitmapFrom(rb *Bitmap) *ConcurrentBitmap {
+	cb := NewConcurrentBitmap()
+	ra := &rb.highlowcontainer
+	for i, c := range ra.containers {
+		cb.containers[ra.keys[i]] = &lockedContainer{c: c.clone()}
+	}
+	return cb
+}
+
+// container returns the container of key, creating it if create is true.
+// It returns with cb.mu held for reading, unless the result is nil.
+func (cb *ConcurrentBitmap) container(key uint16, create bool) *lockedContainer {
+	cb.mu.RLock()
+	lc := cb.containers[key]
+	if lc != nil || !create {
+		if lc == nil {
+			cb.mu.RUnlock()
+		}
+		return lc
+	}
+	cb.mu.RUnlock()
+
+	cb.mu.Lock()
+	if cb.containers[key] == nil {
+		cb.containers[key] = &lockedContainer{c: newArrayContainer()}
+	}
+	cb.mu.Unlock()
+	// the container is never removed, so it is still there
+	cb.mu.RLock()
+	return cb.containers[key]
+}
+
+// Add adds the integer x to the bitmap
+func (cb *ConcurrentBitmap) Add(x uint32) {
+	lc := cb.container(highbits(x), true)
+	lc.mu.Lock()
+	if !lc.c.contains(lowbits(x)) {
+		lc.c = lc.writable().iaddReturnMinimized(lowbits(x))
+	}
+	lc.mu.Unlock()
+	cb.mu.RUnlock()
+}
+
+// AddMany adds all of the values in dat, locking each container
+// once per group of consecutive values that fall in it
+func (cb *ConcurrentBitmap) AddMany(dat []uint32) {
+	for len(dat) > 0 {
+		key := highbits(dat[0])
+		n := 1
+		for n < len(dat) && highbits(dat[n]) == key {
+			n++
+		}
+		lc := cb.container(key, true)
+		lc.mu.Lock()
+		c := lc.writable()
+		for _, x := range dat[:n] {
+			c = c.iaddReturnMinimized(lowbits(x))
+		}
+		lc.c = c
+		lc.mu.Unlock()
+		cb.mu.RUnlock()
+		dat = dat[n:]
+	}
+}
+
+// Remove removes the integer x from the bitmap
+func (cb *ConcurrentBitmap) Remove(x uint32) {
+	lc := cb.container(highbits(x), false)
+	if lc == nil {
+		return
+	}
+	lc.mu.Lock()
+	if lc.c.contains(lowbits(x)) {
+		lc.c = lc.writable().iremoveReturnMinimized(lowbits(x))
+	}
+	lc.mu.Unlock()
+	cb.mu.RUnlock()
+}
+
+// Contains returns true if the integer is contained in the bitmap
+func (cb *ConcurrentBitmap) Contains(x uint32) bool {
+	lc := cb.container(highbits(x), false)
+	if lc == nil {
+		return false
+	}
+	lc.mu.RLock()
+	answer := lc.c.contains(lowbits(x))
+	lc.mu.RUnlock()
+	cb.mu.RUnlock()
+	return answer
+}
+
+// GetCardinality returns the number of integers contained in the
+// bitmap, all writers being held off while it is computed
+func (cb *ConcurrentBitmap) GetCardinality() uint64 {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	size := uint64(0)
+	for _, lc := range cb.containers {
+		size += uint64(lc.c.getCardinality())
+	}
+	return size
+}
+
+// Snapshot returns a *Bitmap holding the values of the bitmap at a
+// single point in time: all writers are held off while it is taken.
+// The snapshot shares its containers with cb, each side copying a
+// container before it modifies it, so taking a snapshot is cheap.
+func (cb *ConcurrentBitmap) Snapshot() *Bitmap {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	keys := make([]uint16, 0, len(cb.containers))
+	for key, lc := range cb.containers {
+		if lc.c.getCardinality() > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Sort(uint16Slice(keys))
+	answer := NewBitmap()
+	for _, key := range keys {
+		answer.highlowcontainer.appendContainer(key, shareContainer(cb.containers[key].c), true)
+	}
+	return answer
+}
@@ -0,0 +1,123 @@
+package roaring
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConcurrentBitmap(t *testing.T) {
+
+	Convey("ConcurrentBitmap should behave like a Bitmap", t, func() {
+		cb := NewConcurrentBitmap()
+		So(cb.Contains(1), ShouldBeFalse)
+		cb.Remove(1)
+		cb.Add(1)
+		cb.AddMany([]uint32{2, 3, 1 << 16, 1<<16 + 1, 5})
+		So(cb.Contains(1<<16), ShouldBeTrue)
+		So(cb.GetCardinality(), ShouldEqual, 6)
+		cb.Remove(1 << 16)
+		cb.Remove(1<<16 + 1)
+		cb.Remove(7)
+		So(cb.Contains(1<<16), ShouldBeFalse)
+		snap := cb.Snapshot()
+		So(snap.ToArray(), ShouldResemble, []uint32{1, 2, 3, 5})
+		So(snap.Validate(), ShouldBeNil)
+
+		rb := iteratorTestBitmap()
+		So(NewConcurrentBitmapFrom(rb).Snapshot().Equals(rb), ShouldBeTrue)
+	})
+
+	Convey("Snapshots should not see later changes, nor change the ConcurrentBitmap", t, func() {
+		cb := NewConcurrentBitmap()
+		for i := uint32(0); i < 10000; i++ {
+			cb.Add(3 * i)
+		}
+		snap := cb.Snapshot()
+		expect := snap.Clone()
+		// adding a value already present keeps sharing the container
+		shared := cb.containers[0].c
+		cb.Add(6)
+		So(cb.containers[0].c == shared, ShouldBeTrue)
+		cb.Add(1)
+		cb.Remove(3)
+		So(snap.Equals(expect), ShouldBeTrue)
+		snap.Add(2)
+		So(cb.Contains(2), ShouldBeFalse)
+		So(cb.Contains(1), ShouldBeTrue)
+		So(cb.Contains(3), ShouldBeFalse)
+	})
+}
+
+// run with go test -race -run TestConcurrent
+func TestConcurrentBitmapWriters(t *testing.T) {
+
+	Convey("Concurrent writers and snapshots should not lose values", t, func() {
+		cb := NewConcurrentBitmap()
+		const writers = 8
+		const perWriter = 20000
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(w)))
+				batch := make([]uint32, 0, 16)
+				for i := 0; i < perWriter; i++ {
+					// the values of writer w are those equal to w modulo writers
+					x := uint32(r.Intn(1<<20))/writers*writers + uint32(w)
+					if i%2 == 0 {
+						cb.Add(x)
+					} else {
+						batch = append(batch, x)
+						if len(batch) == cap(batch) {
+							cb.AddMany(batch)
+							batch = batch[:0]
+						}
+					}
+					if i%7 == 0 {
+						cb.AddMany(batch)
+						batch = batch[:0]
+						cb.Remove(x)
+						cb.Remove(x) // a removed value stays removed
+						if cb.Contains(x) {
+							t.Errorf("%d was removed but is still there", x)
+						}
+					}
+				}
+				cb.AddMany(batch)
+			}(w)
+		}
+		// readers and snapshots, while the writers run
+		var snaps []*Bitmap
+		for i := 0; i < 20; i++ {
+			snap := cb.Snapshot()
+			So(snap.Validate(), ShouldBeNil)
+			snap.Add(MaxUint32) // modifying a snapshot is allowed
+			snaps = append(snaps, snap)
+			cb.Contains(uint32(i))
+		}
+		wg.Wait()
+
+		// replay the writers serially
+		expect := NewBitmap()
+		for w := 0; w < writers; w++ {
+			r := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWriter; i++ {
+				x := uint32(r.Intn(1<<20))/writers*writers + uint32(w)
+				expect.Add(x)
+				if i%7 == 0 {
+					expect.Remove(x)
+				}
+			}
+		}
+		So(cb.Snapshot().Equals(expect), ShouldBeTrue)
+		So(cb.GetCardinality(), ShouldEqual, expect.GetCardinality())
+		for _, snap := range snaps {
+			So(snap.Contains(MaxUint32), ShouldBeTrue)
+		}
+		So(cb.Contains(MaxUint32), ShouldBeFalse)
+	})
+}
@@ -2,6 +2,7 @@ package roaring
 
 import (
 	"container/heap"
+	"sort"
 )
 
 // Or function that requires repairAfterLazy
@@ -100,8 +101,9 @@ main:
 	return answer
 }
 
-// to be called after lazy aggregates
+// to be called after lazy aggregates, whose cardinality it counts as it goes
 func (x1 *Bitmap) repairAfterLazy() {
+	card := uint64(0)
 	for pos := 0; pos < x1.highlowcontainer.size(); pos++ {
 		c := x1.highlowcontainer.getContainerAtIndex(pos)
 		switch c.(type) {
@@ -116,23 +118,25 @@ func (x1 *Bitmap) repairAfterLazy() {
 				}
 			}
 		}
+		card += uint64(x1.highlowcontainer.getContainerAtIndex(pos).getCardinality())
 	}
+	x1.highlowcontainer.setCardinality(card)
 }
 
 // FastAnd computes the intersection between many bitmaps quickly
 // Compared to the And function, it can take many bitmaps as input, thus saving the trouble
 // of manually calling "And" many times.
+// Only the keys held by every bitmap are visited, and for each of them
+// the containers are intersected from the smallest up, stopping as soon
+// as the intersection is empty.
 func FastAnd(bitmaps ...*Bitmap) *Bitmap {
 	if len(bitmaps) == 0 {
 		return NewBitmap()
 	} else if len(bitmaps) == 1 {
 		return bitmaps[0].Clone()
 	}
-	answer := And(bitmaps[0], bitmaps[1])
-	for _, bm := range bitmaps[2:] {
-		answer.And(bm)
-	}
-	return answer
+	keys, groups := groupCommonKeys(bitmaps)
+	return aggregateGroups(keys, groups, andContainers)
 }
 
 // FastOr computes the union between many bitmaps quickly, as opposed to having to call Or repeatedly.
@@ -157,8 +161,9 @@ func FastOr(bitmaps ...*Bitmap) *Bitmap {
 func HeapOr(bitmaps ...*Bitmap) *Bitmap {
 	if len(bitmaps) == 0 {
 		return NewBitmap()
+	} else if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
 	}
-	// TODO:  for better speed, we could do the operation lazily, see Java implementation
 	pq := make(priorityQueue, len(bitmaps))
 	for i, bm := range bitmaps {
 		pq[i] = &item{bm, i}
@@ -168,9 +173,11 @@ func HeapOr(bitmaps ...*Bitmap) *Bitmap {
 	for pq.Len() > 1 {
 		x1 := heap.Pop(&pq).(*item)
 		x2 := heap.Pop(&pq).(*item)
-		heap.Push(&pq, &item{Or(x1.value, x2.value), 0})
+		heap.Push(&pq, &item{lazyOR(x1.value, x2.value), 0})
 	}
-	return heap.Pop(&pq).(*item).value
+	answer := heap.Pop(&pq).(*item).value
+	answer.repairAfterLazy()
+	return answer
 }
 
 // HeapXor computes the symmetric difference between many bitmaps quickly (as opposed to calling Xor repeated).
@@ -194,3 +201,220 @@ func HeapXor(bitmaps ...*Bitmap) *Bitmap {
 	}
 	return heap.Pop(&pq).(*item).value
 }
+
+// FastXor computes the symmetric difference between many bitmaps, that
+// is the values held by an odd number of them. For each key, the
+// containers are accumulated in a bitmap whose cardinality is only
+// computed at the end.
+func FastXor(bitmaps ...*Bitmap) *Bitmap {
+	if len(bitmaps) == 0 {
+		return NewBitmap()
+	} else if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
+	}
+	keys, groups := groupByKey(bitmaps)
+	return aggregateGroups(keys, groups, xorContainers)
+}
+
+// FastAndNot computes the values of the first bitmap that are in none
+// of the others. Only the keys of the first bitmap are visited; for
+// each of them the containers of the others are first united lazily.
+func FastAndNot(bitmaps ...*Bitmap) *Bitmap {
+	if len(bitmaps) == 0 {
+		return NewBitmap()
+	} else if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
+	}
+	keys, groups := groupFirstKeys(bitmaps)
+	return aggregateGroups(keys, groups, andNotContainers)
+}
+
+// groupByKey returns the keys held by any of the bitmaps, in increasing
+// order, along with the containers the bitmaps hold for each of them
+func groupByKey(bitmaps []*Bitmap) ([]uint16, [][]container) {
+	byKey := make(map[uint16][]container)
+	for _, bm := range bitmaps {
+		for i, key := range bm.highlowcontainer.keys {
+			byKey[key] = append(byKey[key], bm.highlowcontainer.getContainerAtIndex(i))
+		}
+	}
+	keys := make([]uint16, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Sort(uint16Slice(keys))
+	groups := make([][]container, len(keys))
+	for i, key := range keys {
+		groups[i] = byKey[key]
+	}
+	return keys, groups
+}
+
+// groupCommonKeys returns the keys held by all the bitmaps, in
+// increasing order, along with the containers the bitmaps hold for
+// each of them
+func groupCommonKeys(bitmaps []*Bitmap) ([]uint16, [][]container) {
+	if len(bitmaps) == 0 {
+		return nil, nil
+	}
+	// probe the bitmaps with the fewest keys first, they are the likeliest to prune a key
+	sorted := append([]*Bitmap(nil), bitmaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].highlowcontainer.size() < sorted[j].highlowcontainer.size() })
+	var keys []uint16
+	var groups [][]container
+main:
+	for i, key := range sorted[0].highlowcontainer.keys {
+		group := make([]container, 0, len(sorted))
+		group = append(group, sorted[0].highlowcontainer.getContainerAtIndex(i))
+		for _, bm := range sorted[1:] {
+			j := bm.highlowcontainer.getIndex(key)
+			if j < 0 {
+				continue main
+			}
+			group = append(group, bm.highlowcontainer.getContainerAtIndex(j))
+		}
+		keys = append(keys, key)
+		groups = append(groups, group)
+	}
+	return keys, groups
+}
+
+// groupFirstKeys returns the keys of the first bitmap along with its
+// container and the containers the other bitmaps hold for each of them
+func groupFirstKeys(bitmaps []*Bitmap) ([]uint16, [][]container) {
+	if len(bitmaps) == 0 {
+		return nil, nil
+	}
+	first := bitmaps[0]
+	keys := first.highlowcontainer.keys
+	groups := make([][]container, len(keys))
+	for i, key := range keys {
+		group := []container{first.highlowcontainer.getContainerAtIndex(i)}
+		for _, bm := range bitmaps[1:] {
+			if j := bm.highlowcontainer.getIndex(key); j >= 0 {
+				group = append(group, bm.highlowcontainer.getContainerAtIndex(j))
+			}
+		}
+		groups[i] = group
+	}
+	return keys, groups
+}
+
+// aggregateGroups applies aggregate to each group of containers and
+// assembles the results in the order of keys. The containers of the
+// groups are never modified.
+func aggregateGroups(keys []uint16, groups [][]container, aggregate func(cs []container) container) *Bitmap {
+	results := make([]container, len(keys))
+	for i, group := range groups {
+		results[i] = aggregate(group)
+	}
+	return assembleAggregate(keys, results)
+}
+
+// assembleAggregate builds a bitmap from the non-empty results
+func assembleAggregate(keys []uint16, results []container) *Bitmap {
+	answer := NewBitmap()
+	answer.highlowcontainer.setCardinality(0)
+	for i, c := range results {
+		if c != nil && c.getCardinality() > 0 {
+			answer.highlowcontainer.appendContainer(keys[i], c, false)
+		}
+	}
+	return answer
+}
+
+// repairAggregate gives its final form to a bitmap container used to
+// accumulate an aggregate, whose cardinality is not known yet
+func repairAggregate(bc *bitmapContainer) container {
+	bc.computeCardinality()
+	if bc.cardinality <= arrayDefaultMaxSize {
+		return bc.toArrayContainer()
+	}
+	if bc.isFull() {
+		return newRunContainer16Range(0, MaxUint16)
+	}
+	return bc
+}
+
+// lazyOrContainers returns the union of cs, accumulated in a bitmap
+// container whose cardinality is left unknown, unless the union is full
+func lazyOrContainers(cs []container) container {
+	var c container = newBitmapContainer()
+	for _, x := range cs {
+		c = c.lazyIOR(x)
+	}
+	return c
+}
+
+// orContainers returns the union of cs
+func orContainers(cs []container) container {
+	if len(cs) == 1 {
+		return cs[0].clone()
+	}
+	if len(cs) == 2 {
+		return cs[0].or(cs[1])
+	}
+	if bc, ok := lazyOrContainers(cs).(*bitmapContainer); ok {
+		return repairAggregate(bc)
+	}
+	return newRunContainer16Range(0, MaxUint16)
+}
+
+// xorContainers returns the values held by an odd number of cs
+func xorContainers(cs []container) container {
+	if len(cs) == 1 {
+		return cs[0].clone()
+	}
+	if len(cs) == 2 {
+		return cs[0].xor(cs[1])
+	}
+	bc := newBitmapContainer()
+	for _, x := range cs {
+		switch x := x.(type) {
+		case *arrayContainer:
+			for _, v := range x.content {
+				bc.bitmap[v/64] ^= 1 << (v % 64)
+			}
+		case *bitmapContainer:
+			for k, w := range x.bitmap {
+				bc.bitmap[k] ^= w
+			}
+		case *runContainer16:
+			for _, iv := range x.iv {
+				flipBitmapRange(bc.bitmap, int(iv.start), int(iv.last())+1)
+			}
+		}
+	}
+	return repairAggregate(bc)
+}
+
+// andContainers returns the intersection of cs
+func andContainers(cs []container) container {
+	if len(cs) == 1 {
+		return cs[0].clone()
+	}
+	// the smallest containers first, to shrink the intersection quickly
+	cs = append([]container(nil), cs...)
+	sort.Slice(cs, func(i, j int) bool { return cs[i].getCardinality() < cs[j].getCardinality() })
+	c := cs[0].and(cs[1])
+	for _, x := range cs[2:] {
+		if c.getCardinality() == 0 {
+			break
+		}
+		c = c.iand(x)
+	}
+	return c
+}
+
+// andNotContainers returns the values of cs[0] that are in none of the
+// other containers
+func andNotContainers(cs []container) container {
+	if len(cs) == 1 {
+		return cs[0].clone()
+	}
+	if len(cs) == 2 {
+		return cs[0].andNot(cs[1])
+	}
+	// andNot does not need the cardinality of the union
+	return cs[0].andNot(lazyOrContainers(cs[1:]))
+}
@@ -4,6 +4,7 @@ package roaring
 
 import (
 	"container/heap"
+	"math/rand"
 	. "github.com/smartystreets/goconvey/convey"
 	"testing"
 )
@@ -253,6 +254,23 @@ func TestFastAggregationsAdvanced_run(t *testing.T) {
 	})
 }
 
+func TestFastAggregationsLazyRuns(t *testing.T) {
+	Convey("FastOr should count the runs OR'ed into a bitmap container", t, func() {
+		rb1 := NewBitmap()
+		for i := uint32(0); i < 20000; i += 2 {
+			rb1.Add(i)
+		}
+		rb2 := NewBitmap()
+		rb2.AddRange(30000, 31000)
+		rb2.RunOptimize()
+		rb3 := NewBitmap()
+		rb3.AddRange(40000, 41000)
+		rb3.RunOptimize()
+
+		So(FastOr(rb1, rb2, rb3).GetCardinality(), ShouldEqual, 12000)
+	})
+}
+
 func TestFastAggregationsXOR(t *testing.T) {
 	Convey("Fast", t, func() {
 		rb1 := NewBitmap()
@@ -337,3 +355,50 @@ func TestFastAggregationsXOR_run(t *testing.T) {
 		So(HeapXor(rb1, rb2, rb3).Equals(bigxor), ShouldEqual, true)
 	})
 }
+
+func TestFastAggregationsManyWay(t *testing.T) {
+	Convey("the many-way aggregations should match the pairwise operations", t, func() {
+		r := rand.New(rand.NewSource(9))
+		for _, n := range []int{1, 2, 3, 4, 25} {
+			bitmaps := randomBitmapsForAggregation(r, n)
+			clones := make([]*Bitmap, n)
+			for i, bm := range bitmaps {
+				clones[i] = bm.Clone()
+			}
+			or, xor, and, andNot := bitmaps[0].Clone(), bitmaps[0].Clone(), bitmaps[0].Clone(), bitmaps[0].Clone()
+			for _, bm := range bitmaps[1:] {
+				or.Or(bm)
+				xor.Xor(bm)
+				and.And(bm)
+				andNot.AndNot(bm)
+			}
+			for _, tc := range []struct {
+				got, expect *Bitmap
+			}{
+				{FastOr(bitmaps...), or},
+				{HeapOr(bitmaps...), or},
+				{FastXor(bitmaps...), xor},
+				{HeapXor(bitmaps...), xor},
+				{FastAnd(bitmaps...), and},
+				{FastAndNot(bitmaps...), andNot},
+			} {
+				So(tc.got.Equals(tc.expect), ShouldBeTrue)
+				So(tc.got.GetCardinality(), ShouldEqual, tc.expect.GetCardinality())
+				So(tc.got.Validate(), ShouldBeNil)
+			}
+			for i, bm := range bitmaps {
+				So(bm.Equals(clones[i]), ShouldBeTrue)
+			}
+		}
+
+		// an empty bitmap, or keys held by only some of the bitmaps, prune the intersection
+		rb1, rb2, rb3 := BitmapOf(1, 1<<16|1, 2<<16|1), BitmapOf(1<<16|1, 2<<16|1), BitmapOf(1, 2<<16|1)
+		So(FastAnd(rb1, rb2, rb3).ToArray(), ShouldResemble, []uint32{2<<16 | 1})
+		So(FastAnd(rb1, NewBitmap(), rb3).IsEmpty(), ShouldBeTrue)
+		So(FastAndNot(rb1, rb2).ToArray(), ShouldResemble, []uint32{1})
+		So(FastAndNot(rb1, rb2, rb3).IsEmpty(), ShouldBeTrue)
+		So(FastXor(rb1, rb2, rb3).ToArray(), ShouldResemble, []uint32{2<<16 | 1})
+		So(FastXor().IsEmpty(), ShouldBeTrue)
+		So(FastAndNot().IsEmpty(), ShouldBeTrue)
+	})
+}
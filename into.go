@@ -0,0 +1,108 @@
+package roaring
+
+// OrInto computes the union between x1 and x2 into dst, replacing its
+// content. It reuses the memory of dst: its slices, and its containers
+// as buffers for those of the result whenever their types allow it, so
+// that computing results of the same shape into dst again and again
+// allocates nothing once its buffers have grown large enough.
+func OrInto(dst, x1, x2 *Bitmap) {
+	into(dst, x1, x2, or)
+}
+
+// AndInto computes the intersection between x1 and x2 into dst,
+// replacing its content and reusing its memory as OrInto does
+func AndInto(dst, x1, x2 *Bitmap) {
+	into(dst, x1, x2, and)
+}
+
+// XorInto computes the symmetric difference between x1 and x2 into dst,
+// replacing its content and reusing its memory as OrInto does
+func XorInto(dst, x1, x2 *Bitmap) {
+	into(dst, x1, x2, xor)
+}
+
+// AndNotInto computes the difference between x1 and x2 into dst,
+// replacing its content and reusing its memory as OrInto does
+func AndNotInto(dst, x1, x2 *Bitmap) {
+	into(dst, x1, x2, andNot)
+}
+
+// into computes op(x1, x2) into dst
+func into(dst, x1, x2 *Bitmap, op func(answer, x1, x2 *Bitmap, r recycler)) {
+	ra := &dst.highlowcontainer
+	if dst == x1 || dst == x2 {
+		// the containers of dst are operands, they cannot be overwritten
+		answer := NewBitmap()
+		op(answer, x1, x2, recycler{})
+		modCount, copyOnWrite := ra.modCount, ra.copyOnWrite
+		card, _ := answer.highlowcontainer.cachedCardinality()
+		*ra = answer.highlowcontainer
+		ra.modCount = modCount + 1
+		ra.copyOnWrite = copyOnWrite
+		ra.setCardinality(card)
+	} else {
+		if dst.buffers == nil {
+			dst.buffers = new(containerBuffers)
+		}
+		ra.reuse(dst.buffers)
+		op(dst, x1, x2, recycler{dst.buffers})
+		dst.buffers.clear()
+	}
+}
+
+// containerBuffers holds the containers of a bitmap that is overwritten
+// by the result of an operation, for the result to be computed in. They
+// are handed out in the order they were put, so that the containers of
+// a result shaped as the previous one get the buffers of the same size.
+type containerBuffers struct {
+	bitmaps    []*bitmapContainer
+	arrays     []*arrayContainer
+	nextBitmap int
+	nextArray  int
+}
+
+func (cb *containerBuffers) getBitmap() *bitmapContainer {
+	if cb.nextBitmap == len(cb.bitmaps) {
+		return newBitmapContainer()
+	}
+	bc := cb.bitmaps[cb.nextBitmap]
+	cb.bitmaps[cb.nextBitmap] = nil
+	cb.nextBitmap++
+	return bc
+}
+
+func (cb *containerBuffers) getArray(size int) *arrayContainer {
+	if cb.nextArray == len(cb.arrays) {
+		return newArrayContainerSize(size)
+	}
+	ac := cb.arrays[cb.nextArray]
+	cb.arrays[cb.nextArray] = nil
+	cb.nextArray++
+	if cap(ac.content) < size {
+		ac.content = make([]uint16, size)
+	}
+	ac.content = ac.content[:size]
+	return ac
+}
+
+func (cb *containerBuffers) put(c container) {
+	switch x := c.(type) {
+	case *bitmapContainer:
+		cb.bitmaps = append(cb.bitmaps, x)
+	case *arrayContainer:
+		cb.arrays = append(cb.arrays, x)
+	}
+}
+
+// clear drops the buffers that were not needed, for the garbage collector
+// to reclaim
+func (cb *containerBuffers) clear() {
+	for i := cb.nextBitmap; i < len(cb.bitmaps); i++ {
+		cb.bitmaps[i] = nil
+	}
+	for i := cb.nextArray; i < len(cb.arrays); i++ {
+		cb.arrays[i] = nil
+	}
+	cb.bitmaps, cb.arrays = cb.bitmaps[:0], cb.arrays[:0]
+	cb.nextBitmap, cb.nextArray = 0, 0
+}
@@ -0,0 +1,83 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInto(t *testing.T) {
+
+	ops := []struct {
+		plain func(x1, x2 *Bitmap) *Bitmap
+		into  func(dst, x1, x2 *Bitmap)
+	}{
+		{Or, OrInto},
+		{And, AndInto},
+		{Xor, XorInto},
+		{AndNot, AndNotInto},
+	}
+
+	Convey("computing into a bitmap should match the plain operations", t, func() {
+		r := rand.New(rand.NewSource(25))
+		dst := NewBitmap()
+		for i := 0; i < 30; i++ {
+			bitmaps := randomBitmapsForAggregation(r, 2)
+			x1, x2 := bitmaps[0], bitmaps[1]
+			x1.SetCopyOnWrite(i%3 == 0)
+			x2.SetCopyOnWrite(i%3 == 0)
+			c1, c2 := x1.Clone(), x2.Clone()
+			for _, op := range ops {
+				expect := op.plain(x1, x2)
+				op.into(dst, x1, x2)
+				So(dst.Equals(expect), ShouldBeTrue)
+				So(dst.GetCardinality(), ShouldEqual, expect.GetCardinality())
+				dst.Add(77)
+			}
+			So(x1.Equals(c1), ShouldBeTrue)
+			So(x2.Equals(c2), ShouldBeTrue)
+		}
+	})
+
+	Convey("the destination may be one of the operands", t, func() {
+		r := rand.New(rand.NewSource(26))
+		for _, op := range ops {
+			bitmaps := randomBitmapsForAggregation(r, 2)
+			x1, x2 := bitmaps[0], bitmaps[1]
+			expect := op.plain(x1, x2)
+			op.into(x1, x1, x2)
+			So(x1.Equals(expect), ShouldBeTrue)
+			expect = op.plain(x1, x2)
+			op.into(x2, x1, x2)
+			So(x2.Equals(expect), ShouldBeTrue)
+			expect = op.plain(x2, x2)
+			op.into(x2, x2, x2)
+			So(x2.Equals(expect), ShouldBeTrue)
+		}
+	})
+
+	Convey("computing into a bitmap should invalidate its iterators", t, func() {
+		dst := BitmapOf(1, 2, 3)
+		it := dst.Iterator()
+		AndInto(dst, BitmapOf(1), BitmapOf(1, 2))
+		So(func() { it.HasNext() }, ShouldPanic)
+	})
+
+	Convey("results of the same shape should reuse the buffers", t, func() {
+		r := rand.New(rand.NewSource(27))
+		x1, x2 := NewBitmap(), NewBitmap()
+		for i := 0; i < 100000; i++ {
+			x1.Add(uint32(r.Int31n(1 << 22)))
+			x2.Add(uint32(r.Int31n(1 << 24)))
+		}
+		dst := NewBitmap()
+		for _, op := range ops {
+			op.into(dst, x1, x2)
+			allocs := testing.AllocsPerRun(10, func() {
+				op.into(dst, x1, x2)
+			})
+			So(allocs, ShouldEqual, 0)
+		}
+	})
+}
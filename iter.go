@@ -0,0 +1,28 @@
+//go:build go1.23
+// +build go1.23
+
+package roaring
+
+import "iter"
+
+// Values returns an iterator over the integers contained in the
+// bitmap, in sorted order, for use in range loops:
+//
+//	for x := range rb.Values() {
+//		...
+//	}
+func (rb *Bitmap) Values() iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		rb.Iterate(yield)
+	}
+}
+
+// Runs returns an iterator over the maximal runs [start, last] of
+// consecutive integers contained in the bitmap, in sorted order. Run
+// containers are read directly; the runs of the other containers are
+// found as they are visited.
+func (rb *Bitmap) Runs() iter.Seq2[uint32, uint32] {
+	return func(yield func(start, last uint32) bool) {
+		rb.iterateRuns(yield)
+	}
+}
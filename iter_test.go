@@ -0,0 +1,85 @@
+//go:build go1.23
+// +build go1.23
+
+package roaring
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValues(t *testing.T) {
+	Convey("Values should range over the values in order", t, func() {
+		rb := iteratorTestBitmap()
+		var got []uint32
+		for x := range rb.Values() {
+			got = append(got, x)
+		}
+		So(got, ShouldResemble, rb.ToArray())
+
+		got = got[:0]
+		for x := range rb.Values() {
+			if x >= 1<<16 {
+				break
+			}
+			got = append(got, x)
+		}
+		So(got, ShouldResemble, []uint32{0, 3, 64, 65, 1<<16 - 1})
+
+		for range NewBitmap().Values() {
+			t.Error("an empty bitmap has no values")
+		}
+	})
+}
+
+func TestRuns(t *testing.T) {
+	Convey("Runs should yield the maximal runs of every container type", t, func() {
+		rb := iteratorTestBitmap()
+		// a run going across three containers, held by a bitmap, a run and an array container
+		for i := uint32(6 << 16); i < 7<<16; i += 2 {
+			rb.Add(i)
+		}
+		rb.AddRange(7<<16-100, 9<<16+5)
+		rb.RunOptimize()
+		rb.AddRange(8<<16, 9<<16)
+		rb.Add(9<<16 + 5)
+
+		// the maximal runs, found value by value
+		var expect [][2]uint32
+		for _, v := range rb.ToArray() {
+			if n := len(expect); n > 0 && expect[n-1][1]+1 == v {
+				expect[n-1][1] = v
+			} else {
+				expect = append(expect, [2]uint32{v, v})
+			}
+		}
+		var got [][2]uint32
+		for start, last := range rb.Runs() {
+			got = append(got, [2]uint32{start, last})
+		}
+		So(got, ShouldResemble, expect)
+		So(got[len(got)-2], ShouldResemble, [2]uint32{7<<16 - 100, 9<<16 + 5})
+
+		n := 0
+		for range rb.Runs() {
+			n++
+			if n == 3 {
+				break
+			}
+		}
+		So(n, ShouldEqual, 3)
+	})
+
+	Convey("Runs should panic before yielding a run once the bitmap is modified", t, func() {
+		rb := iteratorTestBitmap()
+		n := 0
+		So(func() {
+			for start := range rb.Runs() {
+				n++
+				rb.Remove(start)
+			}
+		}, ShouldPanic)
+		So(n, ShouldEqual, 1)
+	})
+}
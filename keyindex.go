@@ -0,0 +1,90 @@
+package roaring
+
+import (
+	"math/bits"
+)
+
+// keyIndexThreshold is the number of keys from which a roaringArray
+// finds its keys with a keyIndex rather than by a binary search
+const keyIndexThreshold = 4096
+
+// keyIndex finds the position of a key among the sorted keys of a
+// roaringArray in constant time. It holds the keys as a bitmap, and
+// for each word of the bitmap the number of keys in the words before,
+// so that the position of a key is that number plus a popcount.
+type keyIndex struct {
+	bits [1 << 16 / 64]uint64
+	// rank[w] is the number of keys in the words before w, for w < words
+	rank [1 << 16 / 64]int32
+	// all of the keys are in the words before words
+	words int
+	size  int32
+}
+
+// keyIndexSizeInBytes is the memory used by a keyIndex
+const keyIndexSizeInBytes = 1 << 16 / 64 * 12
+
+// find returns the position of key, or -(insertion point)-1 if it is
+// absent, as binarySearch does
+func (ki *keyIndex) find(key uint16) int {
+	w := int(key / 64)
+	if w >= ki.words {
+		return -int(ki.size) - 1
+	}
+	bit := uint64(1) << (key % 64)
+	i := int(ki.rank[w]) + bits.OnesCount64(ki.bits[w]&(bit-1))
+	if ki.bits[w]&bit == 0 {
+		return -i - 1
+	}
+	return i
+}
+
+// add adds a key that is absent. Adding the keys in increasing order
+// costs O(1) per key, as appending them to the roaringArray does.
+func (ki *keyIndex) add(key uint16) {
+	w := int(key / 64)
+	for ; ki.words <= w; ki.words++ {
+		ki.rank[ki.words] = ki.size
+	}
+	ki.bits[w] |= 1 << (key % 64)
+	for j := w + 1; j < ki.words; j++ {
+		ki.rank[j]++
+	}
+	ki.size++
+}
+
+// remove removes a key that is present
+func (ki *keyIndex) remove(key uint16) {
+	w := int(key / 64)
+	ki.bits[w] &^= 1 << (key % 64)
+	for j := w + 1; j < ki.words; j++ {
+		ki.rank[j]--
+	}
+	ki.size--
+}
+
+// rebuildKeyIndex builds the key index of the array anew, if it has
+// enough keys to need one, after its keys have been changed wholesale
+func (ra *roaringArray) rebuildKeyIndex() {
+	if len(ra.keys) < keyIndexThreshold {
+		ra.index = nil
+		return
+	}
+	if ra.index == nil {
+		ra.index = new(keyIndex)
+	} else {
+		*ra.index = keyIndex{}
+	}
+	for _, key := range ra.keys {
+		ra.index.add(key)
+	}
+}
+
+// keyAdded updates the key index after key has been added to the array
+func (ra *roaringArray) keyAdded(key uint16) {
+	if ra.index != nil {
+		ra.index.add(key)
+	} else if len(ra.keys) >= keyIndexThreshold {
+		ra.rebuildKeyIndex()
+	}
+}
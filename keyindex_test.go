@@ -0,0 +1,81 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// checkKeyIndex checks that the key index of rb, if any, agrees with a binary search
+func checkKeyIndex(rb *Bitmap) {
+	ra := &rb.highlowcontainer
+	if len(ra.keys) >= keyIndexThreshold {
+		So(ra.index, ShouldNotBeNil)
+	} else if len(ra.keys) < keyIndexThreshold/2 {
+		So(ra.index, ShouldBeNil)
+	}
+	if ra.index == nil {
+		return
+	}
+	for key := 0; key < 1<<16; key++ {
+		if got, expect := ra.index.find(uint16(key)), ra.binarySearch(0, int64(len(ra.keys)), uint16(key)); got != expect {
+			So(got, ShouldEqual, expect)
+		}
+	}
+}
+
+func TestKeyIndex(t *testing.T) {
+
+	Convey("the key index should switch on past the threshold and agree with a binary search", t, func() {
+		rb := NewBitmap()
+		for key := uint32(0); key < keyIndexThreshold-1; key++ {
+			rb.Add(key<<16 + key)
+		}
+		So(rb.highlowcontainer.index, ShouldBeNil)
+		rb.Add(60000 << 16)
+		So(rb.highlowcontainer.index, ShouldNotBeNil)
+		checkKeyIndex(rb)
+
+		r := rand.New(rand.NewSource(23))
+		expect := rb.Clone()
+		for i := 0; i < 2000; i++ {
+			x := uint32(r.Int63n(1 << 32))
+			switch r.Intn(4) {
+			case 0, 1:
+				rb.Add(x)
+				expect.Add(x)
+			case 2:
+				key := uint64(rb.highlowcontainer.keys[r.Intn(len(rb.highlowcontainer.keys))])
+				rb.RemoveRange(key<<16, (key+1)<<16)
+				expect.RemoveRange(key<<16, (key+1)<<16)
+			case 3:
+				So(rb.Contains(x), ShouldEqual, expect.Contains(x))
+			}
+		}
+		checkKeyIndex(rb)
+		So(rb.Equals(expect), ShouldBeTrue)
+
+		// operations that change the keys wholesale
+		rb.And(BitmapOf(5, 1<<16+1, 70<<16+70, 60000<<16))
+		checkKeyIndex(rb)
+		rb = Or(expect, BitmapOf(MaxUint32))
+		checkKeyIndex(rb)
+		rb.Xor(expect)
+		So(rb.ToArray(), ShouldResemble, []uint32{MaxUint32})
+		checkKeyIndex(rb)
+
+		buf, err := expect.ToBytes()
+		So(err, ShouldBeNil)
+		rb, err = FromBuffer(buf)
+		So(err, ShouldBeNil)
+		checkKeyIndex(rb)
+		So(rb.Clone().highlowcontainer.index, ShouldNotBeNil)
+		checkKeyIndex(rb.Clone())
+		checkKeyIndex(NewPersistentBitmap(rb).ToBitmap())
+
+		// removing most of the keys switches it off again
+		rb.RemoveRange(0, 63000<<16)
+		So(rb.highlowcontainer.index, ShouldBeNil)
+	})
+}
@@ -0,0 +1,386 @@
+package roaring
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// LazyBitmap is a read-only bitmap stored in the portable format
+// (see https://github.com/RoaringBitmap/RoaringFormatSpec) behind an
+// io.ReaderAt. Opening it reads only the descriptive header and the
+// container offsets; each container is then read the first time it
+// is needed and kept in memory afterwards. This allows querying
+// bitmap files that are much larger than one would want to load
+// with ReadFrom.
+//
+// To open a bitmap that starts at some offset within a larger file,
+// pass an io.SectionReader.
+//
+// Like Bitmap, a LazyBitmap is not safe for concurrent use.
+type LazyBitmap struct {
+	r io.ReaderAt
+
+	keys       []uint16
+	cards      []int
+	offsets    []int64
+	isRun      []bool
+	containers []container // nil until loaded
+
+	// err is the first error met while iterating
+	err error
+}
+
+// NewLazyBitmap reads the headers of the serialized bitmap found in r
+// and returns a LazyBitmap that reads containers from r on demand.
+// r must not change while the LazyBitmap is in use.
+func NewLazyBitmap(r io.ReaderAt) (*LazyBitmap, error) {
+	lb := &LazyBitmap{r: r}
+	if err := lb.readHeader(); err != nil {
+		return nil, err
+	}
+	return lb, nil
+}
+
+func (lb *LazyBitmap) readHeader() error {
+	pos := int64(0)
+	buf := make([]byte, 8)
+	if err := lb.readAt(buf[:4], pos); err != nil {
+		return fmt.Errorf("error in NewLazyBitmap: could not read initial cookie: %s", err)
+	}
+	pos += 4
+	cookie := binary.LittleEndian.Uint32(buf)
+	var size uint32
+	haveRunContainers := false
+	var isRun []byte
+	if cookie&0x0000FFFF == serialCookie {
+		haveRunContainers = true
+		size = uint32(uint16(cookie>>16) + 1)
+		isRun = make([]byte, (size+7)/8)
+		if err := lb.readAt(isRun, pos); err != nil {
+			return fmt.Errorf("error in NewLazyBitmap: could not read the "+
+				"runContainer bit flags of length %v bytes: %v", len(isRun), err)
+		}
+		pos += int64(len(isRun))
+	} else if cookie == serialCookieNoRunContainer {
+		if err := lb.readAt(buf[:4], pos); err != nil {
+			return fmt.Errorf("error in NewLazyBitmap: when reading size, got: %s", err)
+		}
+		pos += 4
+		size = binary.LittleEndian.Uint32(buf)
+	} else {
+		return fmt.Errorf("error in NewLazyBitmap: did not find expected serialCookie in header")
+	}
+	if size > 1<<16 {
+		return fmt.Errorf("error in NewLazyBitmap: it is logically impossible to have more than (1<<16) containers, got %d", size)
+	}
+	if size == 0 {
+		return nil
+	}
+
+	// descriptive header
+	keycard := make([]byte, 4*size)
+	if err := lb.readAt(keycard, pos); err != nil {
+		return fmt.Errorf("error in NewLazyBitmap: could not read the descriptive header: %s", err)
+	}
+	pos += int64(len(keycard))
+
+	lb.keys = make([]uint16, size)
+	lb.cards = make([]int, size)
+	lb.offsets = make([]int64, size)
+	lb.isRun = make([]bool, size)
+	lb.containers = make([]container, size)
+	for i := range lb.keys {
+		lb.keys[i] = binary.LittleEndian.Uint16(keycard[4*i:])
+		lb.cards[i] = int(binary.LittleEndian.Uint16(keycard[4*i+2:])) + 1
+		if i > 0 && lb.keys[i-1] >= lb.keys[i] {
+			return fmt.Errorf("error in NewLazyBitmap: keys are not in sorted order (%d >= %d)", lb.keys[i-1], lb.keys[i])
+		}
+		lb.isRun[i] = haveRunContainers && isRun[i/8]&(1<<uint(i%8)) != 0
+	}
+
+	// offset header
+	if !haveRunContainers || size >= noOffsetThreshold {
+		offsets := make([]byte, 4*size)
+		if err := lb.readAt(offsets, pos); err != nil {
+			return fmt.Errorf("error in NewLazyBitmap: could not read the offset header: %s", err)
+		}
+		for i := range lb.offsets {
+			lb.offsets[i] = int64(binary.LittleEndian.Uint32(offsets[4*i:]))
+		}
+		return nil
+	}
+
+	// small bitmaps with runs have no offset header, so we walk
+	// the (at most noOffsetThreshold-1) containers to find them
+	for i := range lb.offsets {
+		lb.offsets[i] = pos
+		if lb.isRun[i] {
+			if err := lb.readAt(buf[:2], pos); err != nil {
+				return fmt.Errorf("error in NewLazyBitmap: could not read the run count of container %d: %s", i, err)
+			}
+			pos += 2 + 4*int64(binary.LittleEndian.Uint16(buf))
+		} else {
+			pos += int64(getSizeInBytesFromCardinality(lb.cards[i]))
+		}
+	}
+	return nil
+}
+
+// readAt fills buf from the bytes at offset pos. An io.ReaderAt may
+// return io.EOF along with a full buffer when the read ends the input,
+// which is fine; a short read is reported as io.ErrUnexpectedEOF.
+func (lb *LazyBitmap) readAt(buf []byte, pos int64) error {
+	n, err := lb.r.ReadAt(buf, pos)
+	if n == len(buf) && (err == nil || err == io.EOF) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// container returns the container at index i, reading it from
+// the underlying io.ReaderAt if it has not been loaded yet.
+func (lb *LazyBitmap) container(i int) (container, error) {
+	if c := lb.containers[i]; c != nil {
+		return c, nil
+	}
+	var c container
+	pos := lb.offsets[i]
+	card := lb.cards[i]
+	if lb.isRun[i] {
+		var nr [2]byte
+		if err := lb.readAt(nr[:], pos); err != nil {
+			return nil, fmt.Errorf("error in LazyBitmap: could not read the run count of container %d: %s", i, err)
+		}
+		by := make([]byte, 4*int(binary.LittleEndian.Uint16(nr[:])))
+		if err := lb.readAt(by, pos+2); err != nil {
+			return nil, fmt.Errorf("error in LazyBitmap: could not read the runs of container %d: %s", i, err)
+		}
+		c = &runContainer16{iv: byteSliceAsInterval16Slice(by), card: int64(card)}
+	} else {
+		by := make([]byte, getSizeInBytesFromCardinality(card))
+		if err := lb.readAt(by, pos); err != nil {
+			return nil, fmt.Errorf("error in LazyBitmap: could not read container %d: %s", i, err)
+		}
+		if card > arrayDefaultMaxSize {
+			c = &bitmapContainer{cardinality: card, bitmap: byteSliceAsUint64Slice(by)}
+		} else {
+			c = &arrayContainer{content: byteSliceAsUint16Slice(by)}
+		}
+	}
+	lb.containers[i] = c
+	return c, nil
+}
+
+// getIndex returns the index of the container for key hb, or a negative value if there is none
+func (lb *LazyBitmap) getIndex(hb uint16) int {
+	lo, hi := 0, len(lb.keys)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if lb.keys[mid] < hb {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < len(lb.keys) && lb.keys[lo] == hb {
+		return lo
+	}
+	return -(lo + 1)
+}
+
+// Loaded returns the number of containers read so far, out of the total number of containers
+func (lb *LazyBitmap) Loaded() (loaded, total int) {
+	for _, c := range lb.containers {
+		if c != nil {
+			loaded++
+		}
+	}
+	return loaded, len(lb.containers)
+}
+
+// IsEmpty returns true if the bitmap is empty; it does not read any container
+func (lb *LazyBitmap) IsEmpty() bool {
+	return len(lb.keys) == 0
+}
+
+// GetCardinality returns the number of integers contained in the bitmap;
+// it only uses the header and does not read any container
+func (lb *LazyBitmap) GetCardinality() uint64 {
+	size := uint64(0)
+	for _, card := range lb.cards {
+		size += uint64(card)
+	}
+	return size
+}
+
+// Contains returns true if the integer is contained in the bitmap,
+// reading at most one container
+func (lb *LazyBitmap) Contains(x uint32) (bool, error) {
+	i := lb.getIndex(highbits(x))
+	if i < 0 {
+		return false, nil
+	}
+	c, err := lb.container(i)
+	if err != nil {
+		return false, err
+	}
+	return c.contains(lowbits(x)), nil
+}
+
+// Rank returns the number of integers that are smaller or equal to x,
+// reading at most one container
+func (lb *LazyBitmap) Rank(x uint32) (uint64, error) {
+	size := uint64(0)
+	for i, key := range lb.keys {
+		if key > highbits(x) {
+			return size, nil
+		}
+		if key < highbits(x) {
+			size += uint64(lb.cards[i])
+		} else {
+			c, err := lb.container(i)
+			if err != nil {
+				return 0, err
+			}
+			return size + uint64(c.rank(lowbits(x))), nil
+		}
+	}
+	return size, nil
+}
+
+// Select returns the xth integer in the bitmap, reading at most one container
+func (lb *LazyBitmap) Select(x uint32) (uint32, error) {
+	remaining := x
+	for i, card := range lb.cards {
+		if remaining >= uint32(card) {
+			remaining -= uint32(card)
+			continue
+		}
+		c, err := lb.container(i)
+		if err != nil {
+			return 0, err
+		}
+		return uint32(lb.keys[i])<<16 + uint32(c.selectInt(uint16(remaining))), nil
+	}
+	return 0, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, lb.GetCardinality())
+}
+
+// Iterator creates a new IntIterable to iterate over the integers contained
+// in the bitmap, in sorted order. Containers are read as the iteration
+// reaches them; if reading one fails, the iteration stops early and
+// the error is reported by Err.
+func (lb *LazyBitmap) Iterator() IntIterable {
+	p := &lazyIntIterator{lb: lb}
+	p.init()
+	return p
+}
+
+// Err returns the first error met by an iterator of this bitmap, if any
+func (lb *LazyBitmap) Err() error {
+	return lb.err
+}
+
+type lazyIntIterator struct {
+	pos  int
+	hs   uint32
+	iter shortIterable
+	lb   *LazyBitmap
+}
+
+// HasNext returns true if there are more integers to iterate over
+func (ii *lazyIntIterator) HasNext() bool {
+	return ii.iter != nil
+}
+
+func (ii *lazyIntIterator) init() {
+	ii.iter = nil
+	if ii.pos >= len(ii.lb.keys) {
+		return
+	}
+	c, err := ii.lb.container(ii.pos)
+	if err != nil {
+		if ii.lb.err == nil {
+			ii.lb.err = err
+		}
+		return
+	}
+	ii.iter = c.getShortIterator()
+	ii.hs = uint32(ii.lb.keys[ii.pos]) << 16
+}
+
+// Next returns the next integer
+func (ii *lazyIntIterator) Next() uint32 {
+	x := uint32(ii.iter.next()) | ii.hs
+	if !ii.iter.hasNext() {
+		ii.pos = ii.pos + 1
+		ii.init()
+	}
+	return x
+}
+
+// bitmapOf returns a Bitmap sharing the containers whose keys satisfy
+// keep, reading those that are not loaded yet. The containers are marked
+// as needing copy-on-write so that modifying the result never modifies lb.
+func (lb *LazyBitmap) bitmapOf(keep func(key uint16) bool) (*Bitmap, error) {
+	rb := NewBitmap()
+	rb.highlowcontainer.setCardinality(0)
+	for i, key := range lb.keys {
+		if keep != nil && !keep(key) {
+			continue
+		}
+		c, err := lb.container(i)
+		if err != nil {
+			return nil, err
+		}
+		rb.highlowcontainer.appendContainer(key, shareContainer(c), true)
+	}
+	return rb, nil
+}
+
+// ToBitmap reads all the containers and returns them as a Bitmap
+func (lb *LazyBitmap) ToBitmap() (*Bitmap, error) {
+	return lb.bitmapOf(nil)
+}
+
+// And computes the intersection between the bitmap and x2, only
+// reading the containers whose keys are also present in x2
+func (lb *LazyBitmap) And(x2 *Bitmap) (*Bitmap, error) {
+	x1, err := lb.bitmapOf(func(key uint16) bool {
+		return x2.highlowcontainer.getIndex(key) >= 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return And(x1, x2), nil
+}
+
+// Or computes the union between the bitmap and x2
+func (lb *LazyBitmap) Or(x2 *Bitmap) (*Bitmap, error) {
+	x1, err := lb.ToBitmap()
+	if err != nil {
+		return nil, err
+	}
+	return Or(x1, x2), nil
+}
+
+// Xor computes the symmetric difference between the bitmap and x2
+func (lb *LazyBitmap) Xor(x2 *Bitmap) (*Bitmap, error) {
+	x1, err := lb.ToBitmap()
+	if err != nil {
+		return nil, err
+	}
+	return Xor(x1, x2), nil
+}
+
+// AndNot computes the difference between the bitmap and x2
+func (lb *LazyBitmap) AndNot(x2 *Bitmap) (*Bitmap, error) {
+	x1, err := lb.ToBitmap()
+	if err != nil {
+		return nil, err
+	}
+	return AndNot(x1, x2), nil
+}
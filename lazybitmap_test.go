@@ -0,0 +1,195 @@
+package roaring
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// countingReaderAt counts the calls to ReadAt, and fails them once broken is set
+type countingReaderAt struct {
+	r      io.ReaderAt
+	reads  int
+	broken bool
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if c.broken {
+		return 0, errors.New("broken reader")
+	}
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+// eofReaderAt returns io.EOF along with the bytes of any read that reaches the end of the data
+type eofReaderAt []byte
+
+func (e eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := bytes.NewReader(e).ReadAt(p, off)
+	if err == nil && off+int64(n) == int64(len(e)) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func lazyTestBitmap(runs bool, ncontainers int) *Bitmap {
+	rb := NewBitmap()
+	for k := 0; k < ncontainers; k++ {
+		base := uint64(k) << 16
+		switch k % 3 {
+		case 0: // array
+			rb.AddMany([]uint32{uint32(base + 1), uint32(base + 100), uint32(base + 60000)})
+		case 1: // bitmap
+			for i := base; i < base+(1<<16); i += 5 {
+				rb.Add(uint32(i))
+			}
+		case 2: // run
+			rb.AddRange(base+10, base+20000)
+		}
+	}
+	if runs {
+		rb.RunOptimize()
+	}
+	return rb
+}
+
+func TestLazyBitmap(t *testing.T) {
+
+	Convey("LazyBitmap should agree with the serialized bitmap while reading only what it needs", t, func() {
+		for _, runs := range []bool{false, true} {
+			// 2 containers have no offset header when runs are present
+			for _, ncontainers := range []int{0, 2, 9} {
+				rb := lazyTestBitmap(runs, ncontainers)
+				buf, err := rb.ToBytes()
+				So(err, ShouldBeNil)
+				r := &countingReaderAt{r: bytes.NewReader(buf)}
+
+				lb, err := NewLazyBitmap(r)
+				So(err, ShouldBeNil)
+				So(lb.GetCardinality(), ShouldEqual, rb.GetCardinality())
+				So(lb.IsEmpty(), ShouldEqual, rb.IsEmpty())
+				loaded, total := lb.Loaded()
+				So(loaded, ShouldEqual, 0)
+				So(total, ShouldEqual, ncontainers)
+
+				for _, x := range []uint32{1, 5, 6, 100, 60000, 1<<16 + 5, 2<<16 + 19999, 2<<16 + 20000, 8<<16 + 10, 100 << 16} {
+					found, err := lb.Contains(x)
+					So(err, ShouldBeNil)
+					So(found, ShouldEqual, rb.Contains(x))
+					rank, err := lb.Rank(x)
+					So(err, ShouldBeNil)
+					So(rank, ShouldEqual, rb.Rank(x))
+				}
+				if ncontainers > 0 {
+					for _, x := range []uint32{0, 3, uint32(rb.GetCardinality() - 1)} {
+						v, err := lb.Select(x)
+						So(err, ShouldBeNil)
+						w, _ := rb.Select(x)
+						So(v, ShouldEqual, w)
+					}
+				}
+				_, err = lb.Select(uint32(rb.GetCardinality()))
+				So(err, ShouldNotBeNil)
+
+				// containers are only read once
+				reads := r.reads
+				lb.Contains(1)
+				So(r.reads, ShouldEqual, reads)
+
+				// And only reads the containers with keys 0, 1 and 2
+				other := BitmapOf(1, 100, 1<<16+5, 1<<16+6, 2<<16+30000)
+				fresh, err := NewLazyBitmap(r)
+				So(err, ShouldBeNil)
+				and, err := fresh.And(other)
+				So(err, ShouldBeNil)
+				So(and.Equals(And(rb, other)), ShouldBeTrue)
+				loaded, _ = fresh.Loaded()
+				So(loaded, ShouldBeLessThanOrEqualTo, 3)
+
+				or, err := lb.Or(other)
+				So(err, ShouldBeNil)
+				So(or.Equals(Or(rb, other)), ShouldBeTrue)
+				xor, err := lb.Xor(other)
+				So(err, ShouldBeNil)
+				So(xor.Equals(Xor(rb, other)), ShouldBeTrue)
+				andnot, err := lb.AndNot(other)
+				So(err, ShouldBeNil)
+				So(andnot.Equals(AndNot(rb, other)), ShouldBeTrue)
+
+				// modifying a result must not modify the lazy bitmap
+				or.RemoveRange(0, 1<<32)
+				all, err := lb.ToBitmap()
+				So(err, ShouldBeNil)
+				So(all.Equals(rb), ShouldBeTrue)
+
+				vals := []uint32{}
+				for it := lb.Iterator(); it.HasNext(); {
+					vals = append(vals, it.Next())
+				}
+				So(lb.Err(), ShouldBeNil)
+				So(vals, ShouldResemble, rb.ToArray())
+			}
+		}
+	})
+
+	Convey("LazyBitmap should accept io.EOF with a full read", t, func() {
+		for _, runs := range []bool{false, true} {
+			rb := lazyTestBitmap(runs, 5)
+			buf, err := rb.ToBytes()
+			So(err, ShouldBeNil)
+
+			lb, err := NewLazyBitmap(eofReaderAt(buf))
+			So(err, ShouldBeNil)
+			// the last container ends the input
+			found, err := lb.Contains(4<<16 + 20000)
+			So(err, ShouldBeNil)
+			So(found, ShouldBeTrue)
+			rb2, err := lb.ToBitmap()
+			So(err, ShouldBeNil)
+			So(rb2.Equals(rb), ShouldBeTrue)
+
+			// a short read of the last container is still an error
+			lb, err = NewLazyBitmap(eofReaderAt(buf[:len(buf)-1]))
+			So(err, ShouldBeNil)
+			_, err = lb.ToBitmap()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, io.ErrUnexpectedEOF.Error())
+		}
+	})
+
+	Convey("LazyBitmap should report read errors", t, func() {
+		buf, err := lazyTestBitmap(true, 5).ToBytes()
+		So(err, ShouldBeNil)
+
+		for i := 0; i < 12; i++ {
+			_, err = NewLazyBitmap(bytes.NewReader(buf[:i]))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, io.ErrUnexpectedEOF.Error())
+		}
+
+		r := &countingReaderAt{r: bytes.NewReader(buf)}
+		lb, err := NewLazyBitmap(r)
+		So(err, ShouldBeNil)
+		found, err := lb.Contains(1)
+		So(err, ShouldBeNil)
+		So(found, ShouldBeTrue)
+
+		r.broken = true
+		_, err = lb.Contains(1<<16 + 5)
+		So(err, ShouldNotBeNil)
+		_, err = lb.Rank(1<<16 + 5)
+		So(err, ShouldNotBeNil)
+		_, err = lb.ToBitmap()
+		So(err, ShouldNotBeNil)
+
+		n := 0
+		for it := lb.Iterator(); it.HasNext(); it.Next() {
+			n++
+		}
+		So(n, ShouldEqual, 3)
+		So(lb.Err(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,76 @@
+package roaring
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ParOr computes the union of the bitmaps using parallelism goroutines,
+// each of them handling a share of the container keys. A parallelism of
+// zero or less means runtime.NumCPU(). If ctx is done before the work is
+// complete, ParOr stops and returns ctx.Err().
+func ParOr(ctx context.Context, parallelism int, bitmaps ...*Bitmap) (*Bitmap, error) {
+	keys, groups := groupByKey(bitmaps)
+	return parAggregate(ctx, parallelism, keys, groups, orContainers)
+}
+
+// ParXor computes the symmetric difference of the bitmaps, that is the
+// values held by an odd number of them, using parallelism goroutines
+// (see ParOr).
+func ParXor(ctx context.Context, parallelism int, bitmaps ...*Bitmap) (*Bitmap, error) {
+	keys, groups := groupByKey(bitmaps)
+	return parAggregate(ctx, parallelism, keys, groups, xorContainers)
+}
+
+// ParAnd computes the intersection of the bitmaps using parallelism
+// goroutines (see ParOr). Only the keys held by every bitmap are
+// visited.
+func ParAnd(ctx context.Context, parallelism int, bitmaps ...*Bitmap) (*Bitmap, error) {
+	keys, groups := groupCommonKeys(bitmaps)
+	return parAggregate(ctx, parallelism, keys, groups, andContainers)
+}
+
+// ParAndNot computes the values of the first bitmap that are in none
+// of the others, using parallelism goroutines (see ParOr). Only the keys
+// of the first bitmap are visited.
+func ParAndNot(ctx context.Context, parallelism int, bitmaps ...*Bitmap) (*Bitmap, error) {
+	keys, groups := groupFirstKeys(bitmaps)
+	return parAggregate(ctx, parallelism, keys, groups, andNotContainers)
+}
+
+// parAggregate applies aggregate to each group of containers, spreading
+// the groups over parallelism goroutines, and assembles the non-empty
+// results in the order of keys. The containers of the groups are never
+// modified.
+func parAggregate(ctx context.Context, parallelism int, keys []uint16, groups [][]container,
+	aggregate func(cs []container) container) (*Bitmap, error) {
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > len(keys) {
+		parallelism = len(keys)
+	}
+	results := make([]container, len(keys))
+	next := int64(-1)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(keys) {
+					return
+				}
+				results[i] = aggregate(groups[i])
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return assembleAggregate(keys, results), nil
+}
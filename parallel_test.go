@@ -0,0 +1,94 @@
+package roaring
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// randomBitmapsForAggregation returns n bitmaps sharing some keys, with
+// containers of all kinds
+func randomBitmapsForAggregation(r *rand.Rand, n int) []*Bitmap {
+	bitmaps := make([]*Bitmap, n)
+	for i := range bitmaps {
+		rb := NewBitmap()
+		for k := 0; k < 8; k++ {
+			base := uint32(r.Intn(12)) << 16
+			switch r.Intn(3) {
+			case 0:
+				for j := r.Intn(3000); j > 0; j-- {
+					rb.Add(base + uint32(r.Intn(1<<16)))
+				}
+			case 1:
+				for j := 5000 + r.Intn(30000); j > 0; j-- {
+					rb.Add(base + uint32(r.Intn(1<<16)))
+				}
+			case 2:
+				start := uint64(base) + uint64(r.Intn(1<<16))
+				rb.AddRange(start, start+uint64(r.Intn(100000)))
+			}
+		}
+		rb.RunOptimize()
+		bitmaps[i] = rb
+	}
+	return bitmaps
+}
+
+func TestParallelAggregation(t *testing.T) {
+
+	Convey("the parallel aggregations should match the sequential ones", t, func() {
+		r := rand.New(rand.NewSource(8))
+		ctx := context.Background()
+		for _, n := range []int{1, 2, 3, 20} {
+			bitmaps := randomBitmapsForAggregation(r, n)
+			clones := make([]*Bitmap, n)
+			for i, bm := range bitmaps {
+				clones[i] = bm.Clone()
+			}
+			or, xor, and := FastOr(bitmaps...), HeapXor(bitmaps...), FastAnd(bitmaps...)
+			andNot := bitmaps[0].Clone()
+			for _, bm := range bitmaps[1:] {
+				andNot.AndNot(bm)
+			}
+			for _, parallelism := range []int{0, 1, 3, 100} {
+				for _, tc := range []struct {
+					par    func(context.Context, int, ...*Bitmap) (*Bitmap, error)
+					expect *Bitmap
+				}{
+					{ParOr, or},
+					{ParXor, xor},
+					{ParAnd, and},
+					{ParAndNot, andNot},
+				} {
+					got, err := tc.par(ctx, parallelism, bitmaps...)
+					So(err, ShouldBeNil)
+					So(got.Equals(tc.expect), ShouldBeTrue)
+					So(got.Validate(), ShouldBeNil)
+				}
+			}
+			// the inputs are left alone
+			for i, bm := range bitmaps {
+				So(bm.Equals(clones[i]), ShouldBeTrue)
+			}
+		}
+
+		for _, par := range []func(context.Context, int, ...*Bitmap) (*Bitmap, error){ParOr, ParXor, ParAnd, ParAndNot} {
+			got, err := par(ctx, 0)
+			So(err, ShouldBeNil)
+			So(got.IsEmpty(), ShouldBeTrue)
+		}
+	})
+
+	Convey("the parallel aggregations should stop when the context is done", t, func() {
+		bitmaps := randomBitmapsForAggregation(rand.New(rand.NewSource(9)), 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, par := range []func(context.Context, int, ...*Bitmap) (*Bitmap, error){ParOr, ParXor, ParAnd, ParAndNot} {
+			got, err := par(ctx, 4, bitmaps...)
+			So(err, ShouldEqual, context.Canceled)
+			So(got, ShouldBeNil)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package roaring
+
+// PersistentBitmap is an immutable bitmap. Its methods never modify it:
+// With, Without, Union and the like return new versions, which share the
+// containers they leave unchanged with the version they come from, so
+// that keeping many versions costs little more than their differences.
+// A version can be used by many goroutines at once.
+type PersistentBitmap struct {
+	// ra is never modified once the version is built. All of its
+	// containers are marked as needing copy-on-write, and their
+	// cardinality caches are filled, so reading them writes nothing.
+	ra roaringArray
+}
+
+// NewPersistentBitmap returns a version holding the values of rb
+func NewPersistentBitmap(rb *Bitmap) *PersistentBitmap {
+	return persist(rb.Clone())
+}
+
+// persist builds a version from rb, which must not be used afterwards
+func persist(rb *Bitmap) *PersistentBitmap {
+	ra := &rb.highlowcontainer
+	for _, c := range ra.containers {
+		if rc, ok := c.(*runContainer16); ok {
+			rc.cardinality()
+		}
+	}
+	ra.markAllAsNeedingCopyOnWrite()
+	ra.copyOnWrite = true
+	return &PersistentBitmap{ra: *ra}
+}
+
+// view returns a Bitmap reading the containers of pb, to be used
+// only as the argument of operations that do not modify it
+func (pb *PersistentBitmap) view() *Bitmap {
+	return &Bitmap{highlowcontainer: pb.ra}
+}
+
+// ToBitmap returns a Bitmap holding the values of pb. It shares the
+// containers of pb, copying them when it is first modified, so it can be
+// used as any other Bitmap.
+func (pb *PersistentBitmap) ToBitmap() *Bitmap {
+	rb := NewBitmap()
+	ra := &rb.highlowcontainer
+	ra.keys = make([]uint16, len(pb.ra.keys))
+	copy(ra.keys, pb.ra.keys)
+	ra.containers = make([]container, len(pb.ra.containers))
+	for i, c := range pb.ra.containers {
+		ra.containers[i] = shareContainer(c)
+	}
+	ra.needCopyOnWrite = make([]bool, len(pb.ra.containers))
+	ra.markAllAsNeedingCopyOnWrite()
+	ra.rebuildKeyIndex()
+	return rb
+}
+
+// Contains returns true if the integer is contained in the version
+func (pb *PersistentBitmap) Contains(x uint32) bool {
+	return pb.view().Contains(x)
+}
+
+// GetCardinality returns the number of integers contained in the version
+func (pb *PersistentBitmap) GetCardinality() uint64 {
+	return pb.view().GetCardinality()
+}
+
+// IsEmpty returns true if the version is empty
+func (pb *PersistentBitmap) IsEmpty() bool {
+	return len(pb.ra.keys) == 0
+}
+
+// Iterator creates a new IntIterable to iterate over the integers contained in the version, in sorted order
+func (pb *PersistentBitmap) Iterator() IntIterable {
+	return pb.view().Iterator()
+}
+
+// With returns a version that also holds x
+func (pb *PersistentBitmap) With(x uint32) *PersistentBitmap {
+	if pb.Contains(x) {
+		return pb
+	}
+	rb := pb.ToBitmap()
+	rb.Add(x)
+	return persist(rb)
+}
+
+// Without returns a version that does not hold x
+func (pb *PersistentBitmap) Without(x uint32) *PersistentBitmap {
+	if !pb.Contains(x) {
+		return pb
+	}
+	rb := pb.ToBitmap()
+	rb.Remove(x)
+	return persist(rb)
+}
+
+// WithRange returns a version that also holds the integers in [rangeStart, rangeEnd)
+func (pb *PersistentBitmap) WithRange(rangeStart, rangeEnd uint64) *PersistentBitmap {
+	rb := pb.ToBitmap()
+	rb.AddRange(rangeStart, rangeEnd)
+	return persist(rb)
+}
+
+// WithoutRange returns a version that does not hold the integers in [rangeStart, rangeEnd)
+func (pb *PersistentBitmap) WithoutRange(rangeStart, rangeEnd uint64) *PersistentBitmap {
+	rb := pb.ToBitmap()
+	rb.RemoveRange(rangeStart, rangeEnd)
+	return persist(rb)
+}
+
+// Union returns a version holding the integers of pb and of o
+func (pb *PersistentBitmap) Union(o *PersistentBitmap) *PersistentBitmap {
+	return persist(Or(pb.view(), o.view()))
+}
+
+// Intersection returns a version holding the integers both in pb and in o
+func (pb *PersistentBitmap) Intersection(o *PersistentBitmap) *PersistentBitmap {
+	return persist(And(pb.view(), o.view()))
+}
+
+// Difference returns a version holding the integers of pb that are not in o
+func (pb *PersistentBitmap) Difference(o *PersistentBitmap) *PersistentBitmap {
+	return persist(AndNot(pb.view(), o.view()))
+}
+
+// SymmetricDifference returns a version holding the integers in either pb or o, but not in both
+func (pb *PersistentBitmap) SymmetricDifference(o *PersistentBitmap) *PersistentBitmap {
+	return persist(Xor(pb.view(), o.view()))
+}
+
+// Diff returns the integers added and the integers removed going from
+// pb to newer. The containers the two versions share are skipped
+// without being looked at, so comparing a version with one derived from
+// it costs in proportion to their differences.
+func (pb *PersistentBitmap) Diff(newer *PersistentBitmap) (added, removed *Bitmap) {
+	added, removed = NewBitmap(), NewBitmap()
+	appendNonEmpty := func(rb *Bitmap, key uint16, c container) {
+		if c.getCardinality() > 0 {
+			rb.highlowcontainer.appendContainer(key, c, false)
+		}
+	}
+	ra1, ra2 := &pb.ra, &newer.ra
+	pos1, pos2 := 0, 0
+	for pos1 < len(ra1.keys) && pos2 < len(ra2.keys) {
+		k1, k2 := ra1.keys[pos1], ra2.keys[pos2]
+		switch {
+		case k1 < k2:
+			removed.highlowcontainer.appendContainer(k1, ra1.containers[pos1].clone(), false)
+			pos1++
+		case k1 > k2:
+			added.highlowcontainer.appendContainer(k2, ra2.containers[pos2].clone(), false)
+			pos2++
+		default:
+			c1, c2 := ra1.containers[pos1], ra2.containers[pos2]
+			if c1 != c2 {
+				appendNonEmpty(added, k1, c2.andNot(c1))
+				appendNonEmpty(removed, k1, c1.andNot(c2))
+			}
+			pos1++
+			pos2++
+		}
+	}
+	for ; pos1 < len(ra1.keys); pos1++ {
+		removed.highlowcontainer.appendContainer(ra1.keys[pos1], ra1.containers[pos1].clone(), false)
+	}
+	for ; pos2 < len(ra2.keys); pos2++ {
+		added.highlowcontainer.appendContainer(ra2.keys[pos2], ra2.containers[pos2].clone(), false)
+	}
+	return added, removed
+}
@@ -0,0 +1,130 @@
+package roaring
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPersistentBitmap(t *testing.T) {
+
+	Convey("new versions should leave the old ones unchanged", t, func() {
+		rb := iteratorTestBitmap()
+		v0 := NewPersistentBitmap(rb)
+		rb.Add(17) // rb and v0 are independent
+		So(v0.Contains(17), ShouldBeFalse)
+
+		v1 := v0.With(1<<16 + 1)
+		v2 := v1.Without(3).WithRange(10<<16, 11<<16+5)
+		v3 := v2.WithoutRange(0, 1<<16)
+		So(v0.With(3), ShouldEqual, v0)
+		So(v0.Without(1), ShouldEqual, v0)
+
+		expect := iteratorTestBitmap()
+		So(v0.ToBitmap().Equals(expect), ShouldBeTrue)
+		expect.Add(1<<16 + 1)
+		So(v1.ToBitmap().Equals(expect), ShouldBeTrue)
+		expect.Remove(3)
+		expect.AddRange(10<<16, 11<<16+5)
+		So(v2.ToBitmap().Equals(expect), ShouldBeTrue)
+		So(v2.GetCardinality(), ShouldEqual, expect.GetCardinality())
+		expect.RemoveRange(0, 1<<16)
+		So(v3.ToBitmap().Equals(expect), ShouldBeTrue)
+		So(v3.Contains(0), ShouldBeFalse)
+		So(v2.Contains(0), ShouldBeTrue)
+		So(NewPersistentBitmap(NewBitmap()).IsEmpty(), ShouldBeTrue)
+
+		var values []uint32
+		for it := v3.Iterator(); it.HasNext(); {
+			values = append(values, it.Next())
+		}
+		So(values, ShouldResemble, expect.ToArray())
+	})
+
+	Convey("new versions should share the containers they do not change", t, func() {
+		v0 := NewPersistentBitmap(iteratorTestBitmap())
+		v1 := v0.With(1<<16 + 1)
+		for i, key := range v0.ra.keys {
+			So(v1.ra.keys[i], ShouldEqual, key)
+			if key == 1 {
+				So(v1.ra.containers[i], ShouldNotEqual, v0.ra.containers[i])
+			} else {
+				So(v1.ra.containers[i], ShouldEqual, v0.ra.containers[i])
+			}
+		}
+	})
+
+	Convey("exported bitmaps can be modified without changing the versions", t, func() {
+		v0 := NewPersistentBitmap(iteratorTestBitmap())
+		rb := v0.ToBitmap()
+		rb.RemoveRange(0, 1<<32)
+		rb.Add(5)
+		So(v0.ToBitmap().Equals(iteratorTestBitmap()), ShouldBeTrue)
+		So(rb.ToArray(), ShouldResemble, []uint32{5})
+	})
+
+	Convey("set operations should match those of Bitmap", t, func() {
+		r := rand.New(rand.NewSource(17))
+		bitmaps := randomBitmapsForAggregation(r, 2)
+		a, b := bitmaps[0], bitmaps[1]
+		va, vb := NewPersistentBitmap(a), NewPersistentBitmap(b)
+		So(va.Union(vb).ToBitmap().Equals(Or(a, b)), ShouldBeTrue)
+		So(va.Intersection(vb).ToBitmap().Equals(And(a, b)), ShouldBeTrue)
+		So(va.Difference(vb).ToBitmap().Equals(AndNot(a, b)), ShouldBeTrue)
+		So(va.SymmetricDifference(vb).ToBitmap().Equals(Xor(a, b)), ShouldBeTrue)
+		So(va.ToBitmap().Equals(a), ShouldBeTrue)
+		So(vb.ToBitmap().Equals(b), ShouldBeTrue)
+	})
+
+	Convey("Diff should return the values added and removed between two versions", t, func() {
+		v0 := NewPersistentBitmap(iteratorTestBitmap())
+		v1 := v0.With(1<<16+1).Without(0).WithRange(20<<16, 20<<16+10).WithoutRange(3<<16, 4<<16)
+		added, removed := v0.Diff(v1)
+		So(added.Equals(AndNot(v1.ToBitmap(), v0.ToBitmap())), ShouldBeTrue)
+		So(removed.Equals(AndNot(v0.ToBitmap(), v1.ToBitmap())), ShouldBeTrue)
+		So(added.Contains(1<<16+1), ShouldBeTrue)
+		So(removed.Contains(0), ShouldBeTrue)
+		So(added.Validate(), ShouldBeNil)
+		So(removed.Validate(), ShouldBeNil)
+
+		added, removed = v1.Diff(v1)
+		So(added.IsEmpty() && removed.IsEmpty(), ShouldBeTrue)
+	})
+}
+
+// run with go test -race -run TestConcurrent
+func TestConcurrentPersistentBitmap(t *testing.T) {
+	Convey("versions can be read and derived from by many goroutines", t, func() {
+		v0 := NewPersistentBitmap(iteratorTestBitmap())
+		expect := iteratorTestBitmap()
+		versions := make([]*PersistentBitmap, 8)
+		var wg sync.WaitGroup
+		for g := range versions {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				v := v0
+				for i := 0; i < 100; i++ {
+					v = v.With(uint32(g)<<16 | uint32(i)).Without(uint32(3<<16 + 100*i))
+					v.GetCardinality()
+					v.Union(v0)
+				}
+				rb := v.ToBitmap()
+				rb.Add(MaxUint32 - 1)
+				versions[g] = v
+			}(g)
+		}
+		wg.Wait()
+		So(v0.ToBitmap().Equals(expect), ShouldBeTrue)
+		for g, v := range versions {
+			want := expect.Clone()
+			for i := 0; i < 100; i++ {
+				want.Add(uint32(g)<<16 | uint32(i))
+				want.Remove(uint32(3<<16 + 100*i))
+			}
+			So(v.ToBitmap().Equals(want), ShouldBeTrue)
+		}
+	})
+}
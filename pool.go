@@ -0,0 +1,442 @@
+package roaring
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ContainerPool recycles the memory of temporary bitmaps. Its And, Or,
+// Xor and AndNot compute their results in bitmaps and containers taken
+// from the pool, and Release hands them back once a result is no longer
+// needed, so that code computing and discarding many temporary bitmaps
+// puts little pressure on the garbage collector. Bitmaps created any
+// other way never use the pool.
+//
+// A ContainerPool may be used by several goroutines at once. The zero
+// value is an empty pool ready to use.
+type ContainerPool struct {
+	bitmaps sync.Pool // of *bitmapContainer
+	arrays  sync.Pool // of *arrayContainer
+	results sync.Pool // of *Bitmap, emptied
+}
+
+// NewContainerPool returns an empty pool
+func NewContainerPool() *ContainerPool {
+	return &ContainerPool{}
+}
+
+// Or computes the union between two bitmaps in containers taken from
+// the pool and returns the result, to be given back with Release
+func (p *ContainerPool) Or(x1, x2 *Bitmap) *Bitmap {
+	answer := p.newBitmap()
+	or(answer, x1, x2, recycler{p})
+	return answer
+}
+
+// And computes the intersection between two bitmaps in containers taken
+// from the pool and returns the result, to be given back with Release
+func (p *ContainerPool) And(x1, x2 *Bitmap) *Bitmap {
+	answer := p.newBitmap()
+	and(answer, x1, x2, recycler{p})
+	return answer
+}
+
+// Xor computes the symmetric difference between two bitmaps in containers
+// taken from the pool and returns the result, to be given back with Release
+func (p *ContainerPool) Xor(x1, x2 *Bitmap) *Bitmap {
+	answer := p.newBitmap()
+	xor(answer, x1, x2, recycler{p})
+	return answer
+}
+
+// AndNot computes the difference between two bitmaps in containers taken
+// from the pool and returns the result, to be given back with Release
+func (p *ContainerPool) AndNot(x1, x2 *Bitmap) *Bitmap {
+	answer := p.newBitmap()
+	andNot(answer, x1, x2, recycler{p})
+	return answer
+}
+
+// Release hands the containers of rb back to the pool rb was computed
+// from, after which neither rb nor any iterator over it may be used.
+// Containers that rb shares with other bitmaps are left to them. A
+// bitmap that does not come from a pool is only cleared.
+func (rb *Bitmap) Release() {
+	p := rb.pool
+	if p == nil {
+		rb.Clear()
+		return
+	}
+	ra := &rb.highlowcontainer
+	ra.reuse(p)
+	*ra = roaringArray{
+		keys:            ra.keys,
+		containers:      ra.containers,
+		needCopyOnWrite: ra.needCopyOnWrite,
+		modCount:        ra.modCount,
+	}
+	p.results.Put(rb)
+}
+
+// newBitmap returns an empty bitmap, recycled if possible
+func (p *ContainerPool) newBitmap() *Bitmap {
+	if rb, ok := p.results.Get().(*Bitmap); ok {
+		return rb
+	}
+	rb := NewBitmap()
+	rb.pool = p
+	return rb
+}
+
+// getBitmap returns a bitmap container whose words are all to be
+// overwritten by the caller
+func (p *ContainerPool) getBitmap() *bitmapContainer {
+	if bc, ok := p.bitmaps.Get().(*bitmapContainer); ok {
+		return bc
+	}
+	return newBitmapContainer()
+}
+
+// getArray returns an array container holding size values, all to be
+// overwritten by the caller
+func (p *ContainerPool) getArray(size int) *arrayContainer {
+	ac, ok := p.arrays.Get().(*arrayContainer)
+	if !ok {
+		return newArrayContainerSize(size)
+	}
+	if cap(ac.content) < size {
+		ac.content = make([]uint16, size)
+	}
+	ac.content = ac.content[:size]
+	return ac
+}
+
+// put recycles a container that no bitmap holds any more
+func (p *ContainerPool) put(c container) {
+	switch x := c.(type) {
+	case *bitmapContainer:
+		p.bitmaps.Put(x)
+	case *arrayContainer:
+		p.arrays.Put(x)
+	}
+}
+
+// allocator supplies the containers that the result of an operation is
+// computed in, and takes back those that are not needed any more
+type allocator interface {
+	getBitmap() *bitmapContainer
+	getArray(size int) *arrayContainer
+	put(c container)
+}
+
+// reuse empties the array, keeping its slices, and hands the containers
+// that it holds alone to a
+func (ra *roaringArray) reuse(a allocator) {
+	for i, c := range ra.containers {
+		if !ra.needCopyOnWrite[i] && atomic.LoadInt32(c.sharesCount()) == 0 {
+			a.put(c)
+		}
+		ra.containers[i] = nil
+	}
+	ra.modCount++
+	ra.keys = ra.keys[:0]
+	ra.containers = ra.containers[:0]
+	ra.needCopyOnWrite = ra.needCopyOnWrite[:0]
+	if ra.index != nil {
+		*ra.index = keyIndex{}
+	}
+}
+
+// recycler computes the results of operations in containers taken from
+// its allocator, or in new containers if it has none
+type recycler struct {
+	allocator
+}
+
+// discard recycles c, a result found to be empty
+func (r recycler) discard(c container) {
+	if r.allocator != nil {
+		r.put(c)
+	}
+}
+
+// clone returns a copy of c, made in a recycled container
+func (r recycler) clone(c container) container {
+	switch x := c.(type) {
+	case *bitmapContainer:
+		answer := r.getBitmap()
+		copy(answer.bitmap, x.bitmap)
+		answer.cardinality = x.cardinality
+		return answer
+	case *arrayContainer:
+		answer := r.getArray(len(x.content))
+		copy(answer.content, x.content)
+		return answer
+	}
+	return c.clone()
+}
+
+// appendCopy appends the container of sa at startingindex to ra, as
+// ra.appendCopy does, but copying it in a recycled container
+func (r recycler) appendCopy(ra *roaringArray, sa roaringArray, startingindex int) {
+	if r.allocator == nil || (ra.copyOnWrite && sa.copyOnWrite) || sa.needsCopyOnWrite(startingindex) {
+		ra.appendCopy(sa, startingindex)
+		return
+	}
+	ra.appendContainer(sa.keys[startingindex], r.clone(sa.containers[startingindex]), false)
+}
+
+func (r recycler) appendCopyMany(ra *roaringArray, sa roaringArray, startingindex, end int) {
+	for i := startingindex; i < end; i++ {
+		r.appendCopy(ra, sa, i)
+	}
+}
+
+// The following compute the result of an operation between two containers
+// in recycled containers, when r has an allocator and both are bitmap or
+// array containers; otherwise they leave it to the containers.
+
+func (r recycler) or(c1, c2 container) container {
+	if r.allocator != nil {
+		switch x1 := c1.(type) {
+		case *bitmapContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.orBitmap(x1, x2)
+			case *arrayContainer:
+				return r.orArray(x1, x2)
+			}
+		case *arrayContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.orArray(x2, x1)
+			case *arrayContainer:
+				if len(x1.content)+len(x2.content) > arrayDefaultMaxSize {
+					return r.unionOfArrays(x1, x2, false)
+				}
+				answer := r.getArray(len(x1.content) + len(x2.content))
+				answer.content = answer.content[:union2by2(x1.content, x2.content, answer.content)]
+				return answer
+			}
+		}
+	}
+	return c1.or(c2)
+}
+
+func (r recycler) orBitmap(bc, value2 *bitmapContainer) container {
+	answer := r.getBitmap()
+	for k := 0; k < len(answer.bitmap); k++ {
+		answer.bitmap[k] = bc.bitmap[k] | value2.bitmap[k]
+	}
+	answer.computeCardinality()
+	if answer.isFull() {
+		r.put(answer)
+		return newRunContainer16Range(0, MaxUint16)
+	}
+	return answer
+}
+
+func (r recycler) orArray(bc *bitmapContainer, value2 *arrayContainer) container {
+	answer := r.clone(bc).(*bitmapContainer)
+	for _, v := range value2.content {
+		i := uint(v) >> 6
+		bef := answer.bitmap[i]
+		aft := bef | (uint64(1) << (v % 64))
+		answer.bitmap[i] = aft
+		answer.cardinality += int((bef - aft) >> 63)
+	}
+	return answer
+}
+
+func (r recycler) and(c1, c2 container) container {
+	if r.allocator != nil {
+		switch x1 := c1.(type) {
+		case *bitmapContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.andBitmap(x1, x2)
+			case *arrayContainer:
+				return r.andArray(x2, x1, 1)
+			}
+		case *arrayContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.andArray(x1, x2, 1)
+			case *arrayContainer:
+				answer := r.getArray(min(len(x1.content), len(x2.content)))
+				answer.content = answer.content[:intersection2by2(x1.content, x2.content, answer.content)]
+				return answer
+			}
+		}
+	}
+	return c1.and(c2)
+}
+
+func (r recycler) andBitmap(bc, value2 *bitmapContainer) container {
+	newcardinality := int(popcntAndSlice(bc.bitmap, value2.bitmap))
+	if newcardinality > arrayDefaultMaxSize {
+		answer := r.getBitmap()
+		for k := 0; k < len(answer.bitmap); k++ {
+			answer.bitmap[k] = bc.bitmap[k] & value2.bitmap[k]
+		}
+		answer.cardinality = newcardinality
+		return answer
+	}
+	ac := r.getArray(newcardinality)
+	fillArrayAND(ac.content, bc.bitmap, value2.bitmap)
+	return ac
+}
+
+// andArray keeps the values of ac whose bit in bc is keep, so that it
+// computes their intersection for keep 1 and their difference for keep 0
+func (r recycler) andArray(ac *arrayContainer, bc *bitmapContainer, keep uint64) container {
+	answer := r.getArray(len(ac.content))
+	pos := 0
+	for _, v := range ac.content {
+		answer.content[pos] = v
+		pos += int(bc.bitValue(v) ^ keep ^ 1)
+	}
+	answer.content = answer.content[:pos]
+	return answer
+}
+
+// unionOfArrays computes the union of two array containers, or their
+// symmetric difference if xor is set, in a bitmap container
+func (r recycler) unionOfArrays(ac, value2 *arrayContainer, xor bool) container {
+	answer := r.getBitmap()
+	for k := range answer.bitmap {
+		answer.bitmap[k] = 0
+	}
+	for _, v := range ac.content {
+		answer.bitmap[uint(v)>>6] |= uint64(1) << (v % 64)
+	}
+	for _, v := range value2.content {
+		if xor {
+			answer.bitmap[uint(v)>>6] ^= uint64(1) << (v % 64)
+		} else {
+			answer.bitmap[uint(v)>>6] |= uint64(1) << (v % 64)
+		}
+	}
+	answer.computeCardinality()
+	return r.minimized(answer)
+}
+
+// minimized returns bc, or an array container with its values if it
+// has few enough of them, in which case bc is recycled
+func (r recycler) minimized(bc *bitmapContainer) container {
+	if bc.cardinality > arrayDefaultMaxSize {
+		return bc
+	}
+	ac := r.getArray(bc.cardinality)
+	bc.fillArray(ac.content)
+	r.put(bc)
+	return ac
+}
+
+func (r recycler) xor(c1, c2 container) container {
+	if r.allocator != nil {
+		switch x1 := c1.(type) {
+		case *bitmapContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.xorBitmap(x1, x2)
+			case *arrayContainer:
+				return r.xorArray(x1, x2)
+			}
+		case *arrayContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.xorArray(x2, x1)
+			case *arrayContainer:
+				if len(x1.content)+len(x2.content) > arrayDefaultMaxSize {
+					return r.unionOfArrays(x1, x2, true)
+				}
+				answer := r.getArray(len(x1.content) + len(x2.content))
+				answer.content = answer.content[:exclusiveUnion2by2(x1.content, x2.content, answer.content)]
+				return answer
+			}
+		}
+	}
+	return c1.xor(c2)
+}
+
+func (r recycler) xorArray(bc *bitmapContainer, value2 *arrayContainer) container {
+	answer := r.clone(bc).(*bitmapContainer)
+	for _, v := range value2.content {
+		i := uint(v) >> 6
+		mask := uint64(1) << (v % 64)
+		answer.cardinality += 1 - 2*int((answer.bitmap[i]&mask)>>(v%64))
+		answer.bitmap[i] ^= mask
+	}
+	return r.minimized(answer)
+}
+
+func (r recycler) xorBitmap(bc, value2 *bitmapContainer) container {
+	newCardinality := int(popcntXorSlice(bc.bitmap, value2.bitmap))
+	if newCardinality > arrayDefaultMaxSize {
+		answer := r.getBitmap()
+		for k := 0; k < len(answer.bitmap); k++ {
+			answer.bitmap[k] = bc.bitmap[k] ^ value2.bitmap[k]
+		}
+		answer.cardinality = newCardinality
+		if answer.isFull() {
+			r.put(answer)
+			return newRunContainer16Range(0, MaxUint16)
+		}
+		return answer
+	}
+	ac := r.getArray(newCardinality)
+	fillArrayXOR(ac.content, bc.bitmap, value2.bitmap)
+	return ac
+}
+
+func (r recycler) andNot(c1, c2 container) container {
+	if r.allocator != nil {
+		switch x1 := c1.(type) {
+		case *bitmapContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.andNotBitmap(x1, x2)
+			case *arrayContainer:
+				return r.andNotArray(x1, x2)
+			}
+		case *arrayContainer:
+			switch x2 := c2.(type) {
+			case *bitmapContainer:
+				return r.andArray(x1, x2, 0)
+			case *arrayContainer:
+				answer := r.getArray(len(x1.content))
+				answer.content = answer.content[:difference(x1.content, x2.content, answer.content)]
+				return answer
+			}
+		}
+	}
+	return c1.andNot(c2)
+}
+
+func (r recycler) andNotBitmap(bc, value2 *bitmapContainer) container {
+	newCardinality := int(popcntMaskSlice(bc.bitmap, value2.bitmap))
+	if newCardinality > arrayDefaultMaxSize {
+		answer := r.getBitmap()
+		for k := 0; k < len(answer.bitmap); k++ {
+			answer.bitmap[k] = bc.bitmap[k] &^ value2.bitmap[k]
+		}
+		answer.cardinality = newCardinality
+		return answer
+	}
+	ac := r.getArray(newCardinality)
+	fillArrayANDNOT(ac.content, bc.bitmap, value2.bitmap)
+	return ac
+}
+
+func (r recycler) andNotArray(bc *bitmapContainer, value2 *arrayContainer) container {
+	answer := r.clone(bc).(*bitmapContainer)
+	for _, v := range value2.content {
+		i := uint(v) >> 6
+		oldv := answer.bitmap[i]
+		newv := oldv &^ (uint64(1) << (v % 64))
+		answer.bitmap[i] = newv
+		answer.cardinality -= int((oldv ^ newv) >> (v % 64))
+	}
+	return r.minimized(answer)
+}
@@ -0,0 +1,60 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContainerPool(t *testing.T) {
+
+	Convey("pooled operations should match the plain ones", t, func() {
+		r := rand.New(rand.NewSource(24))
+		pool := NewContainerPool()
+		ops := []struct {
+			plain  func(x1, x2 *Bitmap) *Bitmap
+			pooled func(x1, x2 *Bitmap) *Bitmap
+		}{
+			{Or, pool.Or},
+			{And, pool.And},
+			{Xor, pool.Xor},
+			{AndNot, pool.AndNot},
+		}
+		for i := 0; i < 30; i++ {
+			bitmaps := randomBitmapsForAggregation(r, 2)
+			x1, x2 := bitmaps[0], bitmaps[1]
+			// shared containers must be left to the bitmaps sharing them
+			x1.SetCopyOnWrite(i%3 == 0)
+			x2.SetCopyOnWrite(i%3 == 0)
+			c1, c2 := x1.Clone(), x2.Clone()
+			for _, op := range ops {
+				expect := op.plain(x1, x2)
+				got := op.pooled(x1, x2)
+				So(got.Equals(expect), ShouldBeTrue)
+				So(got.GetCardinality(), ShouldEqual, expect.GetCardinality())
+				got.Add(77)
+				got.Release()
+			}
+			So(x1.Equals(c1), ShouldBeTrue)
+			So(x2.Equals(c2), ShouldBeTrue)
+		}
+	})
+
+	Convey("a released bitmap should be recycled empty", t, func() {
+		var pool ContainerPool
+		rb := pool.Or(BitmapOf(1, 2, 1<<20), BitmapOf(3))
+		rb.Release()
+		rb = pool.And(BitmapOf(5, 6), BitmapOf(6, 7))
+		So(rb.ToArray(), ShouldResemble, []uint32{6})
+		rb.Release()
+	})
+
+	Convey("releasing a bitmap from no pool should clear it", t, func() {
+		rb := BitmapOf(1, 2, 3)
+		rb.Release()
+		So(rb.IsEmpty(), ShouldBeTrue)
+		rb.Add(4)
+		So(rb.GetCardinality(), ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,68 @@
+package roaring
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RankIndex speeds up Rank and Select on a bitmap with many containers.
+// It holds the cumulative cardinalities of the containers, so that both
+// take O(log n) instead of summing the cardinalities every time. The
+// index is rebuilt lazily, on the first call after its bitmap changes,
+// and a rebuild walks all n containers: it pays off when many queries
+// come between changes, while a query after every change costs O(n)
+// just like Bitmap.Rank and Bitmap.Select.
+// Like the bitmap, it must not be used by several goroutines at once.
+type RankIndex struct {
+	rb *Bitmap
+	// the modification count of the bitmap when cumulative was built
+	modCount uint64
+	// cumulative[i] is the number of integers in the containers before
+	// the i-th; cumulative[len(keys)] is the cardinality of the bitmap
+	cumulative []uint64
+}
+
+// NewRankIndex returns an index over the bitmap rb
+func NewRankIndex(rb *Bitmap) *RankIndex {
+	return &RankIndex{rb: rb}
+}
+
+// update rebuilds the index if the bitmap changed since it was built
+func (ri *RankIndex) update() {
+	ra := &ri.rb.highlowcontainer
+	if ri.cumulative != nil && ri.modCount == ra.modCount {
+		return
+	}
+	ri.cumulative = append(ri.cumulative[:0], 0)
+	size := uint64(0)
+	for _, c := range ra.containers {
+		size += uint64(c.getCardinality())
+		ri.cumulative = append(ri.cumulative, size)
+	}
+	ri.modCount = ra.modCount
+}
+
+// Rank returns the number of integers that are smaller or equal to x
+func (ri *RankIndex) Rank(x uint32) uint64 {
+	ri.update()
+	ra := &ri.rb.highlowcontainer
+	i := ra.getIndex(highbits(x))
+	if i < 0 {
+		return ri.cumulative[-i-1]
+	}
+	return ri.cumulative[i] + uint64(ra.getContainerAtIndex(i).rank(lowbits(x)))
+}
+
+// Select returns the xth integer in the bitmap
+func (ri *RankIndex) Select(x uint32) (uint32, error) {
+	ri.update()
+	ra := &ri.rb.highlowcontainer
+	n := len(ra.keys)
+	if ri.cumulative[n] <= uint64(x) {
+		return 0, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, ri.cumulative[n])
+	}
+	// the container holding it is the first to end after it
+	i := sort.Search(n, func(i int) bool { return ri.cumulative[i+1] > uint64(x) })
+	remaining := uint64(x) - ri.cumulative[i]
+	return uint32(ra.getKeyAtIndex(i))<<16 + uint32(ra.getContainerAtIndex(i).selectInt(uint16(remaining))), nil
+}
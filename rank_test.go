@@ -0,0 +1,69 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRankIndex(t *testing.T) {
+
+	Convey("the index should agree with Rank and Select", t, func() {
+		rb := iteratorTestBitmap()
+		ri := NewRankIndex(rb)
+		check := func() {
+			for _, x := range []uint32{0, 1, 3, 4, 1<<16 - 1, 1 << 16, 1<<16 + 7, 2 << 16, 3<<16 + 100, 5 << 16, MaxUint32 - 1, MaxUint32} {
+				So(ri.Rank(x), ShouldEqual, rb.Rank(x))
+			}
+			card := rb.GetCardinality()
+			for x := uint64(0); x < card; x += 37 {
+				got, err := ri.Select(uint32(x))
+				So(err, ShouldBeNil)
+				expect, _ := rb.Select(uint32(x))
+				So(got, ShouldEqual, expect)
+			}
+			if card > 0 {
+				got, _ := ri.Select(uint32(card - 1))
+				So(got, ShouldEqual, rb.Maximum())
+			}
+			_, err := ri.Select(uint32(card))
+			So(err, ShouldNotBeNil)
+		}
+		check()
+
+		// the index follows the modifications of the bitmap
+		rb.Add(4)
+		rb.RemoveRange(1<<16, 1<<16+100)
+		check()
+		rb.Or(BitmapOf(7<<16, 9<<16))
+		check()
+		rb.Clear()
+		check()
+		So(ri.Rank(17), ShouldEqual, 0)
+	})
+
+	Convey("SelectMany should select each of the ranks", t, func() {
+		rb := iteratorTestBitmap()
+		values := rb.ToArray()
+		r := rand.New(rand.NewSource(21))
+		var ranks []uint32
+		for x := uint32(0); x < uint32(len(values)); x += uint32(r.Intn(100)) {
+			ranks = append(ranks, x, x)
+		}
+		got, err := rb.SelectMany(ranks)
+		So(err, ShouldBeNil)
+		So(len(got), ShouldEqual, len(ranks))
+		for j, x := range ranks {
+			So(got[j], ShouldEqual, values[x])
+		}
+
+		_, err = rb.SelectMany([]uint32{3, 2})
+		So(err, ShouldNotBeNil)
+		_, err = rb.SelectMany([]uint32{0, uint32(len(values))})
+		So(err, ShouldNotBeNil)
+		got, err = rb.SelectMany(nil)
+		So(err, ShouldBeNil)
+		So(got, ShouldBeEmpty)
+	})
+}
@@ -42,6 +42,8 @@ import (
 	"fmt"
 	"sort"
 	"unsafe"
+
+	"github.com/tinylib/msgp/msgp"
 )
 
 //go:generate msgp -unexported
@@ -57,21 +59,94 @@ type runContainer32 struct {
 }
 
 // interval32 is the internal to runContainer32
-// structure that maintains the individual [Start, last]
-// closed intervals.
+// structure that maintains the individual [start, last]
+// closed intervals. We store the length rather than
+// the last value so that the in-memory layout matches
+// the serialized format, which lets a runContainer32
+// point directly at serialized bytes (see FromBuffer).
 type interval32 struct {
-	start uint32
-	last  uint32
+	start  uint32
+	length uint32 // length is last - start, so a run of one integer has length 0
+}
+
+// newInterval32Range returns the interval32 [start, last].
+func newInterval32Range(start, last uint32) interval32 {
+	if last < start {
+		panic(fmt.Sprintf("last (%d) cannot be smaller than start (%d)", last, start))
+	}
+	return interval32{
+		start,
+		last - start,
+	}
 }
 
 // runlen returns the count of integers in the interval.
 func (iv interval32) runlen() int64 {
-	return 1 + int64(iv.last) - int64(iv.start)
+	return int64(iv.length) + 1
+}
+
+// last returns the last integer of the interval.
+func (iv interval32) last() uint32 {
+	return iv.start + iv.length
+}
+
+//msgp:ignore interval32
+
+// msgpInterval32 is the msgp wire form of an interval32.
+// The msgp encoding predates the switch to storing the
+// length, so it keeps writing the last value.
+type msgpInterval32 struct {
+	start uint32
+	last  uint32
+}
+
+func (iv interval32) toMsgp() msgpInterval32 {
+	return msgpInterval32{start: iv.start, last: iv.last()}
+}
+
+func (m msgpInterval32) toInterval() interval32 {
+	return interval32{start: m.start, length: m.last - m.start}
+}
+
+// DecodeMsg implements msgp.Decodable
+func (iv *interval32) DecodeMsg(dc *msgp.Reader) error {
+	var m msgpInterval32
+	if err := m.DecodeMsg(dc); err != nil {
+		return err
+	}
+	*iv = m.toInterval()
+	return nil
+}
+
+// EncodeMsg implements msgp.Encodable
+func (iv interval32) EncodeMsg(en *msgp.Writer) error {
+	return iv.toMsgp().EncodeMsg(en)
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (iv interval32) MarshalMsg(b []byte) ([]byte, error) {
+	return iv.toMsgp().MarshalMsg(b)
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (iv *interval32) UnmarshalMsg(bts []byte) ([]byte, error) {
+	var m msgpInterval32
+	o, err := m.UnmarshalMsg(bts)
+	if err != nil {
+		return o, err
+	}
+	*iv = m.toInterval()
+	return o, nil
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (iv interval32) Msgsize() int {
+	return iv.toMsgp().Msgsize()
 }
 
 // String produces a human viewable string of the contents.
 func (iv interval32) String() string {
-	return fmt.Sprintf("[%d, %d]", iv.start, iv.last)
+	return fmt.Sprintf("[%d, %d]", iv.start, iv.last())
 }
 
 func ivalString32(iv []interval32) string {
@@ -79,7 +154,7 @@ func ivalString32(iv []interval32) string {
 	var j int
 	var p interval32
 	for j, p = range iv {
-		s += fmt.Sprintf("%v:[%d, %d], ", j, p.start, p.last)
+		s += fmt.Sprintf("%v:[%d, %d], ", j, p.start, p.last())
 	}
 	return s
 }
@@ -117,7 +192,7 @@ type addHelper32 struct {
 }
 
 func (ah *addHelper32) storeIval(runstart, runlen uint32) {
-	mi := interval32{start: runstart, last: runstart + runlen}
+	mi := interval32{start: runstart, length: runlen}
 	ah.m = append(ah.m, mi)
 }
 
@@ -145,7 +220,7 @@ func (ah *addHelper32) add(cur, prev uint32, i int) {
 // newRunContainerRange makes a new container made of just the specified closed interval [rangestart,rangelast]
 func newRunContainer32Range(rangestart uint32, rangelast uint32) *runContainer32 {
 	rc := &runContainer32{}
-	rc.iv = append(rc.iv, interval32{start: rangestart, last: rangelast})
+	rc.iv = append(rc.iv, newInterval32Range(rangestart, rangelast))
 	return rc
 }
 
@@ -173,7 +248,7 @@ func newRunContainer32FromVals(alreadySorted bool, vals ...uint32) *runContainer
 	case n == 0:
 		// nothing more
 	case n == 1:
-		ah.m = append(ah.m, interval32{start: vals[0], last: vals[0]})
+		ah.m = append(ah.m, newInterval32Range(vals[0], vals[0]))
 		ah.actuallyAdded++
 	default:
 		ah.runstart = vals[0]
@@ -232,13 +307,13 @@ func newRunContainer32FromBitmapContainer(bc *bitmapContainer) *runContainer32 {
 			// a final unterminated run of 1s
 			runEnd = wordSizeInBits + longCtr*64
 			rc.iv[runCount].start = uint32(runStart)
-			rc.iv[runCount].last = uint32(runEnd) - 1
+			rc.iv[runCount].length = uint32(runEnd) - 1 - uint32(runStart)
 			return rc
 		}
 		localRunEnd := countTrailingZerosDeBruijn(^curWordWith1s)
 		runEnd = localRunEnd + longCtr*64
 		rc.iv[runCount].start = uint32(runStart)
-		rc.iv[runCount].last = uint32(runEnd) - 1
+		rc.iv[runCount].length = uint32(runEnd) - 1 - uint32(runStart)
 		runCount++
 		// now, zero out everything right of runEnd.
 		curWord = curWordWith1s & (curWordWith1s + 1)
@@ -263,7 +338,7 @@ func newRunContainer32FromArray(arr *arrayContainer) *runContainer32 {
 	case n == 0:
 		// nothing more
 	case n == 1:
-		ah.m = append(ah.m, interval32{start: uint32(arr.content[0]), last: uint32(arr.content[0])})
+		ah.m = append(ah.m, newInterval32Range(uint32(arr.content[0]), uint32(arr.content[0])))
 		ah.actuallyAdded++
 	default:
 		ah.runstart = uint32(arr.content[0])
@@ -301,10 +376,10 @@ func (rc *runContainer32) set(alreadySorted bool, vals ...uint32) {
 // contiguous and so can be merged into
 // a single interval.
 func canMerge32(a, b interval32) bool {
-	if int64(a.last)+1 < int64(b.start) {
+	if int64(a.last())+1 < int64(b.start) {
 		return false
 	}
-	return int64(b.last)+1 >= int64(a.start)
+	return int64(b.last())+1 >= int64(a.start)
 }
 
 // haveOverlap differs from canMerge in that
@@ -313,10 +388,10 @@ func canMerge32(a, b interval32) bool {
 // it would be the empty set, and we return
 // false).
 func haveOverlap32(a, b interval32) bool {
-	if int64(a.last)+1 <= int64(b.start) {
+	if int64(a.last())+1 <= int64(b.start) {
 		return false
 	}
-	return int64(b.last)+1 > int64(a.start)
+	return int64(b.last())+1 > int64(a.start)
 }
 
 // mergeInterval32s joins a and b into a
@@ -330,10 +405,10 @@ func mergeInterval32s(a, b interval32) (res interval32) {
 	} else {
 		res.start = a.start
 	}
-	if b.last > a.last {
-		res.last = b.last
+	if b.last() > a.last() {
+		res.length = b.last() - res.start
 	} else {
-		res.last = a.last
+		res.length = a.last() - res.start
 	}
 	return
 }
@@ -351,10 +426,10 @@ func intersectInterval32s(a, b interval32) (res interval32, isEmpty bool) {
 	} else {
 		res.start = a.start
 	}
-	if b.last < a.last {
-		res.last = b.last
+	if b.last() < a.last() {
+		res.length = b.last() - res.start
 	} else {
-		res.last = a.last
+		res.length = a.last() - res.start
 	}
 	return
 }
@@ -392,12 +467,12 @@ func (rc *runContainer32) union(b *runContainer32) *runContainer32 {
 			mergedUpdated := false
 			if canMerge32(cura, merged) {
 				merged = mergeInterval32s(cura, merged)
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				mergedUpdated = true
 			}
 			if canMerge32(curb, merged) {
 				merged = mergeInterval32s(curb, merged)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				mergedUpdated = true
 			}
 			if !mergedUpdated {
@@ -420,8 +495,8 @@ func (rc *runContainer32) union(b *runContainer32) *runContainer32 {
 			} else {
 				merged = mergeInterval32s(cura, curb)
 				mergedUsed = true
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 			}
 		}
 	}
@@ -440,7 +515,7 @@ func (rc *runContainer32) union(b *runContainer32) *runContainer32 {
 				cura = rc.iv[na]
 				if canMerge32(cura, merged) {
 					merged = mergeInterval32s(cura, merged)
-					na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+					na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				} else {
 					break aAdds
 				}
@@ -454,7 +529,7 @@ func (rc *runContainer32) union(b *runContainer32) *runContainer32 {
 				curb = b.iv[nb]
 				if canMerge32(curb, merged) {
 					merged = mergeInterval32s(curb, merged)
-					nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+					nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				} else {
 					break bAdds
 				}
@@ -506,18 +581,18 @@ func (rc *runContainer32) unionCardinality(b *runContainer32) uint64 {
 			mergedUpdated := false
 			if canMerge32(cura, merged) {
 				merged = mergeInterval32s(cura, merged)
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				mergedUpdated = true
 			}
 			if canMerge32(curb, merged) {
 				merged = mergeInterval32s(curb, merged)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				mergedUpdated = true
 			}
 			if !mergedUpdated {
 				// we know that merged is disjoint from cura and curb
 				//m = append(m, merged)
-				answer += uint64(merged.last) - uint64(merged.start) + 1
+				answer += uint64(merged.last()) - uint64(merged.start) + 1
 				mergedUsed = false
 			}
 			continue
@@ -526,19 +601,19 @@ func (rc *runContainer32) unionCardinality(b *runContainer32) uint64 {
 			// !mergedUsed
 			if !canMerge32(cura, curb) {
 				if cura.start < curb.start {
-					answer += uint64(cura.last) - uint64(cura.start) + 1
+					answer += uint64(cura.last()) - uint64(cura.start) + 1
 					//m = append(m, cura)
 					na++
 				} else {
-					answer += uint64(curb.last) - uint64(curb.start) + 1
+					answer += uint64(curb.last()) - uint64(curb.start) + 1
 					//m = append(m, curb)
 					nb++
 				}
 			} else {
 				merged = mergeInterval32s(cura, curb)
 				mergedUsed = true
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 			}
 		}
 	}
@@ -557,7 +632,7 @@ func (rc *runContainer32) unionCardinality(b *runContainer32) uint64 {
 				cura = rc.iv[na]
 				if canMerge32(cura, merged) {
 					merged = mergeInterval32s(cura, merged)
-					na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+					na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				} else {
 					break aAdds
 				}
@@ -571,7 +646,7 @@ func (rc *runContainer32) unionCardinality(b *runContainer32) uint64 {
 				curb = b.iv[nb]
 				if canMerge32(curb, merged) {
 					merged = mergeInterval32s(curb, merged)
-					nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+					nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				} else {
 					break bAdds
 				}
@@ -580,13 +655,13 @@ func (rc *runContainer32) unionCardinality(b *runContainer32) uint64 {
 		}
 
 		//m = append(m, merged)
-		answer += uint64(merged.last) - uint64(merged.start) + 1
+		answer += uint64(merged.last()) - uint64(merged.start) + 1
 	}
 	for _, r := range rc.iv[na:] {
-		answer += uint64(r.last) - uint64(r.start) + 1
+		answer += uint64(r.last()) - uint64(r.start) + 1
 	}
 	for _, r := range b.iv[nb:] {
-		answer += uint64(r.last) - uint64(r.start) + 1
+		answer += uint64(r.last()) - uint64(r.start) + 1
 	}
 	return answer
 }
@@ -638,7 +713,7 @@ toploop:
 	for acuri < numa && bcuri < numb {
 		pass++
 
-		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover32(astart, int64(a.iv[acuri].last), bstart, int64(b.iv[bcuri].last))
+		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover32(astart, int64(a.iv[acuri].last()), bstart, int64(b.iv[bcuri].last()))
 
 		if !isOverlap {
 			switch {
@@ -747,7 +822,7 @@ toploop:
 	for acuri < numa && bcuri < numb {
 		pass++
 
-		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover32(astart, int64(a.iv[acuri].last), bstart, int64(b.iv[bcuri].last))
+		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover32(astart, int64(a.iv[acuri].last()), bstart, int64(b.iv[bcuri].last()))
 
 		if !isOverlap {
 			switch {
@@ -771,7 +846,7 @@ toploop:
 
 		} else {
 			// isOverlap
-			answer += int64(intersection.last) - int64(intersection.start) + 1
+			answer += int64(intersection.last()) - int64(intersection.start) + 1
 			switch {
 			case isLeftoverA:
 				// note that we change astart without advancing acuri,
@@ -846,7 +921,7 @@ func (rc *runContainer32) numIntervals() int {
 //
 //  c) whichInterval32 is set to the minimum index of rc.iv
 //     which comes strictly before the key;
-//     so  rc.iv[whichInterval32].last < key,
+//     so  rc.iv[whichInterval32].last() < key,
 //     and  if whichInterval32+1 exists, then key < rc.iv[whichInterval32+1].start
 //     (Note that whichInterval32+1 won't exist when
 //     whichInterval32 is the last interval.)
@@ -908,7 +983,7 @@ func (rc *runContainer32) search(key int64, opts *searchOptions) (whichInterval3
 	if below == n {
 		// all falses => key is >= start of all interval32s
 		// ... so does it belong to the last interval32?
-		if key < int64(rc.iv[n-1].last)+1 {
+		if key < int64(rc.iv[n-1].last())+1 {
 			// yes, it belongs to the last interval32
 			alreadyPresent = true
 			return
@@ -929,7 +1004,7 @@ func (rc *runContainer32) search(key int64, opts *searchOptions) (whichInterval3
 	//        key is <  rc.iv[below].start
 
 	// is key in below-1 interval32?
-	if key >= int64(rc.iv[below-1].start) && key < int64(rc.iv[below-1].last)+1 {
+	if key >= int64(rc.iv[below-1].start) && key < int64(rc.iv[below-1].last())+1 {
 		// yes, it is. key is in below-1 interval32.
 		alreadyPresent = true
 		return
@@ -964,7 +1039,7 @@ func (rc *runContainer32) AsSlice() []uint32 {
 	s := make([]uint32, rc.cardinality())
 	j := 0
 	for _, p := range rc.iv {
-		for i := p.start; i <= p.last; i++ {
+		for i := p.start; i <= p.last(); i++ {
 			s[j] = uint32(i)
 			j++
 		}
@@ -1049,21 +1124,22 @@ func (rc *runContainer32) Add(k uint32) (wasNew bool) {
 		if n > 0 {
 			if rc.iv[0].start == k+1 {
 				rc.iv[0].start = k
+				rc.iv[0].length++
 				return
 			}
 		}
 		// nope, k stands alone, starting the new first interval32.
-		rc.iv = append([]interval32{{start: k, last: k}}, rc.iv...)
+		rc.iv = append([]interval32{newInterval32Range(k, k)}, rc.iv...)
 		return
 	}
 
 	// are we off the end? handle both index == n and index == n-1:
 	if index >= n-1 {
-		if int64(rc.iv[n-1].last)+1 == k64 {
-			rc.iv[n-1].last++
+		if int64(rc.iv[n-1].last())+1 == k64 {
+			rc.iv[n-1].length++
 			return
 		}
-		rc.iv = append(rc.iv, interval32{start: k, last: k})
+		rc.iv = append(rc.iv, newInterval32Range(k, k))
 		return
 	}
 
@@ -1078,18 +1154,18 @@ func (rc *runContainer32) Add(k uint32) (wasNew bool) {
 	right := index + 1
 
 	// are we fusing left and right by adding k?
-	if int64(rc.iv[left].last)+1 == k64 && int64(rc.iv[right].start) == k64+1 {
+	if int64(rc.iv[left].last())+1 == k64 && int64(rc.iv[right].start) == k64+1 {
 		// fuse into left
-		rc.iv[left].last = rc.iv[right].last
+		rc.iv[left].length = rc.iv[right].last() - rc.iv[left].start
 		// remove redundant right
 		rc.iv = append(rc.iv[:left+1], rc.iv[right+1:]...)
 		return
 	}
 
 	// are we an addition to left?
-	if int64(rc.iv[left].last)+1 == k64 {
+	if int64(rc.iv[left].last())+1 == k64 {
 		// yes
-		rc.iv[left].last++
+		rc.iv[left].length++
 		return
 	}
 
@@ -1097,11 +1173,12 @@ func (rc *runContainer32) Add(k uint32) (wasNew bool) {
 	if int64(rc.iv[right].start) == k64+1 {
 		// yes
 		rc.iv[right].start = k
+		rc.iv[right].length++
 		return
 	}
 
 	// k makes a standalone new interval32, inserted in the middle
-	tail := append([]interval32{{start: k, last: k}}, rc.iv[right:]...)
+	tail := append([]interval32{newInterval32Range(k, k)}, rc.iv[right:]...)
 	rc.iv = append(rc.iv[:left+1], tail...)
 	return
 }
@@ -1154,7 +1231,7 @@ func (ri *runIterator32) next() uint32 {
 		ri.curIndex = 0
 	} else {
 		ri.curPosInIndex++
-		if int64(ri.rc.iv[ri.curIndex].start)+int64(ri.curPosInIndex) == int64(ri.rc.iv[ri.curIndex].last)+1 {
+		if int64(ri.rc.iv[ri.curIndex].start)+int64(ri.curPosInIndex) == int64(ri.rc.iv[ri.curIndex].last())+1 {
 			ri.curPosInIndex = 0
 			ri.curIndex++
 		}
@@ -1203,7 +1280,7 @@ func (rc *runContainer32) deleteAt(curIndex *int64, curPosInIndex *uint32, curSe
 	// are we first, last, or in the middle of our interval32?
 	switch {
 	case pos == 0:
-		if int64(rc.iv[ci].start) == int64(rc.iv[ci].last) {
+		if rc.iv[ci].length == 0 {
 			// our interval disappears
 			rc.iv = append(rc.iv[:ci], rc.iv[ci+1:]...)
 			// curIndex stays the same, since the delete did
@@ -1211,27 +1288,24 @@ func (rc *runContainer32) deleteAt(curIndex *int64, curPosInIndex *uint32, curSe
 			*curPosInIndex = 0
 		} else {
 			rc.iv[ci].start++ // no longer overflowable
+			rc.iv[ci].length--
 		}
 	case int64(pos) == rc.iv[ci].runlen()-1:
 		// last
-		rc.iv[ci].last--
+		rc.iv[ci].length--
 		// our interval32 cannot disappear, else we would have been pos == 0, case first above.
 		(*curPosInIndex)--
 		// if we leave *curIndex alone, then Next() will work properly even after the delete.
 	default:
 		//middle
 		// split into two, adding an interval32
-		new0 := interval32{
-			start: rc.iv[ci].start,
-			last:  rc.iv[ci].start + *curPosInIndex - 1}
+		new0 := newInterval32Range(rc.iv[ci].start, rc.iv[ci].start+*curPosInIndex-1)
 
 		new1start := int64(rc.iv[ci].start) + int64(*curPosInIndex) + 1
 		if new1start > int64(MaxUint32) {
 			panic("overflow?!?!")
 		}
-		new1 := interval32{
-			start: uint32(new1start),
-			last:  rc.iv[ci].last}
+		new1 := newInterval32Range(uint32(new1start), rc.iv[ci].last())
 		tail := append([]interval32{new0, new1}, rc.iv[ci+1:]...)
 		rc.iv = append(rc.iv[:ci], tail...)
 		// update curIndex and curPosInIndex
@@ -1264,14 +1338,14 @@ func intersectWithLeftover32(astart, alast, bstart, blast int64) (isOverlap, isL
 	case blast < alast:
 		isLeftoverA = true
 		leftoverstart = int64(blast) + 1
-		intersection.last = uint32(blast)
+		intersection.length = uint32(blast) - intersection.start
 	case alast < blast:
 		isLeftoverB = true
 		leftoverstart = int64(alast) + 1
-		intersection.last = uint32(alast)
+		intersection.length = uint32(alast) - intersection.start
 	default:
 		// alast == blast
-		intersection.last = uint32(alast)
+		intersection.length = uint32(alast) - intersection.start
 	}
 
 	return
@@ -1315,7 +1389,7 @@ func (rc *runContainer32) selectInt32(j uint32) int {
 
 	var offset int64
 	for k := range rc.iv {
-		nextOffset := offset + rc.iv[k].runlen() + 1
+		nextOffset := offset + rc.iv[k].runlen()
 		if nextOffset > int64(j) {
 			return int(int64(rc.iv[k].start) + (int64(j) - offset))
 		}
@@ -1327,19 +1401,19 @@ func (rc *runContainer32) selectInt32(j uint32) int {
 // helper for invert
 func (rc *runContainer32) invertlastInterval(origin uint32, lastIdx int) []interval32 {
 	cur := rc.iv[lastIdx]
-	if cur.last == MaxUint32 {
+	if cur.last() == MaxUint32 {
 		if cur.start == origin {
 			return nil // empty container
 		}
-		return []interval32{{start: origin, last: cur.start - 1}}
+		return []interval32{newInterval32Range(origin, cur.start-1)}
 	}
 	if cur.start == origin {
-		return []interval32{{start: cur.last + 1, last: MaxUint32}}
+		return []interval32{newInterval32Range(cur.last()+1, MaxUint32)}
 	}
 	// invert splits
 	return []interval32{
-		{start: origin, last: cur.start - 1},
-		{start: cur.last + 1, last: MaxUint32},
+		newInterval32Range(origin, cur.start-1),
+		newInterval32Range(cur.last()+1, MaxUint32),
 	}
 }
 
@@ -1351,7 +1425,7 @@ func (rc *runContainer32) invert() *runContainer32 {
 	var m []interval32
 	switch ni {
 	case 0:
-		return &runContainer32{iv: []interval32{{0, MaxUint32}}}
+		return &runContainer32{iv: []interval32{newInterval32Range(0, MaxUint32)}}
 	case 1:
 		return &runContainer32{iv: rc.invertlastInterval(0, 0)}
 	}
@@ -1366,28 +1440,28 @@ func (rc *runContainer32) invert() *runContainer32 {
 		}
 		// INVAR: i and cur are not the last interval, there is a next at i+1
 		//
-		// ........[cur.start, cur.last] ...... [next.start, next.last]....
+		// ........[cur.start, cur.last()] ...... [next.start, next.last()]....
 		//    ^                             ^                           ^
 		//   (a)                           (b)                         (c)
 		//
 		// Now: we add interval (a); but if (a) is empty, for cur.start==0, we skip it.
 		if cur.start > 0 {
-			m = append(m, interval32{start: uint32(invstart), last: cur.start - 1})
+			m = append(m, newInterval32Range(uint32(invstart), cur.start-1))
 		}
-		invstart = int64(cur.last + 1)
+		invstart = int64(cur.last() + 1)
 	}
 	return &runContainer32{iv: m}
 }
 
 func (iv interval32) equal(b interval32) bool {
 	if iv.start == b.start {
-		return iv.last == b.last
+		return iv.length == b.length
 	}
 	return false
 }
 
 func (iv interval32) isSuperSetOf(b interval32) bool {
-	return iv.start <= b.start && b.last <= iv.last
+	return iv.start <= b.start && b.last() <= iv.last()
 }
 
 func (iv interval32) subtractInterval(del interval32) (left []interval32, delcount int64) {
@@ -1401,14 +1475,14 @@ func (iv interval32) subtractInterval(del interval32) (left []interval32, delcou
 	}
 
 	switch {
-	case isect.start > iv.start && isect.last < iv.last:
-		new0 := interval32{start: iv.start, last: isect.start - 1}
-		new1 := interval32{start: isect.last + 1, last: iv.last}
+	case isect.start > iv.start && isect.last() < iv.last():
+		new0 := newInterval32Range(iv.start, isect.start-1)
+		new1 := newInterval32Range(isect.last()+1, iv.last())
 		return []interval32{new0, new1}, isect.runlen()
 	case isect.start == iv.start:
-		return []interval32{{start: isect.last + 1, last: iv.last}}, isect.runlen()
+		return []interval32{newInterval32Range(isect.last()+1, iv.last())}, isect.runlen()
 	default:
-		return []interval32{{start: iv.start, last: isect.start - 1}}, isect.runlen()
+		return []interval32{newInterval32Range(iv.start, isect.start-1)}, isect.runlen()
 	}
 }
 
@@ -1421,16 +1495,14 @@ func (rc *runContainer32) isubtract(del interval32) {
 	}
 
 	_, isEmpty := intersectInterval32s(
-		interval32{
-			start: rc.iv[0].start,
-			last:  rc.iv[n-1].last,
-		}, del)
+		newInterval32Range(rc.iv[0].start, rc.iv[n-1].last()),
+		del)
 	if isEmpty {
 		return // done
 	}
 	// INVAR there is some intersection between rc and del
 	istart, startAlready, _ := rc.search(int64(del.start), nil)
-	ilast, lastAlready, _ := rc.search(int64(del.last), nil)
+	ilast, lastAlready, _ := rc.search(int64(del.last()), nil)
 	rc.card = -1
 	if istart == -1 {
 		if ilast == n-1 && !lastAlready {
@@ -1498,22 +1570,22 @@ func (rc *runContainer32) isubtract(del interval32) {
 		//
 		//  c) istart is set to the minimum index of rc.iv
 		//     which comes strictly before the del.start;
-		//     so  del.start > rc.iv[istart].last,
+		//     so  del.start > rc.iv[istart].last(),
 		//     and  if istart+1 exists, then del.start < rc.iv[istart+1].startx
 
-		// if del.last is not present, then ilast is
+		// if del.last() is not present, then ilast is
 		// set as follows:
 		//
-		//  a) ilast == n-1 if del.last is beyond our
+		//  a) ilast == n-1 if del.last() is beyond our
 		//     last interval32 in rc.iv;
 		//
-		//  b) ilast == -1 if del.last is before our first
+		//  b) ilast == -1 if del.last() is before our first
 		//     interval32 in rc.iv;
 		//
 		//  c) ilast is set to the minimum index of rc.iv
-		//     which comes strictly before the del.last;
-		//     so  del.last > rc.iv[ilast].last,
-		//     and  if ilast+1 exists, then del.last < rc.iv[ilast+1].start
+		//     which comes strictly before the del.last();
+		//     so  del.last() > rc.iv[ilast].last(),
+		//     and  if ilast+1 exists, then del.last() < rc.iv[ilast+1].start
 
 		// INVAR: istart >= 0
 		pre := rc.iv[:istart+1]
@@ -1571,7 +1643,7 @@ func (rc *runContainer32) isubtract(del interval32) {
 func (rc *runContainer32) AndNotRunContainer32(b *runContainer32) *runContainer32 {
 
 	if len(b.iv) == 0 || len(rc.iv) == 0 {
-		return rc
+		return rc.Clone()
 	}
 
 	dst := newRunContainer32()
@@ -1581,9 +1653,9 @@ func (rc *runContainer32) AndNotRunContainer32(b *runContainer32) *runContainer3
 	a := rc
 
 	astart := a.iv[apos].start
-	alast := a.iv[apos].last
+	alast := a.iv[apos].last()
 	bstart := b.iv[bpos].start
-	blast := b.iv[bpos].last
+	blast := b.iv[bpos].last()
 
 	alen := len(a.iv)
 	blen := len(b.iv)
@@ -1592,18 +1664,18 @@ func (rc *runContainer32) AndNotRunContainer32(b *runContainer32) *runContainer3
 		switch {
 		case alast < bstart:
 			// output the first run
-			dst.iv = append(dst.iv, interval32{start: uint32(astart), last: uint32(alast)})
+			dst.iv = append(dst.iv, newInterval32Range(astart, alast))
 			apos++
 			if apos < alen {
 				astart = a.iv[apos].start
-				alast = a.iv[apos].last
+				alast = a.iv[apos].last()
 			}
 		case blast < astart:
 			// exit the second run
 			bpos++
 			if bpos < blen {
 				bstart = b.iv[bpos].start
-				blast = b.iv[bpos].last
+				blast = b.iv[bpos].last()
 			}
 		default:
 			//   a: [             ]
@@ -1611,7 +1683,7 @@ func (rc *runContainer32) AndNotRunContainer32(b *runContainer32) *runContainer3
 			// alast >= bstart
 			// blast >= astart
 			if astart < bstart {
-				dst.iv = append(dst.iv, interval32{start: uint32(astart), last: uint32(bstart - 1)})
+				dst.iv = append(dst.iv, newInterval32Range(astart, bstart-1))
 			}
 			if alast > blast {
 				astart = blast + 1
@@ -1619,13 +1691,13 @@ func (rc *runContainer32) AndNotRunContainer32(b *runContainer32) *runContainer3
 				apos++
 				if apos < alen {
 					astart = a.iv[apos].start
-					alast = a.iv[apos].last
+					alast = a.iv[apos].last()
 				}
 			}
 		}
 	}
 	if apos < alen {
-		dst.iv = append(dst.iv, interval32{start: uint32(astart), last: uint32(alast)})
+		dst.iv = append(dst.iv, newInterval32Range(astart, alast))
 		apos++
 		if apos < alen {
 			dst.iv = append(dst.iv, a.iv[apos:]...)
@@ -42,6 +42,8 @@ import (
 	"fmt"
 	"sort"
 	"unsafe"
+
+	"github.com/tinylib/msgp/msgp"
 )
 
 //go:generate msgp -unexported
@@ -52,26 +54,99 @@ type runContainer16 struct {
 	iv   []interval16
 	card int64
 
-	// avoid allocation during search
-	myOpts searchOptions `msg:"-"`
+	// the number of bitmaps sharing the container, beyond the first
+	shares int32 `msg:"-"`
 }
 
 // interval16 is the internal to runContainer16
-// structure that maintains the individual [Start, last]
-// closed intervals.
+// structure that maintains the individual [start, last]
+// closed intervals. We store the length rather than
+// the last value so that the in-memory layout matches
+// the serialized format, which lets a runContainer16
+// point directly at serialized bytes (see FromBuffer).
 type interval16 struct {
-	start uint16
-	last  uint16
+	start  uint16
+	length uint16 // length is last - start, so a run of one integer has length 0
+}
+
+// newInterval16Range returns the interval16 [start, last].
+func newInterval16Range(start, last uint16) interval16 {
+	if last < start {
+		panic(fmt.Sprintf("last (%d) cannot be smaller than start (%d)", last, start))
+	}
+	return interval16{
+		start,
+		last - start,
+	}
 }
 
 // runlen returns the count of integers in the interval.
 func (iv interval16) runlen() int64 {
-	return 1 + int64(iv.last) - int64(iv.start)
+	return int64(iv.length) + 1
+}
+
+// last returns the last integer of the interval.
+func (iv interval16) last() uint16 {
+	return iv.start + iv.length
+}
+
+//msgp:ignore interval16
+
+// msgpInterval16 is the msgp wire form of an interval16.
+// The msgp encoding predates the switch to storing the
+// length, so it keeps writing the last value.
+type msgpInterval16 struct {
+	start uint16
+	last  uint16
+}
+
+func (iv interval16) toMsgp() msgpInterval16 {
+	return msgpInterval16{start: iv.start, last: iv.last()}
+}
+
+func (m msgpInterval16) toInterval() interval16 {
+	return interval16{start: m.start, length: m.last - m.start}
+}
+
+// DecodeMsg implements msgp.Decodable
+func (iv *interval16) DecodeMsg(dc *msgp.Reader) error {
+	var m msgpInterval16
+	if err := m.DecodeMsg(dc); err != nil {
+		return err
+	}
+	*iv = m.toInterval()
+	return nil
+}
+
+// EncodeMsg implements msgp.Encodable
+func (iv interval16) EncodeMsg(en *msgp.Writer) error {
+	return iv.toMsgp().EncodeMsg(en)
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (iv interval16) MarshalMsg(b []byte) ([]byte, error) {
+	return iv.toMsgp().MarshalMsg(b)
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (iv *interval16) UnmarshalMsg(bts []byte) ([]byte, error) {
+	var m msgpInterval16
+	o, err := m.UnmarshalMsg(bts)
+	if err != nil {
+		return o, err
+	}
+	*iv = m.toInterval()
+	return o, nil
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (iv interval16) Msgsize() int {
+	return iv.toMsgp().Msgsize()
 }
 
 // String produces a human viewable string of the contents.
 func (iv interval16) String() string {
-	return fmt.Sprintf("[%d, %d]", iv.start, iv.last)
+	return fmt.Sprintf("[%d, %d]", iv.start, iv.last())
 }
 
 func ivalString16(iv []interval16) string {
@@ -79,7 +154,7 @@ func ivalString16(iv []interval16) string {
 	var j int
 	var p interval16
 	for j, p = range iv {
-		s += fmt.Sprintf("%v:[%d, %d], ", j, p.start, p.last)
+		s += fmt.Sprintf("%v:[%d, %d], ", j, p.start, p.last())
 	}
 	return s
 }
@@ -117,7 +192,7 @@ type addHelper16 struct {
 }
 
 func (ah *addHelper16) storeIval(runstart, runlen uint16) {
-	mi := interval16{start: runstart, last: runstart + runlen}
+	mi := interval16{start: runstart, length: runlen}
 	ah.m = append(ah.m, mi)
 }
 
@@ -145,7 +220,7 @@ func (ah *addHelper16) add(cur, prev uint16, i int) {
 // newRunContainerRange makes a new container made of just the specified closed interval [rangestart,rangelast]
 func newRunContainer16Range(rangestart uint16, rangelast uint16) *runContainer16 {
 	rc := &runContainer16{}
-	rc.iv = append(rc.iv, interval16{start: rangestart, last: rangelast})
+	rc.iv = append(rc.iv, newInterval16Range(rangestart, rangelast))
 	return rc
 }
 
@@ -173,7 +248,7 @@ func newRunContainer16FromVals(alreadySorted bool, vals ...uint16) *runContainer
 	case n == 0:
 		// nothing more
 	case n == 1:
-		ah.m = append(ah.m, interval16{start: vals[0], last: vals[0]})
+		ah.m = append(ah.m, newInterval16Range(vals[0], vals[0]))
 		ah.actuallyAdded++
 	default:
 		ah.runstart = vals[0]
@@ -232,13 +307,13 @@ func newRunContainer16FromBitmapContainer(bc *bitmapContainer) *runContainer16 {
 			// a final unterminated run of 1s
 			runEnd = wordSizeInBits + longCtr*64
 			rc.iv[runCount].start = uint16(runStart)
-			rc.iv[runCount].last = uint16(runEnd) - 1
+			rc.iv[runCount].length = uint16(runEnd) - 1 - uint16(runStart)
 			return rc
 		}
 		localRunEnd := countTrailingZerosDeBruijn(^curWordWith1s)
 		runEnd = localRunEnd + longCtr*64
 		rc.iv[runCount].start = uint16(runStart)
-		rc.iv[runCount].last = uint16(runEnd) - 1
+		rc.iv[runCount].length = uint16(runEnd) - 1 - uint16(runStart)
 		runCount++
 		// now, zero out everything right of runEnd.
 		curWord = curWordWith1s & (curWordWith1s + 1)
@@ -263,7 +338,7 @@ func newRunContainer16FromArray(arr *arrayContainer) *runContainer16 {
 	case n == 0:
 		// nothing more
 	case n == 1:
-		ah.m = append(ah.m, interval16{start: uint16(arr.content[0]), last: uint16(arr.content[0])})
+		ah.m = append(ah.m, newInterval16Range(uint16(arr.content[0]), uint16(arr.content[0])))
 		ah.actuallyAdded++
 	default:
 		ah.runstart = uint16(arr.content[0])
@@ -301,10 +376,10 @@ func (rc *runContainer16) set(alreadySorted bool, vals ...uint16) {
 // contiguous and so can be merged into
 // a single interval.
 func canMerge16(a, b interval16) bool {
-	if int64(a.last)+1 < int64(b.start) {
+	if int64(a.last())+1 < int64(b.start) {
 		return false
 	}
-	return int64(b.last)+1 >= int64(a.start)
+	return int64(b.last())+1 >= int64(a.start)
 }
 
 // haveOverlap differs from canMerge in that
@@ -313,10 +388,10 @@ func canMerge16(a, b interval16) bool {
 // it would be the empty set, and we return
 // false).
 func haveOverlap16(a, b interval16) bool {
-	if int64(a.last)+1 <= int64(b.start) {
+	if int64(a.last())+1 <= int64(b.start) {
 		return false
 	}
-	return int64(b.last)+1 > int64(a.start)
+	return int64(b.last())+1 > int64(a.start)
 }
 
 // mergeInterval16s joins a and b into a
@@ -330,10 +405,10 @@ func mergeInterval16s(a, b interval16) (res interval16) {
 	} else {
 		res.start = a.start
 	}
-	if b.last > a.last {
-		res.last = b.last
+	if b.last() > a.last() {
+		res.length = b.last() - res.start
 	} else {
-		res.last = a.last
+		res.length = a.last() - res.start
 	}
 	return
 }
@@ -351,10 +426,10 @@ func intersectInterval16s(a, b interval16) (res interval16, isEmpty bool) {
 	} else {
 		res.start = a.start
 	}
-	if b.last < a.last {
-		res.last = b.last
+	if b.last() < a.last() {
+		res.length = b.last() - res.start
 	} else {
-		res.last = a.last
+		res.length = a.last() - res.start
 	}
 	return
 }
@@ -392,12 +467,12 @@ func (rc *runContainer16) union(b *runContainer16) *runContainer16 {
 			mergedUpdated := false
 			if canMerge16(cura, merged) {
 				merged = mergeInterval16s(cura, merged)
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				mergedUpdated = true
 			}
 			if canMerge16(curb, merged) {
 				merged = mergeInterval16s(curb, merged)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				mergedUpdated = true
 			}
 			if !mergedUpdated {
@@ -420,8 +495,8 @@ func (rc *runContainer16) union(b *runContainer16) *runContainer16 {
 			} else {
 				merged = mergeInterval16s(cura, curb)
 				mergedUsed = true
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 			}
 		}
 	}
@@ -440,7 +515,7 @@ func (rc *runContainer16) union(b *runContainer16) *runContainer16 {
 				cura = rc.iv[na]
 				if canMerge16(cura, merged) {
 					merged = mergeInterval16s(cura, merged)
-					na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+					na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				} else {
 					break aAdds
 				}
@@ -454,7 +529,7 @@ func (rc *runContainer16) union(b *runContainer16) *runContainer16 {
 				curb = b.iv[nb]
 				if canMerge16(curb, merged) {
 					merged = mergeInterval16s(curb, merged)
-					nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+					nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				} else {
 					break bAdds
 				}
@@ -506,18 +581,18 @@ func (rc *runContainer16) unionCardinality(b *runContainer16) uint64 {
 			mergedUpdated := false
 			if canMerge16(cura, merged) {
 				merged = mergeInterval16s(cura, merged)
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				mergedUpdated = true
 			}
 			if canMerge16(curb, merged) {
 				merged = mergeInterval16s(curb, merged)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				mergedUpdated = true
 			}
 			if !mergedUpdated {
 				// we know that merged is disjoint from cura and curb
 				//m = append(m, merged)
-				answer += uint64(merged.last) - uint64(merged.start) + 1
+				answer += uint64(merged.last()) - uint64(merged.start) + 1
 				mergedUsed = false
 			}
 			continue
@@ -526,19 +601,19 @@ func (rc *runContainer16) unionCardinality(b *runContainer16) uint64 {
 			// !mergedUsed
 			if !canMerge16(cura, curb) {
 				if cura.start < curb.start {
-					answer += uint64(cura.last) - uint64(cura.start) + 1
+					answer += uint64(cura.last()) - uint64(cura.start) + 1
 					//m = append(m, cura)
 					na++
 				} else {
-					answer += uint64(curb.last) - uint64(curb.start) + 1
+					answer += uint64(curb.last()) - uint64(curb.start) + 1
 					//m = append(m, curb)
 					nb++
 				}
 			} else {
 				merged = mergeInterval16s(cura, curb)
 				mergedUsed = true
-				na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
-				nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+				na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
+				nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 			}
 		}
 	}
@@ -557,7 +632,7 @@ func (rc *runContainer16) unionCardinality(b *runContainer16) uint64 {
 				cura = rc.iv[na]
 				if canMerge16(cura, merged) {
 					merged = mergeInterval16s(cura, merged)
-					na = rc.indexOfIntervalAtOrAfter(int64(merged.last)+1, na+1)
+					na = rc.indexOfIntervalAtOrAfter(int64(merged.last())+1, na+1)
 				} else {
 					break aAdds
 				}
@@ -571,7 +646,7 @@ func (rc *runContainer16) unionCardinality(b *runContainer16) uint64 {
 				curb = b.iv[nb]
 				if canMerge16(curb, merged) {
 					merged = mergeInterval16s(curb, merged)
-					nb = b.indexOfIntervalAtOrAfter(int64(merged.last)+1, nb+1)
+					nb = b.indexOfIntervalAtOrAfter(int64(merged.last())+1, nb+1)
 				} else {
 					break bAdds
 				}
@@ -580,23 +655,24 @@ func (rc *runContainer16) unionCardinality(b *runContainer16) uint64 {
 		}
 
 		//m = append(m, merged)
-		answer += uint64(merged.last) - uint64(merged.start) + 1
+		answer += uint64(merged.last()) - uint64(merged.start) + 1
 	}
 	for _, r := range rc.iv[na:] {
-		answer += uint64(r.last) - uint64(r.start) + 1
+		answer += uint64(r.last()) - uint64(r.start) + 1
 	}
 	for _, r := range b.iv[nb:] {
-		answer += uint64(r.last) - uint64(r.start) + 1
+		answer += uint64(r.last()) - uint64(r.start) + 1
 	}
 	return answer
 }
 
 // indexOfIntervalAtOrAfter is a helper for union.
 func (rc *runContainer16) indexOfIntervalAtOrAfter(key int64, startIndex int64) int64 {
-	rc.myOpts.startIndex = startIndex
-	rc.myOpts.endxIndex = 0
+	// the options are not kept in rc, which may be
+	// shared with other goroutines reading it
+	opts := searchOptions{startIndex: startIndex}
 
-	w, already, _ := rc.search(key, &rc.myOpts)
+	w, already, _ := rc.search(key, &opts)
 	if already {
 		return int64(w)
 	}
@@ -638,7 +714,7 @@ toploop:
 	for acuri < numa && bcuri < numb {
 		pass++
 
-		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover16(astart, int64(a.iv[acuri].last), bstart, int64(b.iv[bcuri].last))
+		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover16(astart, int64(a.iv[acuri].last()), bstart, int64(b.iv[bcuri].last()))
 
 		if !isOverlap {
 			switch {
@@ -747,7 +823,7 @@ toploop:
 	for acuri < numa && bcuri < numb {
 		pass++
 
-		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover16(astart, int64(a.iv[acuri].last), bstart, int64(b.iv[bcuri].last))
+		isOverlap, isLeftoverA, isLeftoverB, leftoverstart, intersection = intersectWithLeftover16(astart, int64(a.iv[acuri].last()), bstart, int64(b.iv[bcuri].last()))
 
 		if !isOverlap {
 			switch {
@@ -771,7 +847,7 @@ toploop:
 
 		} else {
 			// isOverlap
-			answer += int64(intersection.last) - int64(intersection.start) + 1
+			answer += int64(intersection.last()) - int64(intersection.start) + 1
 			switch {
 			case isLeftoverA:
 				// note that we change astart without advancing acuri,
@@ -846,7 +922,7 @@ func (rc *runContainer16) numIntervals() int {
 //
 //  c) whichInterval16 is set to the minimum index of rc.iv
 //     which comes strictly before the key;
-//     so  rc.iv[whichInterval16].last < key,
+//     so  rc.iv[whichInterval16].last() < key,
 //     and  if whichInterval16+1 exists, then key < rc.iv[whichInterval16+1].start
 //     (Note that whichInterval16+1 won't exist when
 //     whichInterval16 is the last interval.)
@@ -908,7 +984,7 @@ func (rc *runContainer16) search(key int64, opts *searchOptions) (whichInterval1
 	if below == n {
 		// all falses => key is >= start of all interval16s
 		// ... so does it belong to the last interval16?
-		if key < int64(rc.iv[n-1].last)+1 {
+		if key < int64(rc.iv[n-1].last())+1 {
 			// yes, it belongs to the last interval16
 			alreadyPresent = true
 			return
@@ -929,7 +1005,7 @@ func (rc *runContainer16) search(key int64, opts *searchOptions) (whichInterval1
 	//        key is <  rc.iv[below].start
 
 	// is key in below-1 interval16?
-	if key >= int64(rc.iv[below-1].start) && key < int64(rc.iv[below-1].last)+1 {
+	if key >= int64(rc.iv[below-1].start) && key < int64(rc.iv[below-1].last())+1 {
 		// yes, it is. key is in below-1 interval16.
 		alreadyPresent = true
 		return
@@ -964,7 +1040,7 @@ func (rc *runContainer16) AsSlice() []uint16 {
 	s := make([]uint16, rc.cardinality())
 	j := 0
 	for _, p := range rc.iv {
-		for i := p.start; i <= p.last; i++ {
+		for i := p.start; i <= p.last(); i++ {
 			s[j] = uint16(i)
 			j++
 		}
@@ -1049,21 +1125,22 @@ func (rc *runContainer16) Add(k uint16) (wasNew bool) {
 		if n > 0 {
 			if rc.iv[0].start == k+1 {
 				rc.iv[0].start = k
+				rc.iv[0].length++
 				return
 			}
 		}
 		// nope, k stands alone, starting the new first interval16.
-		rc.iv = append([]interval16{{start: k, last: k}}, rc.iv...)
+		rc.iv = append([]interval16{newInterval16Range(k, k)}, rc.iv...)
 		return
 	}
 
 	// are we off the end? handle both index == n and index == n-1:
 	if index >= n-1 {
-		if int64(rc.iv[n-1].last)+1 == k64 {
-			rc.iv[n-1].last++
+		if int64(rc.iv[n-1].last())+1 == k64 {
+			rc.iv[n-1].length++
 			return
 		}
-		rc.iv = append(rc.iv, interval16{start: k, last: k})
+		rc.iv = append(rc.iv, newInterval16Range(k, k))
 		return
 	}
 
@@ -1078,18 +1155,18 @@ func (rc *runContainer16) Add(k uint16) (wasNew bool) {
 	right := index + 1
 
 	// are we fusing left and right by adding k?
-	if int64(rc.iv[left].last)+1 == k64 && int64(rc.iv[right].start) == k64+1 {
+	if int64(rc.iv[left].last())+1 == k64 && int64(rc.iv[right].start) == k64+1 {
 		// fuse into left
-		rc.iv[left].last = rc.iv[right].last
+		rc.iv[left].length = rc.iv[right].last() - rc.iv[left].start
 		// remove redundant right
 		rc.iv = append(rc.iv[:left+1], rc.iv[right+1:]...)
 		return
 	}
 
 	// are we an addition to left?
-	if int64(rc.iv[left].last)+1 == k64 {
+	if int64(rc.iv[left].last())+1 == k64 {
 		// yes
-		rc.iv[left].last++
+		rc.iv[left].length++
 		return
 	}
 
@@ -1097,11 +1174,12 @@ func (rc *runContainer16) Add(k uint16) (wasNew bool) {
 	if int64(rc.iv[right].start) == k64+1 {
 		// yes
 		rc.iv[right].start = k
+		rc.iv[right].length++
 		return
 	}
 
 	// k makes a standalone new interval16, inserted in the middle
-	tail := append([]interval16{{start: k, last: k}}, rc.iv[right:]...)
+	tail := append([]interval16{newInterval16Range(k, k)}, rc.iv[right:]...)
 	rc.iv = append(rc.iv[:left+1], tail...)
 	return
 }
@@ -1154,7 +1232,7 @@ func (ri *runIterator16) next() uint16 {
 		ri.curIndex = 0
 	} else {
 		ri.curPosInIndex++
-		if int64(ri.rc.iv[ri.curIndex].start)+int64(ri.curPosInIndex) == int64(ri.rc.iv[ri.curIndex].last)+1 {
+		if int64(ri.rc.iv[ri.curIndex].start)+int64(ri.curPosInIndex) == int64(ri.rc.iv[ri.curIndex].last())+1 {
 			ri.curPosInIndex = 0
 			ri.curIndex++
 		}
@@ -1163,6 +1241,76 @@ func (ri *runIterator16) next() uint16 {
 	return ri.cur()
 }
 
+// peekNext returns the value Next would return, without
+// consuming it. As with Next, HasNext must be true.
+func (ri *runIterator16) peekNext() uint16 {
+	if ri.curIndex == -1 {
+		return ri.rc.iv[0].start
+	}
+	cur := ri.cur()
+	if cur == ri.rc.iv[ri.curIndex].last() {
+		return ri.rc.iv[ri.curIndex+1].start
+	}
+	return cur + 1
+}
+
+// advanceIfNeeded skips the values smaller than minval, galloping
+// over the runs that end before minval.
+func (ri *runIterator16) advanceIfNeeded(minval uint16) {
+	if !ri.hasNext() {
+		return
+	}
+	peek := ri.peekNext()
+	if peek >= minval {
+		return
+	}
+	// from is the run holding peek
+	from := 0
+	if ri.curIndex >= 0 {
+		from = int(ri.curIndex)
+		if ri.cur() == ri.rc.iv[from].last() {
+			from++
+		}
+	}
+	seq := ri.curSeq
+	if ri.curIndex == -1 {
+		seq = -1
+	}
+
+	idx := ri.rc.advanceUntilRun(from, minval)
+	if idx == len(ri.rc.iv) {
+		// nothing left
+		ri.curIndex = int64(idx - 1)
+		ri.curPosInIndex = ri.rc.iv[idx-1].length
+		ri.curSeq = ri.rc.cardinality() - 1
+		return
+	}
+	target := minval
+	if target < ri.rc.iv[idx].start {
+		target = ri.rc.iv[idx].start
+	}
+	// count the values skipped, from peek up to target
+	if from == idx {
+		seq += int64(target - peek)
+	} else {
+		seq += ri.rc.iv[from].runlen() - int64(peek-ri.rc.iv[from].start)
+		for j := from + 1; j < idx; j++ {
+			seq += ri.rc.iv[j].runlen()
+		}
+		seq += int64(target - ri.rc.iv[idx].start)
+	}
+
+	// stop on the value just before target, so that next returns target
+	ri.curSeq = seq
+	if pos := target - ri.rc.iv[idx].start; pos > 0 {
+		ri.curIndex = int64(idx)
+		ri.curPosInIndex = pos - 1
+	} else {
+		ri.curIndex = int64(idx - 1)
+		ri.curPosInIndex = ri.rc.iv[idx-1].length
+	}
+}
+
 // remove removes the element that the iterator
 // is on from the run container. You can use
 // Cur if you want to double check what is about
@@ -1203,7 +1351,7 @@ func (rc *runContainer16) deleteAt(curIndex *int64, curPosInIndex *uint16, curSe
 	// are we first, last, or in the middle of our interval16?
 	switch {
 	case pos == 0:
-		if int64(rc.iv[ci].start) == int64(rc.iv[ci].last) {
+		if rc.iv[ci].length == 0 {
 			// our interval disappears
 			rc.iv = append(rc.iv[:ci], rc.iv[ci+1:]...)
 			// curIndex stays the same, since the delete did
@@ -1211,27 +1359,24 @@ func (rc *runContainer16) deleteAt(curIndex *int64, curPosInIndex *uint16, curSe
 			*curPosInIndex = 0
 		} else {
 			rc.iv[ci].start++ // no longer overflowable
+			rc.iv[ci].length--
 		}
 	case int64(pos) == rc.iv[ci].runlen()-1:
 		// last
-		rc.iv[ci].last--
+		rc.iv[ci].length--
 		// our interval16 cannot disappear, else we would have been pos == 0, case first above.
 		(*curPosInIndex)--
 		// if we leave *curIndex alone, then Next() will work properly even after the delete.
 	default:
 		//middle
 		// split into two, adding an interval16
-		new0 := interval16{
-			start: rc.iv[ci].start,
-			last:  rc.iv[ci].start + *curPosInIndex - 1}
+		new0 := newInterval16Range(rc.iv[ci].start, rc.iv[ci].start+*curPosInIndex-1)
 
 		new1start := int64(rc.iv[ci].start) + int64(*curPosInIndex) + 1
 		if new1start > int64(MaxUint16) {
 			panic("overflow?!?!")
 		}
-		new1 := interval16{
-			start: uint16(new1start),
-			last:  rc.iv[ci].last}
+		new1 := newInterval16Range(uint16(new1start), rc.iv[ci].last())
 		tail := append([]interval16{new0, new1}, rc.iv[ci+1:]...)
 		rc.iv = append(rc.iv[:ci], tail...)
 		// update curIndex and curPosInIndex
@@ -1264,25 +1409,25 @@ func intersectWithLeftover16(astart, alast, bstart, blast int64) (isOverlap, isL
 	case blast < alast:
 		isLeftoverA = true
 		leftoverstart = int64(blast) + 1
-		intersection.last = uint16(blast)
+		intersection.length = uint16(blast) - intersection.start
 	case alast < blast:
 		isLeftoverB = true
 		leftoverstart = int64(alast) + 1
-		intersection.last = uint16(alast)
+		intersection.length = uint16(alast) - intersection.start
 	default:
 		// alast == blast
-		intersection.last = uint16(alast)
+		intersection.length = uint16(alast) - intersection.start
 	}
 
 	return
 }
 
 func (rc *runContainer16) findNextIntervalThatIntersectsStartingFrom(startIndex int64, key int64) (index int64, done bool) {
+	// the options are not kept in rc, which may be
+	// shared with other goroutines reading it
+	opts := searchOptions{startIndex: startIndex}
 
-	rc.myOpts.startIndex = startIndex
-	rc.myOpts.endxIndex = 0
-
-	w, _, _ := rc.search(key, &rc.myOpts)
+	w, _, _ := rc.search(key, &opts)
 	// rc.search always returns w < len(rc.iv)
 	if w < startIndex {
 		// not found and comes before lower bound startIndex,
@@ -1315,7 +1460,7 @@ func (rc *runContainer16) selectInt16(j uint16) int {
 
 	var offset int64
 	for k := range rc.iv {
-		nextOffset := offset + rc.iv[k].runlen() + 1
+		nextOffset := offset + rc.iv[k].runlen()
 		if nextOffset > int64(j) {
 			return int(int64(rc.iv[k].start) + (int64(j) - offset))
 		}
@@ -1327,19 +1472,19 @@ func (rc *runContainer16) selectInt16(j uint16) int {
 // helper for invert
 func (rc *runContainer16) invertlastInterval(origin uint16, lastIdx int) []interval16 {
 	cur := rc.iv[lastIdx]
-	if cur.last == MaxUint16 {
+	if cur.last() == MaxUint16 {
 		if cur.start == origin {
 			return nil // empty container
 		}
-		return []interval16{{start: origin, last: cur.start - 1}}
+		return []interval16{newInterval16Range(origin, cur.start-1)}
 	}
 	if cur.start == origin {
-		return []interval16{{start: cur.last + 1, last: MaxUint16}}
+		return []interval16{newInterval16Range(cur.last()+1, MaxUint16)}
 	}
 	// invert splits
 	return []interval16{
-		{start: origin, last: cur.start - 1},
-		{start: cur.last + 1, last: MaxUint16},
+		newInterval16Range(origin, cur.start-1),
+		newInterval16Range(cur.last()+1, MaxUint16),
 	}
 }
 
@@ -1351,7 +1496,7 @@ func (rc *runContainer16) invert() *runContainer16 {
 	var m []interval16
 	switch ni {
 	case 0:
-		return &runContainer16{iv: []interval16{{0, MaxUint16}}}
+		return &runContainer16{iv: []interval16{newInterval16Range(0, MaxUint16)}}
 	case 1:
 		return &runContainer16{iv: rc.invertlastInterval(0, 0)}
 	}
@@ -1366,28 +1511,28 @@ func (rc *runContainer16) invert() *runContainer16 {
 		}
 		// INVAR: i and cur are not the last interval, there is a next at i+1
 		//
-		// ........[cur.start, cur.last] ...... [next.start, next.last]....
+		// ........[cur.start, cur.last()] ...... [next.start, next.last()]....
 		//    ^                             ^                           ^
 		//   (a)                           (b)                         (c)
 		//
 		// Now: we add interval (a); but if (a) is empty, for cur.start==0, we skip it.
 		if cur.start > 0 {
-			m = append(m, interval16{start: uint16(invstart), last: cur.start - 1})
+			m = append(m, newInterval16Range(uint16(invstart), cur.start-1))
 		}
-		invstart = int64(cur.last + 1)
+		invstart = int64(cur.last() + 1)
 	}
 	return &runContainer16{iv: m}
 }
 
 func (iv interval16) equal(b interval16) bool {
 	if iv.start == b.start {
-		return iv.last == b.last
+		return iv.length == b.length
 	}
 	return false
 }
 
 func (iv interval16) isSuperSetOf(b interval16) bool {
-	return iv.start <= b.start && b.last <= iv.last
+	return iv.start <= b.start && b.last() <= iv.last()
 }
 
 func (iv interval16) subtractInterval(del interval16) (left []interval16, delcount int64) {
@@ -1401,14 +1546,14 @@ func (iv interval16) subtractInterval(del interval16) (left []interval16, delcou
 	}
 
 	switch {
-	case isect.start > iv.start && isect.last < iv.last:
-		new0 := interval16{start: iv.start, last: isect.start - 1}
-		new1 := interval16{start: isect.last + 1, last: iv.last}
+	case isect.start > iv.start && isect.last() < iv.last():
+		new0 := newInterval16Range(iv.start, isect.start-1)
+		new1 := newInterval16Range(isect.last()+1, iv.last())
 		return []interval16{new0, new1}, isect.runlen()
 	case isect.start == iv.start:
-		return []interval16{{start: isect.last + 1, last: iv.last}}, isect.runlen()
+		return []interval16{newInterval16Range(isect.last()+1, iv.last())}, isect.runlen()
 	default:
-		return []interval16{{start: iv.start, last: isect.start - 1}}, isect.runlen()
+		return []interval16{newInterval16Range(iv.start, isect.start-1)}, isect.runlen()
 	}
 }
 
@@ -1421,16 +1566,14 @@ func (rc *runContainer16) isubtract(del interval16) {
 	}
 
 	_, isEmpty := intersectInterval16s(
-		interval16{
-			start: rc.iv[0].start,
-			last:  rc.iv[n-1].last,
-		}, del)
+		newInterval16Range(rc.iv[0].start, rc.iv[n-1].last()),
+		del)
 	if isEmpty {
 		return // done
 	}
 	// INVAR there is some intersection between rc and del
 	istart, startAlready, _ := rc.search(int64(del.start), nil)
-	ilast, lastAlready, _ := rc.search(int64(del.last), nil)
+	ilast, lastAlready, _ := rc.search(int64(del.last()), nil)
 	rc.card = -1
 	if istart == -1 {
 		if ilast == n-1 && !lastAlready {
@@ -1498,22 +1641,22 @@ func (rc *runContainer16) isubtract(del interval16) {
 		//
 		//  c) istart is set to the minimum index of rc.iv
 		//     which comes strictly before the del.start;
-		//     so  del.start > rc.iv[istart].last,
+		//     so  del.start > rc.iv[istart].last(),
 		//     and  if istart+1 exists, then del.start < rc.iv[istart+1].startx
 
-		// if del.last is not present, then ilast is
+		// if del.last() is not present, then ilast is
 		// set as follows:
 		//
-		//  a) ilast == n-1 if del.last is beyond our
+		//  a) ilast == n-1 if del.last() is beyond our
 		//     last interval16 in rc.iv;
 		//
-		//  b) ilast == -1 if del.last is before our first
+		//  b) ilast == -1 if del.last() is before our first
 		//     interval16 in rc.iv;
 		//
 		//  c) ilast is set to the minimum index of rc.iv
-		//     which comes strictly before the del.last;
-		//     so  del.last > rc.iv[ilast].last,
-		//     and  if ilast+1 exists, then del.last < rc.iv[ilast+1].start
+		//     which comes strictly before the del.last();
+		//     so  del.last() > rc.iv[ilast].last(),
+		//     and  if ilast+1 exists, then del.last() < rc.iv[ilast+1].start
 
 		// INVAR: istart >= 0
 		pre := rc.iv[:istart+1]
@@ -1571,7 +1714,7 @@ func (rc *runContainer16) isubtract(del interval16) {
 func (rc *runContainer16) AndNotRunContainer16(b *runContainer16) *runContainer16 {
 
 	if len(b.iv) == 0 || len(rc.iv) == 0 {
-		return rc
+		return rc.Clone()
 	}
 
 	dst := newRunContainer16()
@@ -1581,9 +1724,9 @@ func (rc *runContainer16) AndNotRunContainer16(b *runContainer16) *runContainer1
 	a := rc
 
 	astart := a.iv[apos].start
-	alast := a.iv[apos].last
+	alast := a.iv[apos].last()
 	bstart := b.iv[bpos].start
-	blast := b.iv[bpos].last
+	blast := b.iv[bpos].last()
 
 	alen := len(a.iv)
 	blen := len(b.iv)
@@ -1592,18 +1735,18 @@ func (rc *runContainer16) AndNotRunContainer16(b *runContainer16) *runContainer1
 		switch {
 		case alast < bstart:
 			// output the first run
-			dst.iv = append(dst.iv, interval16{start: uint16(astart), last: uint16(alast)})
+			dst.iv = append(dst.iv, newInterval16Range(astart, alast))
 			apos++
 			if apos < alen {
 				astart = a.iv[apos].start
-				alast = a.iv[apos].last
+				alast = a.iv[apos].last()
 			}
 		case blast < astart:
 			// exit the second run
 			bpos++
 			if bpos < blen {
 				bstart = b.iv[bpos].start
-				blast = b.iv[bpos].last
+				blast = b.iv[bpos].last()
 			}
 		default:
 			//   a: [             ]
@@ -1611,7 +1754,7 @@ func (rc *runContainer16) AndNotRunContainer16(b *runContainer16) *runContainer1
 			// alast >= bstart
 			// blast >= astart
 			if astart < bstart {
-				dst.iv = append(dst.iv, interval16{start: uint16(astart), last: uint16(bstart - 1)})
+				dst.iv = append(dst.iv, newInterval16Range(astart, bstart-1))
 			}
 			if alast > blast {
 				astart = blast + 1
@@ -1619,13 +1762,13 @@ func (rc *runContainer16) AndNotRunContainer16(b *runContainer16) *runContainer1
 				apos++
 				if apos < alen {
 					astart = a.iv[apos].start
-					alast = a.iv[apos].last
+					alast = a.iv[apos].last()
 				}
 			}
 		}
 	}
 	if apos < alen {
-		dst.iv = append(dst.iv, interval16{start: uint16(astart), last: uint16(alast)})
+		dst.iv = append(dst.iv, newInterval16Range(astart, alast))
 		apos++
 		if apos < alen {
 			dst.iv = append(dst.iv, a.iv[apos:]...)
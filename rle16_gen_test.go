@@ -125,7 +125,7 @@ func BenchmarkDecodeaddHelper16(b *testing.B) {
 }
 
 func TestMarshalUnmarshalinterval16(t *testing.T) {
-	v := interval16{}
+	v := msgpInterval16{}
 	bts, err := v.MarshalMsg(nil)
 	if err != nil {
 		t.Fatal(err)
@@ -148,7 +148,7 @@ func TestMarshalUnmarshalinterval16(t *testing.T) {
 }
 
 func BenchmarkMarshalMsginterval16(b *testing.B) {
-	v := interval16{}
+	v := msgpInterval16{}
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -157,7 +157,7 @@ func BenchmarkMarshalMsginterval16(b *testing.B) {
 }
 
 func BenchmarkAppendMsginterval16(b *testing.B) {
-	v := interval16{}
+	v := msgpInterval16{}
 	bts := make([]byte, 0, v.Msgsize())
 	bts, _ = v.MarshalMsg(bts[0:0])
 	b.SetBytes(int64(len(bts)))
@@ -169,7 +169,7 @@ func BenchmarkAppendMsginterval16(b *testing.B) {
 }
 
 func BenchmarkUnmarshalinterval16(b *testing.B) {
-	v := interval16{}
+	v := msgpInterval16{}
 	bts, _ := v.MarshalMsg(nil)
 	b.ReportAllocs()
 	b.SetBytes(int64(len(bts)))
@@ -183,7 +183,7 @@ func BenchmarkUnmarshalinterval16(b *testing.B) {
 }
 
 func TestEncodeDecodeinterval16(t *testing.T) {
-	v := interval16{}
+	v := msgpInterval16{}
 	var buf bytes.Buffer
 	msgp.Encode(&buf, &v)
 
@@ -192,7 +192,7 @@ func TestEncodeDecodeinterval16(t *testing.T) {
 		t.Logf("WARNING: Msgsize() for %v is inaccurate", v)
 	}
 
-	vn := interval16{}
+	vn := msgpInterval16{}
 	err := msgp.Decode(&buf, &vn)
 	if err != nil {
 		t.Error(err)
@@ -207,7 +207,7 @@ func TestEncodeDecodeinterval16(t *testing.T) {
 }
 
 func BenchmarkEncodeinterval16(b *testing.B) {
-	v := interval16{}
+	v := msgpInterval16{}
 	var buf bytes.Buffer
 	msgp.Encode(&buf, &v)
 	b.SetBytes(int64(buf.Len()))
@@ -221,7 +221,7 @@ func BenchmarkEncodeinterval16(b *testing.B) {
 }
 
 func BenchmarkDecodeinterval16(b *testing.B) {
-	v := interval16{}
+	v := msgpInterval16{}
 	var buf bytes.Buffer
 	msgp.Encode(&buf, &v)
 	b.SetBytes(int64(buf.Len()))
@@ -49,35 +49,10 @@ func (z *addHelper32) DecodeMsg(dc *msgp.Reader) (err error) {
 				z.m = make([]interval32, zcmr)
 			}
 			for zxvk := range z.m {
-				var zajw uint32
-				zajw, err = dc.ReadMapHeader()
+				err = z.m[zxvk].DecodeMsg(dc)
 				if err != nil {
 					return
 				}
-				for zajw > 0 {
-					zajw--
-					field, err = dc.ReadMapKeyPtr()
-					if err != nil {
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "start":
-						z.m[zxvk].start, err = dc.ReadUint32()
-						if err != nil {
-							return
-						}
-					case "last":
-						z.m[zxvk].last, err = dc.ReadUint32()
-						if err != nil {
-							return
-						}
-					default:
-						err = dc.Skip()
-						if err != nil {
-							return
-						}
-					}
-				}
 			}
 		case "rc":
 			if dc.IsNil() {
@@ -114,35 +89,10 @@ func (z *addHelper32) DecodeMsg(dc *msgp.Reader) (err error) {
 							z.rc.iv = make([]interval32, zhct)
 						}
 						for zbzg := range z.rc.iv {
-							var zcua uint32
-							zcua, err = dc.ReadMapHeader()
+							err = z.rc.iv[zbzg].DecodeMsg(dc)
 							if err != nil {
 								return
 							}
-							for zcua > 0 {
-								zcua--
-								field, err = dc.ReadMapKeyPtr()
-								if err != nil {
-									return
-								}
-								switch msgp.UnsafeString(field) {
-								case "start":
-									z.rc.iv[zbzg].start, err = dc.ReadUint32()
-									if err != nil {
-										return
-									}
-								case "last":
-									z.rc.iv[zbzg].last, err = dc.ReadUint32()
-									if err != nil {
-										return
-									}
-								default:
-									err = dc.Skip()
-									if err != nil {
-										return
-									}
-								}
-							}
 						}
 					case "card":
 						z.rc.card, err = dc.ReadInt64()
@@ -207,22 +157,7 @@ func (z *addHelper32) EncodeMsg(en *msgp.Writer) (err error) {
 		return
 	}
 	for zxvk := range z.m {
-		// map header, size 2
-		// write "start"
-		err = en.Append(0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-		if err != nil {
-			return err
-		}
-		err = en.WriteUint32(z.m[zxvk].start)
-		if err != nil {
-			return
-		}
-		// write "last"
-		err = en.Append(0xa4, 0x6c, 0x61, 0x73, 0x74)
-		if err != nil {
-			return err
-		}
-		err = en.WriteUint32(z.m[zxvk].last)
+		err = z.m[zxvk].EncodeMsg(en)
 		if err != nil {
 			return
 		}
@@ -249,22 +184,7 @@ func (z *addHelper32) EncodeMsg(en *msgp.Writer) (err error) {
 			return
 		}
 		for zbzg := range z.rc.iv {
-			// map header, size 2
-			// write "start"
-			err = en.Append(0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-			if err != nil {
-				return err
-			}
-			err = en.WriteUint32(z.rc.iv[zbzg].start)
-			if err != nil {
-				return
-			}
-			// write "last"
-			err = en.Append(0xa4, 0x6c, 0x61, 0x73, 0x74)
-			if err != nil {
-				return err
-			}
-			err = en.WriteUint32(z.rc.iv[zbzg].last)
+			err = z.rc.iv[zbzg].EncodeMsg(en)
 			if err != nil {
 				return
 			}
@@ -299,13 +219,10 @@ func (z *addHelper32) MarshalMsg(b []byte) (o []byte, err error) {
 	o = append(o, 0xa1, 0x6d)
 	o = msgp.AppendArrayHeader(o, uint32(len(z.m)))
 	for zxvk := range z.m {
-		// map header, size 2
-		// string "start"
-		o = append(o, 0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-		o = msgp.AppendUint32(o, z.m[zxvk].start)
-		// string "last"
-		o = append(o, 0xa4, 0x6c, 0x61, 0x73, 0x74)
-		o = msgp.AppendUint32(o, z.m[zxvk].last)
+		o, err = z.m[zxvk].MarshalMsg(o)
+		if err != nil {
+			return
+		}
 	}
 	// string "rc"
 	o = append(o, 0xa2, 0x72, 0x63)
@@ -317,13 +234,10 @@ func (z *addHelper32) MarshalMsg(b []byte) (o []byte, err error) {
 		o = append(o, 0x82, 0xa2, 0x69, 0x76)
 		o = msgp.AppendArrayHeader(o, uint32(len(z.rc.iv)))
 		for zbzg := range z.rc.iv {
-			// map header, size 2
-			// string "start"
-			o = append(o, 0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-			o = msgp.AppendUint32(o, z.rc.iv[zbzg].start)
-			// string "last"
-			o = append(o, 0xa4, 0x6c, 0x61, 0x73, 0x74)
-			o = msgp.AppendUint32(o, z.rc.iv[zbzg].last)
+			o, err = z.rc.iv[zbzg].MarshalMsg(o)
+			if err != nil {
+				return
+			}
 		}
 		// string "card"
 		o = append(o, 0xa4, 0x63, 0x61, 0x72, 0x64)
@@ -375,35 +289,10 @@ func (z *addHelper32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				z.m = make([]interval32, zlqf)
 			}
 			for zxvk := range z.m {
-				var zdaf uint32
-				zdaf, bts, err = msgp.ReadMapHeaderBytes(bts)
+				bts, err = z.m[zxvk].UnmarshalMsg(bts)
 				if err != nil {
 					return
 				}
-				for zdaf > 0 {
-					zdaf--
-					field, bts, err = msgp.ReadMapKeyZC(bts)
-					if err != nil {
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "start":
-						z.m[zxvk].start, bts, err = msgp.ReadUint32Bytes(bts)
-						if err != nil {
-							return
-						}
-					case "last":
-						z.m[zxvk].last, bts, err = msgp.ReadUint32Bytes(bts)
-						if err != nil {
-							return
-						}
-					default:
-						bts, err = msgp.Skip(bts)
-						if err != nil {
-							return
-						}
-					}
-				}
 			}
 		case "rc":
 			if msgp.IsNil(bts) {
@@ -440,35 +329,10 @@ func (z *addHelper32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 							z.rc.iv = make([]interval32, zjfb)
 						}
 						for zbzg := range z.rc.iv {
-							var zcxo uint32
-							zcxo, bts, err = msgp.ReadMapHeaderBytes(bts)
+							bts, err = z.rc.iv[zbzg].UnmarshalMsg(bts)
 							if err != nil {
 								return
 							}
-							for zcxo > 0 {
-								zcxo--
-								field, bts, err = msgp.ReadMapKeyZC(bts)
-								if err != nil {
-									return
-								}
-								switch msgp.UnsafeString(field) {
-								case "start":
-									z.rc.iv[zbzg].start, bts, err = msgp.ReadUint32Bytes(bts)
-									if err != nil {
-										return
-									}
-								case "last":
-									z.rc.iv[zbzg].last, bts, err = msgp.ReadUint32Bytes(bts)
-									if err != nil {
-										return
-									}
-								default:
-									bts, err = msgp.Skip(bts)
-									if err != nil {
-										return
-									}
-								}
-							}
 						}
 					case "card":
 						z.rc.card, bts, err = msgp.ReadInt64Bytes(bts)
@@ -496,17 +360,25 @@ func (z *addHelper32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *addHelper32) Msgsize() (s int) {
-	s = 1 + 9 + msgp.Uint32Size + 7 + msgp.Uint32Size + 14 + msgp.Uint32Size + 2 + msgp.ArrayHeaderSize + (len(z.m) * (12 + msgp.Uint32Size + msgp.Uint32Size)) + 3
+	s = 1 + 9 + msgp.Uint32Size + 7 + msgp.Uint32Size + 14 + msgp.Uint32Size + 2 + msgp.ArrayHeaderSize
+	for zxvk := range z.m {
+		s += z.m[zxvk].Msgsize()
+	}
+	s += 3
 	if z.rc == nil {
 		s += msgp.NilSize
 	} else {
-		s += 1 + 3 + msgp.ArrayHeaderSize + (len(z.rc.iv) * (12 + msgp.Uint32Size + msgp.Uint32Size)) + 5 + msgp.Int64Size
+		s += 1 + 3 + msgp.ArrayHeaderSize
+		for zbzg := range z.rc.iv {
+			s += z.rc.iv[zbzg].Msgsize()
+		}
+		s += 5 + msgp.Int64Size
 	}
 	return
 }
 
 // DecodeMsg implements msgp.Decodable
-func (z *interval32) DecodeMsg(dc *msgp.Reader) (err error) {
+func (z *msgpInterval32) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
 	_ = field
 	var zeff uint32
@@ -542,7 +414,7 @@ func (z *interval32) DecodeMsg(dc *msgp.Reader) (err error) {
 }
 
 // EncodeMsg implements msgp.Encodable
-func (z interval32) EncodeMsg(en *msgp.Writer) (err error) {
+func (z msgpInterval32) EncodeMsg(en *msgp.Writer) (err error) {
 	// map header, size 2
 	// write "start"
 	err = en.Append(0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
@@ -566,7 +438,7 @@ func (z interval32) EncodeMsg(en *msgp.Writer) (err error) {
 }
 
 // MarshalMsg implements msgp.Marshaler
-func (z interval32) MarshalMsg(b []byte) (o []byte, err error) {
+func (z msgpInterval32) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
 	// map header, size 2
 	// string "start"
@@ -579,7 +451,7 @@ func (z interval32) MarshalMsg(b []byte) (o []byte, err error) {
 }
 
 // UnmarshalMsg implements msgp.Unmarshaler
-func (z *interval32) UnmarshalMsg(bts []byte) (o []byte, err error) {
+func (z *msgpInterval32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 	var field []byte
 	_ = field
 	var zrsw uint32
@@ -616,7 +488,7 @@ func (z *interval32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 }
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
-func (z interval32) Msgsize() (s int) {
+func (z msgpInterval32) Msgsize() (s int) {
 	s = 1 + 6 + msgp.Uint32Size + 5 + msgp.Uint32Size
 	return
 }
@@ -649,35 +521,10 @@ func (z *runContainer32) DecodeMsg(dc *msgp.Reader) (err error) {
 				z.iv = make([]interval32, zobc)
 			}
 			for zxpk := range z.iv {
-				var zsnv uint32
-				zsnv, err = dc.ReadMapHeader()
+				err = z.iv[zxpk].DecodeMsg(dc)
 				if err != nil {
 					return
 				}
-				for zsnv > 0 {
-					zsnv--
-					field, err = dc.ReadMapKeyPtr()
-					if err != nil {
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "start":
-						z.iv[zxpk].start, err = dc.ReadUint32()
-						if err != nil {
-							return
-						}
-					case "last":
-						z.iv[zxpk].last, err = dc.ReadUint32()
-						if err != nil {
-							return
-						}
-					default:
-						err = dc.Skip()
-						if err != nil {
-							return
-						}
-					}
-				}
 			}
 		case "card":
 			z.card, err = dc.ReadInt64()
@@ -707,22 +554,7 @@ func (z *runContainer32) EncodeMsg(en *msgp.Writer) (err error) {
 		return
 	}
 	for zxpk := range z.iv {
-		// map header, size 2
-		// write "start"
-		err = en.Append(0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-		if err != nil {
-			return err
-		}
-		err = en.WriteUint32(z.iv[zxpk].start)
-		if err != nil {
-			return
-		}
-		// write "last"
-		err = en.Append(0xa4, 0x6c, 0x61, 0x73, 0x74)
-		if err != nil {
-			return err
-		}
-		err = en.WriteUint32(z.iv[zxpk].last)
+		err = z.iv[zxpk].EncodeMsg(en)
 		if err != nil {
 			return
 		}
@@ -747,13 +579,10 @@ func (z *runContainer32) MarshalMsg(b []byte) (o []byte, err error) {
 	o = append(o, 0x82, 0xa2, 0x69, 0x76)
 	o = msgp.AppendArrayHeader(o, uint32(len(z.iv)))
 	for zxpk := range z.iv {
-		// map header, size 2
-		// string "start"
-		o = append(o, 0x82, 0xa5, 0x73, 0x74, 0x61, 0x72, 0x74)
-		o = msgp.AppendUint32(o, z.iv[zxpk].start)
-		// string "last"
-		o = append(o, 0xa4, 0x6c, 0x61, 0x73, 0x74)
-		o = msgp.AppendUint32(o, z.iv[zxpk].last)
+		o, err = z.iv[zxpk].MarshalMsg(o)
+		if err != nil {
+			return
+		}
 	}
 	// string "card"
 	o = append(o, 0xa4, 0x63, 0x61, 0x72, 0x64)
@@ -789,35 +618,10 @@ func (z *runContainer32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				z.iv = make([]interval32, zema)
 			}
 			for zxpk := range z.iv {
-				var zpez uint32
-				zpez, bts, err = msgp.ReadMapHeaderBytes(bts)
+				bts, err = z.iv[zxpk].UnmarshalMsg(bts)
 				if err != nil {
 					return
 				}
-				for zpez > 0 {
-					zpez--
-					field, bts, err = msgp.ReadMapKeyZC(bts)
-					if err != nil {
-						return
-					}
-					switch msgp.UnsafeString(field) {
-					case "start":
-						z.iv[zxpk].start, bts, err = msgp.ReadUint32Bytes(bts)
-						if err != nil {
-							return
-						}
-					case "last":
-						z.iv[zxpk].last, bts, err = msgp.ReadUint32Bytes(bts)
-						if err != nil {
-							return
-						}
-					default:
-						bts, err = msgp.Skip(bts)
-						if err != nil {
-							return
-						}
-					}
-				}
 			}
 		case "card":
 			z.card, bts, err = msgp.ReadInt64Bytes(bts)
@@ -837,7 +641,11 @@ func (z *runContainer32) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *runContainer32) Msgsize() (s int) {
-	s = 1 + 3 + msgp.ArrayHeaderSize + (len(z.iv) * (12 + msgp.Uint32Size + msgp.Uint32Size)) + 5 + msgp.Int64Size
+	s = 1 + 3 + msgp.ArrayHeaderSize
+	for zxpk := range z.iv {
+		s += z.iv[zxpk].Msgsize()
+	}
+	s += 5 + msgp.Int64Size
 	return
 }
 
@@ -1,6 +1,7 @@
 package roaring
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -8,6 +9,7 @@ import (
 	//"unsafe"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/tinylib/msgp/msgp"
 )
 
 func init() {
@@ -17,20 +19,20 @@ func init() {
 func TestRleInterval32s(t *testing.T) {
 
 	Convey("canMerge, and mergeInterval32s should do what they say", t, func() {
-		a := interval32{start: 0, last: 9}
+		a := newInterval32Range(0, 9)
 		msg := a.String()
 		p("a is %v", msg)
-		b := interval32{start: 0, last: 1}
+		b := newInterval32Range(0, 1)
 		report := sliceToString32([]interval32{a, b})
 		_ = report
 		p("a and b together are: %s", report)
-		c := interval32{start: 2, last: 4}
-		d := interval32{start: 2, last: 5}
-		e := interval32{start: 0, last: 4}
-		f := interval32{start: 9, last: 9}
-		g := interval32{start: 8, last: 9}
-		h := interval32{start: 5, last: 6}
-		i := interval32{start: 6, last: 6}
+		c := newInterval32Range(2, 4)
+		d := newInterval32Range(2, 5)
+		e := newInterval32Range(0, 4)
+		f := newInterval32Range(9, 9)
+		g := newInterval32Range(8, 9)
+		h := newInterval32Range(5, 6)
+		i := newInterval32Range(6, 6)
 
 		aIb, empty := intersectInterval32s(a, b)
 		So(empty, ShouldBeFalse)
@@ -55,15 +57,15 @@ func TestRleInterval32s(t *testing.T) {
 		So(mergeInterval32s(i, h), ShouldResemble, h)
 
 		////// start
-		So(mergeInterval32s(interval32{start: 0, last: 0}, interval32{start: 1, last: 1}), ShouldResemble, interval32{start: 0, last: 1})
-		So(mergeInterval32s(interval32{start: 1, last: 1}, interval32{start: 0, last: 0}), ShouldResemble, interval32{start: 0, last: 1})
-		So(mergeInterval32s(interval32{start: 0, last: 4}, interval32{start: 3, last: 5}), ShouldResemble, interval32{start: 0, last: 5})
-		So(mergeInterval32s(interval32{start: 0, last: 4}, interval32{start: 3, last: 4}), ShouldResemble, interval32{start: 0, last: 4})
+		So(mergeInterval32s(newInterval32Range(0, 0), newInterval32Range(1, 1)), ShouldResemble, newInterval32Range(0, 1))
+		So(mergeInterval32s(newInterval32Range(1, 1), newInterval32Range(0, 0)), ShouldResemble, newInterval32Range(0, 1))
+		So(mergeInterval32s(newInterval32Range(0, 4), newInterval32Range(3, 5)), ShouldResemble, newInterval32Range(0, 5))
+		So(mergeInterval32s(newInterval32Range(0, 4), newInterval32Range(3, 4)), ShouldResemble, newInterval32Range(0, 4))
 
-		So(mergeInterval32s(interval32{start: 0, last: 8}, interval32{start: 1, last: 7}), ShouldResemble, interval32{start: 0, last: 8})
-		So(mergeInterval32s(interval32{start: 1, last: 7}, interval32{start: 0, last: 8}), ShouldResemble, interval32{start: 0, last: 8})
+		So(mergeInterval32s(newInterval32Range(0, 8), newInterval32Range(1, 7)), ShouldResemble, newInterval32Range(0, 8))
+		So(mergeInterval32s(newInterval32Range(1, 7), newInterval32Range(0, 8)), ShouldResemble, newInterval32Range(0, 8))
 
-		So(func() { _ = mergeInterval32s(interval32{start: 0, last: 0}, interval32{start: 2, last: 3}) }, ShouldPanic)
+		So(func() { _ = mergeInterval32s(newInterval32Range(0, 0), newInterval32Range(2, 3)) }, ShouldPanic)
 
 	})
 }
@@ -81,7 +83,7 @@ func TestRleRunIterator32(t *testing.T) {
 			So(it.hasNext(), ShouldBeFalse)
 		}
 		{
-			rc := newRunContainer32TakeOwnership([]interval32{{start: 4, last: 4}})
+			rc := newRunContainer32TakeOwnership([]interval32{newInterval32Range(4, 4)})
 			So(rc.cardinality(), ShouldEqual, 1)
 			it := rc.newRunIterator32()
 			So(it.hasNext(), ShouldBeTrue)
@@ -89,7 +91,7 @@ func TestRleRunIterator32(t *testing.T) {
 			So(it.cur(), ShouldResemble, uint32(4))
 		}
 		{
-			rc := newRunContainer32CopyIv([]interval32{{start: 4, last: 9}})
+			rc := newRunContainer32CopyIv([]interval32{newInterval32Range(4, 9)})
 			So(rc.cardinality(), ShouldEqual, 6)
 			it := rc.newRunIterator32()
 			So(it.hasNext(), ShouldBeTrue)
@@ -100,7 +102,7 @@ func TestRleRunIterator32(t *testing.T) {
 		}
 
 		{
-			rc := newRunContainer32TakeOwnership([]interval32{{start: 4, last: 9}})
+			rc := newRunContainer32TakeOwnership([]interval32{newInterval32Range(4, 9)})
 			card := rc.cardinality()
 			So(card, ShouldEqual, 6)
 			//So(rc.serializedSizeInBytes(), ShouldEqual, 2+4*rc.numberOfRuns())
@@ -128,14 +130,14 @@ func TestRleRunIterator32(t *testing.T) {
 		}
 		{
 			rc := newRunContainer32TakeOwnership([]interval32{
-				{start: 0, last: 0},
-				{start: 2, last: 2},
-				{start: 4, last: 4},
+				newInterval32Range(0, 0),
+				newInterval32Range(2, 2),
+				newInterval32Range(4, 4),
 			})
 			rc1 := newRunContainer32TakeOwnership([]interval32{
-				{start: 6, last: 7},
-				{start: 10, last: 11},
-				{start: MaxUint32, last: MaxUint32},
+				newInterval32Range(6, 7),
+				newInterval32Range(10, 11),
+				newInterval32Range(MaxUint32, MaxUint32),
 			})
 
 			rc = rc.union(rc1)
@@ -153,7 +155,7 @@ func TestRleRunIterator32(t *testing.T) {
 			So(it.hasNext(), ShouldEqual, false)
 
 			rc2 := newRunContainer32TakeOwnership([]interval32{
-				{start: 0, last: MaxUint32},
+				newInterval32Range(0, MaxUint32),
 			})
 
 			p("union with a full [0,2^32-1] container should yield that same single interval run container")
@@ -253,8 +255,8 @@ func TestRleIntersection32(t *testing.T) {
 			p("a is %v", a)
 			p("b is %v", b)
 
-			So(haveOverlap32(interval32{0, 2}, interval32{2, 2}), ShouldBeTrue)
-			So(haveOverlap32(interval32{0, 2}, interval32{3, 3}), ShouldBeFalse)
+			So(haveOverlap32(newInterval32Range(0, 2), newInterval32Range(2, 2)), ShouldBeTrue)
+			So(haveOverlap32(newInterval32Range(0, 2), newInterval32Range(3, 3)), ShouldBeFalse)
 
 			isect := a.intersect(b)
 
@@ -265,7 +267,7 @@ func TestRleIntersection32(t *testing.T) {
 			So(isect.contains(6), ShouldBeTrue)
 			So(isect.contains(8), ShouldBeTrue)
 
-			d := newRunContainer32TakeOwnership([]interval32{{start: 0, last: MaxUint32}})
+			d := newRunContainer32TakeOwnership([]interval32{newInterval32Range(0, MaxUint32)})
 
 			isect = isect.intersect(d)
 			p("isect is %v", isect)
@@ -275,8 +277,8 @@ func TestRleIntersection32(t *testing.T) {
 			So(isect.contains(8), ShouldBeTrue)
 
 			p("test breaking apart intervals")
-			e := newRunContainer32TakeOwnership([]interval32{{2, 4}, {8, 9}, {14, 16}, {20, 22}})
-			f := newRunContainer32TakeOwnership([]interval32{{3, 18}, {22, 23}})
+			e := newRunContainer32TakeOwnership([]interval32{newInterval32Range(2, 4), newInterval32Range(8, 9), newInterval32Range(14, 16), newInterval32Range(20, 22)})
+			f := newRunContainer32TakeOwnership([]interval32{newInterval32Range(3, 18), newInterval32Range(22, 23)})
 
 			p("e = %v", e)
 			p("f = %v", f)
@@ -520,9 +522,9 @@ func TestRleAndOrXor32(t *testing.T) {
 	Convey("RunContainer And, Or, Xor tests", t, func() {
 		{
 			rc := newRunContainer32TakeOwnership([]interval32{
-				{start: 0, last: 0},
-				{start: 2, last: 2},
-				{start: 4, last: 4},
+				newInterval32Range(0, 0),
+				newInterval32Range(2, 2),
+				newInterval32Range(4, 4),
 			})
 			b0 := NewBitmap()
 			b0.Add(2)
@@ -584,12 +586,12 @@ func TestRleCoverageOddsAndEnds32(t *testing.T) {
 		rc := &runContainer32{}
 		So(rc.String(), ShouldEqual, "runContainer32{}")
 		rc.iv = make([]interval32, 1)
-		rc.iv[0] = interval32{start: 3, last: 4}
+		rc.iv[0] = newInterval32Range(3, 4)
 		So(rc.String(), ShouldEqual, "runContainer32{0:[3, 4], }")
 
-		a := interval32{start: 5, last: 9}
-		b := interval32{start: 0, last: 1}
-		c := interval32{start: 1, last: 2}
+		a := newInterval32Range(5, 9)
+		b := newInterval32Range(0, 1)
+		c := newInterval32Range(1, 2)
 
 		// intersectInterval32s(a, b interval32)
 		isect, isEmpty := intersectInterval32s(a, b)
@@ -720,6 +722,65 @@ func TestRleStoringMax32(t *testing.T) {
 	})
 }
 
+func TestRleSelect32(t *testing.T) {
+
+	Convey("selectInt32 should count every value of the runs before", t, func() {
+		rc := newRunContainer32TakeOwnership([]interval32{
+			newInterval32Range(0, 9),
+			newInterval32Range(20, 29),
+			newInterval32Range(100, 100),
+		})
+		var expect []int
+		for it := rc.newRunIterator32(); it.hasNext(); {
+			expect = append(expect, int(it.next()))
+		}
+		So(len(expect), ShouldEqual, 21)
+		for j, x := range expect {
+			So(rc.selectInt32(uint32(j)), ShouldEqual, x)
+		}
+	})
+}
+
+func TestRleMsgpLast32(t *testing.T) {
+
+	Convey("msgp should keep encoding runs as start and last", t, func() {
+		// the encoding written before intervals stored their length
+		var b []byte
+		b = msgp.AppendMapHeader(b, 2)
+		b = msgp.AppendString(b, "iv")
+		b = msgp.AppendArrayHeader(b, 2)
+		for _, r := range [][2]uint32{{3, 9}, {20, 20}} {
+			b = msgp.AppendMapHeader(b, 2)
+			b = msgp.AppendString(b, "start")
+			b = msgp.AppendUint32(b, r[0])
+			b = msgp.AppendString(b, "last")
+			b = msgp.AppendUint32(b, r[1])
+		}
+		b = msgp.AppendString(b, "card")
+		b = msgp.AppendInt64(b, 8)
+
+		rc := &runContainer32{}
+		left, err := rc.UnmarshalMsg(b)
+		So(err, ShouldBeNil)
+		So(len(left), ShouldEqual, 0)
+		So(rc.iv, ShouldResemble, []interval32{newInterval32Range(3, 9), newInterval32Range(20, 20)})
+
+		rc2 := &runContainer32{}
+		So(rc2.DecodeMsg(msgp.NewReader(bytes.NewReader(b))), ShouldBeNil)
+		So(rc2.iv, ShouldResemble, rc.iv)
+
+		out, err := rc.MarshalMsg(nil)
+		So(err, ShouldBeNil)
+		So(out, ShouldResemble, b)
+
+		var buf bytes.Buffer
+		en := msgp.NewWriter(&buf)
+		So(rc.EncodeMsg(en), ShouldBeNil)
+		So(en.Flush(), ShouldBeNil)
+		So(buf.Bytes(), ShouldResemble, b)
+	})
+}
+
 // go test -bench BenchmarkFromBitmap -run -
 func BenchmarkFromBitmap32(b *testing.B) {
 	b.StopTimer()
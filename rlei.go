@@ -8,11 +8,16 @@ package roaring
 
 import (
 	"fmt"
+	"sort"
 )
 
 // compile time verify we meet interface requirements
 var _ container = &runContainer16{}
 
+func (rc *runContainer16) sharesCount() *int32 {
+	return &rc.shares
+}
+
 func (rc *runContainer16) clone() container {
 	return newRunContainer16CopyIv(rc.iv)
 }
@@ -22,11 +27,11 @@ func (rc *runContainer16) minimum() uint16 {
 }
 
 func (rc *runContainer16) maximum() uint16 {
-	return rc.iv[len(rc.iv)-1].last // assume not empty
+	return rc.iv[len(rc.iv)-1].last() // assume not empty
 }
 
 func (rc *runContainer16) isFull() bool {
-	return (len(rc.iv) == 1) && ((rc.iv[0].start == 0) && (rc.iv[0].last == MaxUint16))
+	return (len(rc.iv) == 1) && ((rc.iv[0].start == 0) && (rc.iv[0].last() == MaxUint16))
 }
 
 func (rc *runContainer16) and(a container) container {
@@ -56,47 +61,124 @@ func (rc *runContainer16) andCardinality(a container) int {
 	panic("unsupported container type")
 }
 
-// andBitmapContainer finds the intersection of rc and b.
+// andBitmapContainer finds the intersection of rc and bc.
 func (rc *runContainer16) andBitmapContainer(bc *bitmapContainer) container {
-	bc2 := newBitmapContainerFromRun(rc)
-	return bc2.andBitmap(bc)
+	card := rc.andBitmapContainerCardinality(bc)
+	if card <= arrayDefaultMaxSize {
+		answer := newArrayContainerCapacity(card)
+		answer.content = rc.appendBitsInRuns(answer.content, bc.bitmap, false)
+		return answer
+	}
+	answer := newBitmapContainer()
+	for i := range rc.iv {
+		setBitmapRange(answer.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	for k := range answer.bitmap {
+		answer.bitmap[k] &= bc.bitmap[k]
+	}
+	answer.cardinality = card
+	return answer
+}
+
+// appendBitsInRuns appends to dst the values within the runs of rc
+// whose bit is set in bitmap, or clear if negate is true, a word at a
+// time.
+func (rc *runContainer16) appendBitsInRuns(dst []uint16, bitmap []uint64, negate bool) []uint16 {
+	for _, run := range rc.iv {
+		start, end := int(run.start), int(run.last())+1
+		firstword, endword := start/64, (end-1)/64
+		for k := firstword; k <= endword; k++ {
+			w := bitmap[k]
+			if negate {
+				w = ^w
+			}
+			if k == firstword {
+				w &= ^uint64(0) << uint(start%64)
+			}
+			if k == endword {
+				w &= ^uint64(0) >> (uint(-end) % 64)
+			}
+			for w != 0 {
+				t := w & -w
+				dst = append(dst, uint16(k*64+int(popcount(t-1))))
+				w ^= t
+			}
+		}
+	}
+	return dst
 }
 
+// advanceUntilRun returns the index of the first run, at pos or after
+// it, whose last value is at least min, or len(rc.iv) if there is none.
+// Like advanceUntil, it gallops before it bisects.
+func (rc *runContainer16) advanceUntilRun(pos int, min uint16) int {
+	n := len(rc.iv)
+	if pos >= n || rc.iv[pos].last() >= min {
+		return pos
+	}
+	spansize := 1
+	for pos+spansize < n && rc.iv[pos+spansize].last() < min {
+		spansize *= 2
+	}
+	// the run at lower ends before min, the one at upper (if any) does not
+	lower := pos + spansize/2
+	upper := pos + spansize
+	if upper > n {
+		upper = n
+	}
+	return lower + 1 + sort.Search(upper-lower-1, func(i int) bool { return rc.iv[lower+1+i].last() >= min })
+}
+
+// andArray finds the intersection of rc and ac, galloping over the
+// runs that end before the next value of ac, and over the values of
+// ac that come before the next run.
 func (rc *runContainer16) andArray(ac *arrayContainer) container {
-	bc1 := ac.toBitmapContainer()
-	bc2 := newBitmapContainerFromRun(rc)
-	return bc2.andBitmap(bc1)
+	answer := newArrayContainerCapacity(len(ac.content))
+	rlepos, arraypos := 0, 0
+	for arraypos < len(ac.content) && rlepos < len(rc.iv) {
+		v := ac.content[arraypos]
+		if rc.iv[rlepos].last() < v {
+			rlepos = rc.advanceUntilRun(rlepos, v)
+		} else if v < rc.iv[rlepos].start {
+			arraypos = advanceUntil(ac.content, arraypos, len(ac.content), rc.iv[rlepos].start)
+		} else {
+			answer.content = append(answer.content, v)
+			arraypos++
+		}
+	}
+	return answer
 }
 
 func (rc *runContainer16) andArrayCardinality(ac *arrayContainer) int {
-	pos := 0
 	answer := 0
-	maxpos := ac.getCardinality()
-	if maxpos == 0 {
-		return 0 // won't happen in actual code
-	}
-	v := ac.content[pos]
-mainloop:
-	for _, p := range rc.iv {
-		for v < p.start {
-			pos += 1
-			if pos == maxpos {
-				break mainloop
-			}
-			v = ac.content[pos]
-		}
-		for v <= p.last {
-			answer += 1
-			pos += 1
-			if pos == maxpos {
-				break mainloop
-			}
-			v = ac.content[pos]
+	rlepos, arraypos := 0, 0
+	for arraypos < len(ac.content) && rlepos < len(rc.iv) {
+		v := ac.content[arraypos]
+		if rc.iv[rlepos].last() < v {
+			rlepos = rc.advanceUntilRun(rlepos, v)
+		} else if v < rc.iv[rlepos].start {
+			arraypos = advanceUntil(ac.content, arraypos, len(ac.content), rc.iv[rlepos].start)
+		} else {
+			answer++
+			arraypos++
 		}
 	}
 	return answer
 }
 
+// appendInterval16 appends the run [start, last] to iv, extending the
+// last run of iv instead when the two overlap or touch. Runs must be
+// appended by increasing start.
+func appendInterval16(iv []interval16, start, last uint16) []interval16 {
+	if n := len(iv); n > 0 && int(start) <= int(iv[n-1].last())+1 {
+		if last > iv[n-1].last() {
+			iv[n-1].length = last - iv[n-1].start
+		}
+		return iv
+	}
+	return append(iv, newInterval16Range(start, last))
+}
+
 func (rc *runContainer16) iand(a container) container {
 	if rc.isFull() {
 		return a.clone()
@@ -128,37 +210,22 @@ func (rc *runContainer16) iandBitmapContainer(bc *bitmapContainer) container {
 }
 
 func (rc *runContainer16) iandArray(ac *arrayContainer) container {
-
-	bc1 := newBitmapContainerFromRun(rc)
-	bc2 := ac.toBitmapContainer()
-	and := bc1.andBitmap(bc2)
-	var rc2 *runContainer16
-	switch x := and.(type) {
-	case *bitmapContainer:
-		rc2 = newRunContainer16FromBitmapContainer(x)
-	case *arrayContainer:
-		rc2 = newRunContainer16FromArray(x)
-	case *runContainer16:
-		rc2 = x
-	default:
-		panic("unknown container type")
+	var iv []interval16
+	rlepos, arraypos := 0, 0
+	for arraypos < len(ac.content) && rlepos < len(rc.iv) {
+		v := ac.content[arraypos]
+		if rc.iv[rlepos].last() < v {
+			rlepos = rc.advanceUntilRun(rlepos, v)
+		} else if v < rc.iv[rlepos].start {
+			arraypos = advanceUntil(ac.content, arraypos, len(ac.content), rc.iv[rlepos].start)
+		} else {
+			iv = appendInterval16(iv, v, v)
+			arraypos++
+		}
 	}
-	*rc = *rc2
+	rc.iv = iv
+	rc.card = 0
 	return rc
-
-	/*
-		// TODO: optimize by doing less allocation, possibly?
-		out := newRunContainer16()
-		for _, p := range rc.iv {
-			for i := p.start; i <= p.last; i++ {
-				if ac.contains(i) {
-					out.Add(i)
-				}
-			}
-		}
-		*rc = *out
-		return rc
-	*/
 }
 
 func (rc *runContainer16) andNot(a container) container {
@@ -190,6 +257,109 @@ func (rc *runContainer16) getShortIterator() shortIterable {
 	return rc.newRunIterator16()
 }
 
+func (rc *runContainer16) iterate(hs uint32, cb func(x uint32) bool) bool {
+	for _, iv := range rc.iv {
+		for v := int(iv.start); v <= int(iv.last()); v++ {
+			if !cb(hs | uint32(v)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (rc *runContainer16) iterateRuns(cb func(start, last uint16) bool) bool {
+	for _, iv := range rc.iv {
+		if !cb(iv.start, iv.last()) {
+			return false
+		}
+	}
+	return true
+}
+
+// runManyIterator16 expands the runs of a run container in chunks
+type runManyIterator16 struct {
+	rc    *runContainer16
+	index int // the run holding the next value
+	cur   int // the next value
+}
+
+func (ri *runManyIterator16) reset(rc *runContainer16) {
+	ri.rc = rc
+	ri.index = 0
+	ri.cur = int(rc.iv[0].start)
+}
+
+func (ri *runManyIterator16) nextMany(hs uint32, buf []uint32) int {
+	n := 0
+	for n < len(buf) && ri.index < len(ri.rc.iv) {
+		last := int(ri.rc.iv[ri.index].last())
+		end := ri.cur + len(buf) - n - 1
+		if end > last {
+			end = last
+		}
+		for v := ri.cur; v <= end; v++ {
+			buf[n] = hs | uint32(v)
+			n++
+		}
+		ri.cur = end + 1
+		if ri.cur > last {
+			ri.index++
+			if ri.index < len(ri.rc.iv) {
+				ri.cur = int(ri.rc.iv[ri.index].start)
+			}
+		}
+	}
+	return n
+}
+
+// runReverseIterator16 iterates over the values of a run
+// container in decreasing order
+type runReverseIterator16 struct {
+	rc    *runContainer16
+	index int // the run holding the next value, -1 at the end
+	cur   int // the next value
+}
+
+func (ri *runReverseIterator16) hasNext() bool {
+	return ri.index >= 0
+}
+
+func (ri *runReverseIterator16) next() uint16 {
+	x := uint16(ri.cur)
+	if ri.cur > int(ri.rc.iv[ri.index].start) {
+		ri.cur--
+	} else {
+		ri.index--
+		if ri.index >= 0 {
+			ri.cur = int(ri.rc.iv[ri.index].last())
+		}
+	}
+	return x
+}
+
+func (ri *runReverseIterator16) advanceIfNeeded(maxval uint16) {
+	if ri.index < 0 || ri.cur <= int(maxval) {
+		return
+	}
+	w, _, _ := ri.rc.search(int64(maxval), &searchOptions{endxIndex: int64(ri.index + 1)})
+	ri.index = int(w)
+	if ri.index >= 0 {
+		ri.cur = int(ri.rc.iv[ri.index].last())
+		if ri.cur > int(maxval) {
+			ri.cur = int(maxval)
+		}
+	}
+}
+
+func (rc *runContainer16) getReverseIterator() reverseShortIterable {
+	ri := &runReverseIterator16{rc: rc, index: len(rc.iv) - 1}
+	if ri.index >= 0 {
+		ri.cur = int(rc.iv[ri.index].last())
+	}
+	return ri
+}
+
 // add the values in the range [firstOfRange, endx). endx
 // is still abe to express 2^16 because it is an int not an uint16.
 func (rc *runContainer16) iaddRange(firstOfRange, endx int) container {
@@ -198,10 +368,7 @@ func (rc *runContainer16) iaddRange(firstOfRange, endx int) container {
 		panic(fmt.Sprintf("invalid %v = endx >= firstOfRange", endx))
 	}
 	addme := newRunContainer16TakeOwnership([]interval16{
-		{
-			start: uint16(firstOfRange),
-			last:  uint16(endx - 1),
-		},
+		newInterval16Range(uint16(firstOfRange), uint16(endx-1)),
 	})
 	*rc = *rc.union(addme)
 	return rc
@@ -214,7 +381,7 @@ func (rc *runContainer16) iremoveRange(firstOfRange, endx int) container {
 			" nothing to do.", firstOfRange, endx))
 		//return rc
 	}
-	x := interval16{start: uint16(firstOfRange), last: uint16(endx - 1)}
+	x := newInterval16Range(uint16(firstOfRange), uint16(endx-1))
 	rc.isubtract(x)
 	return rc
 }
@@ -256,7 +423,7 @@ func (rc *runContainer16) Not(firstOfRange, endx int) *runContainer16 {
 
 	nota := a.invert()
 
-	bs := []interval16{{start: uint16(firstOfRange), last: uint16(endx - 1)}}
+	bs := []interval16{newInterval16Range(uint16(firstOfRange), uint16(endx-1))}
 	b := newRunContainer16TakeOwnership(bs)
 
 	notAintersectB := nota.intersect(b)
@@ -362,14 +529,21 @@ func (rc *runContainer16) orCardinality(a container) int {
 
 // orBitmapContainer finds the union of rc and bc.
 func (rc *runContainer16) orBitmapContainer(bc *bitmapContainer) container {
-	bc2 := newBitmapContainerFromRun(rc)
-	return bc2.iorBitmap(bc)
+	answer := bc.clone().(*bitmapContainer)
+	answer.repairCardinality()
+	for i := range rc.iv {
+		answer.cardinality += setBitmapRangeAndCardinalityChange(answer.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	if answer.isFull() {
+		return newRunContainer16Range(0, MaxUint16)
+	}
+	return answer
 }
 
 func (rc *runContainer16) andBitmapContainerCardinality(bc *bitmapContainer) int {
 	answer := 0
 	for i := range rc.iv {
-		answer += bc.getCardinalityInRange(uint(rc.iv[i].start), uint(rc.iv[i].last)+1)
+		answer += bc.getCardinalityInRange(uint(rc.iv[i].start), uint(rc.iv[i].last())+1)
 	}
 	//bc.computeCardinality()
 	return answer
@@ -381,9 +555,24 @@ func (rc *runContainer16) orBitmapContainerCardinality(bc *bitmapContainer) int
 
 // orArray finds the union of rc and ac.
 func (rc *runContainer16) orArray(ac *arrayContainer) container {
-	bc1 := newBitmapContainerFromRun(rc)
-	bc2 := ac.toBitmapContainer()
-	return bc1.orBitmap(bc2)
+	return newRunContainer16TakeOwnership(rc.orArrayRuns(ac)).toEfficientContainer()
+}
+
+// orArrayRuns merges the values of ac into the runs of rc, and returns
+// the runs of their union.
+func (rc *runContainer16) orArrayRuns(ac *arrayContainer) []interval16 {
+	iv := make([]interval16, 0, len(rc.iv))
+	rlepos, arraypos := 0, 0
+	for rlepos < len(rc.iv) || arraypos < len(ac.content) {
+		if arraypos == len(ac.content) || (rlepos < len(rc.iv) && rc.iv[rlepos].start <= ac.content[arraypos]) {
+			iv = appendInterval16(iv, rc.iv[rlepos].start, rc.iv[rlepos].last())
+			rlepos++
+		} else {
+			iv = appendInterval16(iv, ac.content[arraypos], ac.content[arraypos])
+			arraypos++
+		}
+	}
+	return iv
 }
 
 // orArray finds the union of rc and ac.
@@ -409,7 +598,7 @@ func (rc *runContainer16) ior(a container) container {
 func (rc *runContainer16) inplaceUnion(rc2 *runContainer16) container {
 	p("rc.inplaceUnion with len(rc2.iv)=%v", len(rc2.iv))
 	for _, p := range rc2.iv {
-		last := int64(p.last)
+		last := int64(p.last())
 		for i := int64(p.start); i <= last; i++ {
 			rc.Add(uint16(i))
 		}
@@ -418,19 +607,14 @@ func (rc *runContainer16) inplaceUnion(rc2 *runContainer16) container {
 }
 
 func (rc *runContainer16) iorBitmapContainer(bc *bitmapContainer) container {
-
-	it := bc.getShortIterator()
-	for it.hasNext() {
-		rc.Add(it.next())
-	}
+	union := rc.orBitmapContainer(bc)
+	*rc = *newRunContainer16FromContainer(union)
 	return rc
 }
 
 func (rc *runContainer16) iorArray(ac *arrayContainer) container {
-	it := ac.getShortIterator()
-	for it.hasNext() {
-		rc.Add(it.next())
-	}
+	rc.iv = rc.orArrayRuns(ac)
+	rc.card = 0
 	return rc
 }
 
@@ -592,21 +776,54 @@ func (rc *runContainer16) andNotRunContainer16(b *runContainer16) container {
 }
 
 func (rc *runContainer16) andNotArray(ac *arrayContainer) container {
-	rcb := rc.toBitmapContainer()
-	acb := ac.toBitmapContainer()
-	return rcb.andNotBitmap(acb)
+	return newRunContainer16TakeOwnership(rc.andNotArrayRuns(ac)).toEfficientContainer()
+}
+
+// andNotArrayRuns returns the runs of rc with holes punched at the
+// values of ac.
+func (rc *runContainer16) andNotArrayRuns(ac *arrayContainer) []interval16 {
+	iv := make([]interval16, 0, len(rc.iv))
+	arraypos := 0
+	for _, run := range rc.iv {
+		start := int(run.start) // the first value of the run not yet handled
+		arraypos = advanceUntil(ac.content, arraypos-1, len(ac.content), run.start)
+		for ; arraypos < len(ac.content) && ac.content[arraypos] <= run.last(); arraypos++ {
+			v := int(ac.content[arraypos])
+			if v > start {
+				iv = append(iv, newInterval16Range(uint16(start), uint16(v-1)))
+			}
+			start = v + 1
+		}
+		if start <= int(run.last()) {
+			iv = append(iv, newInterval16Range(uint16(start), run.last()))
+		}
+	}
+	return iv
 }
 
 func (rc *runContainer16) andNotBitmap(bc *bitmapContainer) container {
-	rcb := rc.toBitmapContainer()
-	return rcb.andNotBitmap(bc)
+	card := rc.getCardinality() - rc.andBitmapContainerCardinality(bc)
+	if card <= arrayDefaultMaxSize {
+		answer := newArrayContainerCapacity(card)
+		answer.content = rc.appendBitsInRuns(answer.content, bc.bitmap, true)
+		return answer
+	}
+	answer := newBitmapContainer()
+	for i := range rc.iv {
+		setBitmapRange(answer.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	for k := range answer.bitmap {
+		answer.bitmap[k] &^= bc.bitmap[k]
+	}
+	answer.cardinality = card
+	return answer
 }
 
 func (rc *runContainer16) toBitmapContainer() *bitmapContainer {
 	p("run16 toBitmap starting; rc has %v ranges", len(rc.iv))
 	bc := newBitmapContainer()
 	for i := range rc.iv {
-		bc.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last)+1)
+		bc.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last())+1)
 	}
 	bc.computeCardinality()
 	return bc
@@ -624,23 +841,14 @@ func (rc *runContainer16) iandNotRunContainer16(x2 *runContainer16) container {
 }
 
 func (rc *runContainer16) iandNotArray(ac *arrayContainer) container {
-	rcb := rc.toBitmapContainer()
-	acb := ac.toBitmapContainer()
-	rcb.iandNotBitmapSurely(acb)
-	// TODO: check size and optimize the return value
-	// TODO: is inplace modification really required? If not, elide the copy.
-	rc2 := newRunContainer16FromBitmapContainer(rcb)
-	*rc = *rc2
+	rc.iv = rc.andNotArrayRuns(ac)
+	rc.card = 0
 	return rc
 }
 
 func (rc *runContainer16) iandNotBitmap(bc *bitmapContainer) container {
-	rcb := rc.toBitmapContainer()
-	rcb.iandNotBitmapSurely(bc)
-	// TODO: check size and optimize the return value
-	// TODO: is inplace modification really required? If not, elide the copy.
-	rc2 := newRunContainer16FromBitmapContainer(rcb)
-	*rc = *rc2
+	diff := rc.andNotBitmap(bc)
+	*rc = *newRunContainer16FromContainer(diff)
 	return rc
 }
 
@@ -650,15 +858,45 @@ func (rc *runContainer16) xorRunContainer16(x2 *runContainer16) container {
 	return rcb.xorBitmap(x2b)
 }
 
+// xorArray finds the symmetric difference of rc and ac: the values of
+// ac punch holes in the runs that hold them, and the others become
+// runs of their own.
 func (rc *runContainer16) xorArray(ac *arrayContainer) container {
-	rcb := rc.toBitmapContainer()
-	acb := ac.toBitmapContainer()
-	return rcb.xorBitmap(acb)
+	iv := make([]interval16, 0, len(rc.iv))
+	arraypos := 0
+	for _, run := range rc.iv {
+		start := int(run.start) // the first value of the run not yet handled
+		for ; arraypos < len(ac.content) && ac.content[arraypos] <= run.last(); arraypos++ {
+			v := int(ac.content[arraypos])
+			if v < start {
+				iv = appendInterval16(iv, uint16(v), uint16(v))
+				continue
+			}
+			if v > start {
+				iv = appendInterval16(iv, uint16(start), uint16(v-1))
+			}
+			start = v + 1
+		}
+		if start <= int(run.last()) {
+			iv = appendInterval16(iv, uint16(start), run.last())
+		}
+	}
+	for ; arraypos < len(ac.content); arraypos++ {
+		iv = appendInterval16(iv, ac.content[arraypos], ac.content[arraypos])
+	}
+	return newRunContainer16TakeOwnership(iv).toEfficientContainer()
 }
 
 func (rc *runContainer16) xorBitmap(bc *bitmapContainer) container {
-	rcb := rc.toBitmapContainer()
-	return rcb.xorBitmap(bc)
+	answer := bc.clone().(*bitmapContainer)
+	answer.repairCardinality()
+	for i := range rc.iv {
+		answer.cardinality += flipBitmapRangeAndCardinalityChange(answer.bitmap, int(rc.iv[i].start), int(rc.iv[i].last())+1)
+	}
+	if answer.cardinality <= arrayDefaultMaxSize {
+		return answer.toArrayContainer()
+	}
+	return answer
 }
 
 // convert to bitmap or array *if needed*
@@ -682,7 +920,7 @@ func (rc *runContainer16) toEfficientContainer() container {
 func (rc *runContainer16) toArrayContainer() *arrayContainer {
 	ac := newArrayContainer()
 	for i := range rc.iv {
-		ac.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last)+1)
+		ac.iaddRange(int(rc.iv[i].start), int(rc.iv[i].last())+1)
 	}
 	return ac
 }
@@ -809,29 +809,29 @@ func TestRle16SubtractionOfIntervals019(t *testing.T) {
 	Convey("runContainer `subtract` operation removes an interval in-place", t, func() {
 		// basics
 
-		i22 := interval16{start: 2, last: 2}
+		i22 := newInterval16Range(2, 2)
 		left, _ := i22.subtractInterval(i22)
 		So(len(left), ShouldResemble, 0)
 
-		v := interval16{start: 1, last: 6}
-		left, _ = v.subtractInterval(interval16{start: 3, last: 4})
+		v := newInterval16Range(1, 6)
+		left, _ = v.subtractInterval(newInterval16Range(3, 4))
 		So(len(left), ShouldResemble, 2)
 		So(left[0].start, ShouldEqual, 1)
-		So(left[0].last, ShouldEqual, 2)
+		So(left[0].last(), ShouldEqual, 2)
 		So(left[1].start, ShouldEqual, 5)
-		So(left[1].last, ShouldEqual, 6)
+		So(left[1].last(), ShouldEqual, 6)
 
-		v = interval16{start: 1, last: 6}
-		left, _ = v.subtractInterval(interval16{start: 4, last: 10})
+		v = newInterval16Range(1, 6)
+		left, _ = v.subtractInterval(newInterval16Range(4, 10))
 		So(len(left), ShouldResemble, 1)
 		So(left[0].start, ShouldEqual, 1)
-		So(left[0].last, ShouldEqual, 3)
+		So(left[0].last(), ShouldEqual, 3)
 
-		v = interval16{start: 5, last: 10}
-		left, _ = v.subtractInterval(interval16{start: 0, last: 7})
+		v = newInterval16Range(5, 10)
+		left, _ = v.subtractInterval(newInterval16Range(0, 7))
 		So(len(left), ShouldResemble, 1)
 		So(left[0].start, ShouldEqual, 8)
-		So(left[0].last, ShouldEqual, 10)
+		So(left[0].last(), ShouldEqual, 10)
 
 		seed := int64(42)
 		p("seed is %v", seed)
@@ -887,7 +887,7 @@ func TestRle16SubtractionOfIntervals019(t *testing.T) {
 				it := rcb.newRunIterator16()
 				for it.hasNext() {
 					nx := it.next()
-					rc.isubtract(interval16{start: nx, last: nx})
+					rc.isubtract(newInterval16Range(nx, nx))
 				}
 
 				// also check full interval subtraction
@@ -1614,7 +1614,7 @@ func TestAllContainerMethodsAllContainerTypesWithData067(t *testing.T) {
 
 		trials := []trial{
 			{n: 100, percentFill: .7, ntrial: 1, numRandomOpsPass: 100},
-			{n: 100, percentFill: .7, ntrial: 1, numRandomOpsPass: 100, srang: &interval16{MaxUint16 - 100, MaxUint16}}}
+			{n: 100, percentFill: .7, ntrial: 1, numRandomOpsPass: 100, srang: &interval16{start: MaxUint16 - 100, length: 100}}}
 
 		tester := func(tr trial) {
 			for j := 0; j < tr.ntrial; j++ {
@@ -1770,7 +1770,7 @@ func getRandomSameThreeContainers(tr trial) (*arrayContainer, *runContainer16, *
 		if n-1 > MaxUint16 {
 			panic(fmt.Errorf("n out of range: %v", n))
 		}
-		samp.last = uint16(n - 1)
+		samp.length = uint16(n - 1)
 	}
 
 	draw := int(float64(n) * tr.percentFill)
@@ -1792,3 +1792,95 @@ func getRandomSameThreeContainers(tr trial) (*arrayContainer, *runContainer16, *
 
 	return ac, rc, bc
 }
+
+func TestRunArrayAndRunBitmapKernels068(t *testing.T) {
+
+	Convey("the run x array and run x bitmap operations should match a brute force computation", t, func() {
+		r := rand.New(rand.NewSource(68))
+		valuesOf := func(set []bool) []uint16 {
+			ans := []uint16{}
+			for v, in := range set {
+				if in {
+					ans = append(ans, uint16(v))
+				}
+			}
+			return ans
+		}
+		for trial := 0; trial < 40; trial++ {
+			inRun := make([]bool, 1<<16)
+			rc := newRunContainer16()
+			for start := r.Intn(100); start < 1<<16; start += 2 + r.Intn(2000) {
+				last := start + r.Intn(1+r.Intn(1000))
+				if trial%5 == 0 && last >= 1<<16-500 {
+					last = MaxUint16
+				}
+				if last > MaxUint16 {
+					last = MaxUint16
+				}
+				rc.iv = append(rc.iv, newInterval16Range(uint16(start), uint16(last)))
+				for v := start; v <= last; v++ {
+					inRun[v] = true
+				}
+				start = last
+			}
+			other := make([]bool, 1<<16)
+			var o container
+			if trial%2 == 0 {
+				ac := newArrayContainer()
+				for i := r.Intn(4096); i > 0; i-- {
+					v := uint16(r.Intn(1 << 16))
+					ac.iadd(v)
+					other[v] = true
+				}
+				o = ac
+			} else {
+				bc := newBitmapContainer()
+				for i := 5000 + r.Intn(40000); i > 0; i-- {
+					v := uint16(r.Intn(1 << 16))
+					bc.iadd(v)
+					other[v] = true
+				}
+				o = bc
+			}
+			expect := func(keep func(a, b bool) bool) []uint16 {
+				set := make([]bool, 1<<16)
+				for v := range set {
+					set[v] = keep(inRun[v], other[v])
+				}
+				return valuesOf(set)
+			}
+			and := expect(func(a, b bool) bool { return a && b })
+			or := expect(func(a, b bool) bool { return a || b })
+			xor := expect(func(a, b bool) bool { return a != b })
+			runNotOther := expect(func(a, b bool) bool { return a && !b })
+			otherNotRun := expect(func(a, b bool) bool { return b && !a })
+
+			for _, tc := range []struct {
+				c      container
+				values []uint16
+			}{
+				{rc.and(o), and},
+				{o.and(rc), and},
+				{rc.or(o), or},
+				{o.or(rc), or},
+				{rc.xor(o), xor},
+				{o.xor(rc), xor},
+				{rc.andNot(o), runNotOther},
+				{o.andNot(rc), otherNotRun},
+				{rc.clone().iand(o), and},
+				{o.clone().iand(rc), and},
+				{rc.clone().ior(o), or},
+				{o.clone().ior(rc), or},
+				{rc.clone().iandNot(o), runNotOther},
+				{o.clone().iandNot(rc), otherNotRun},
+			} {
+				So(checkContent(tc.c, tc.values), ShouldBeTrue)
+				So(tc.c.getCardinality(), ShouldEqual, len(tc.values))
+			}
+			So(rc.andCardinality(o), ShouldEqual, len(and))
+			So(o.andCardinality(rc), ShouldEqual, len(and))
+			So(rc.orCardinality(o), ShouldEqual, len(or))
+			So(o.orCardinality(rc), ShouldEqual, len(or))
+		}
+	})
+}
@@ -17,6 +17,11 @@ import (
 // Bitmap represents a compressed bitmap where you can add integers.
 type Bitmap struct {
 	highlowcontainer roaringArray
+	// the pool the bitmap was computed from, if any, for Release
+	pool *ContainerPool
+	// the containers of the bitmap while it is overwritten by AndInto and
+	// the like, for the result to reuse
+	buffers *containerBuffers
 }
 
 // ToBase64 serializes a bitmap as Base64
@@ -52,6 +57,13 @@ func (rb *Bitmap) ToBytes() ([]byte, error) {
 	return rb.highlowcontainer.toBytes()
 }
 
+// AppendBinary appends the bytes that WriteTo would write to dst and
+// returns the extended slice. If dst has enough spare capacity
+// (see GetSerializedSizeInBytes), nothing is allocated.
+func (rb *Bitmap) AppendBinary(dst []byte) ([]byte, error) {
+	return rb.highlowcontainer.appendBinary(dst), nil
+}
+
 // WriteToMsgpack writes a msgpack2/snappy-streaming compressed serialized
 // version of this bitmap to stream. The format is not
 // compatible with the WriteTo() format, and is
@@ -68,12 +80,34 @@ func (rb *Bitmap) WriteToMsgpack(stream io.Writer) (int64, error) {
 // implementations (Java, C) and is documented here:
 // https://github.com/RoaringBitmap/RoaringFormatSpec
 func (rb *Bitmap) ReadFrom(stream io.Reader) (int64, error) {
+	rb.highlowcontainer.setCardinality(rb.highlowcontainer.cardinalityBeforeUpdate())
 	return rb.highlowcontainer.readFrom(stream)
 }
 
+// FromBuffer returns a bitmap that is a read-only view of the
+// serialized bitmap in buf, as written by WriteTo or ToBytes.
+// Unlike ReadFrom, the containers point directly into buf rather
+// than being copied out of it, which makes FromBuffer cheap on
+// memory-mapped files. The first write to a container copies it,
+// so buf itself is never modified. The caller must not modify buf
+// while the returned bitmap, or any bitmap computed from it, is in use.
+// On platforms other than amd64 and 386 the containers are decoded
+// into fresh memory instead.
+func FromBuffer(buf []byte) (*Bitmap, error) {
+	rb := NewBitmap()
+	rb.highlowcontainer.setCardinality(0)
+	_, err := rb.highlowcontainer.fromBuffer(buf)
+	if err != nil {
+		return nil, err
+	}
+	return rb, nil
+}
+
 // RunOptimize attempts to further compress the runs of consecutive values found in the bitmap
 func (rb *Bitmap) RunOptimize() {
+	card := rb.highlowcontainer.cardinalityBeforeUpdate()
 	rb.highlowcontainer.runOptimize()
+	rb.highlowcontainer.setCardinality(card)
 }
 
 // HasRunCompression returns true if the bitmap benefits from run compression
@@ -91,17 +125,7 @@ func (rb *Bitmap) ReadFromMsgpack(stream io.Reader) (int64, error) {
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface for the bitmap
 func (rb *Bitmap) MarshalBinary() ([]byte, error) {
-	var buf bytes.Buffer
-	writer := bufio.NewWriter(&buf)
-	_, err := rb.WriteTo(writer)
-	if err != nil {
-		return nil, err
-	}
-	err = writer.Flush()
-	if err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	return rb.ToBytes()
 }
 
 // UnmarshalBinary implements the encoding.BinaryUnmarshaler interface for the bitmap
@@ -118,17 +142,18 @@ func (rb *Bitmap) UnmarshalBinary(data []byte) error {
 
 // NewBitmap creates a new empty Bitmap (see also New)
 func NewBitmap() *Bitmap {
-	return &Bitmap{*newRoaringArray()}
+	return &Bitmap{highlowcontainer: *newRoaringArray()}
 }
 
 // New creates a new empty Bitmap (same as NewBitmap)
 func New() *Bitmap {
-	return &Bitmap{*newRoaringArray()}
+	return &Bitmap{highlowcontainer: *newRoaringArray()}
 }
 
 // Clear removes all content from the Bitmap and frees the memory
 func (rb *Bitmap) Clear() {
-	rb.highlowcontainer = *newRoaringArray()
+	rb.highlowcontainer.clear()
+	rb.highlowcontainer.setCardinality(0)
 }
 
 // ToArray creates a new slice containing all of the integers stored in the Bitmap in sorted order
@@ -154,6 +179,9 @@ func (rb *Bitmap) GetSizeInBytes() uint64 {
 	for _, c := range rb.highlowcontainer.containers {
 		size += uint64(2) + uint64(c.getSizeInBytes())
 	}
+	if rb.highlowcontainer.index != nil {
+		size += keyIndexSizeInBytes
+	}
 	return size
 }
 
@@ -194,16 +222,52 @@ type IntIterable interface {
 	Next() uint32
 }
 
+// IntPeekable allows you to look at the next value without consuming it,
+// and to skip ahead efficiently, as when intersecting a bitmap with a
+// sorted stream of values
+type IntPeekable interface {
+	IntIterable
+	// PeekNext returns the value Next would return, without
+	// consuming it. HasNext must be true.
+	PeekNext() uint32
+	// AdvanceIfNeeded skips the values smaller than minval
+	AdvanceIfNeeded(minval uint32)
+}
+
+// IntRemovable is an IntPeekable that can remove values from its bitmap
+// while iterating over it
+type IntRemovable interface {
+	IntPeekable
+	// Remove removes from the bitmap the value last returned by Next
+	Remove()
+}
+
+// The iterators panic when their bitmap is modified other than through
+// them while they are in use: they would otherwise skip values, return
+// stale ones or index out of range.
 type intIterator struct {
 	pos              int
 	hs               uint32
 	iter             shortIterable
 	highlowcontainer *roaringArray
+	bitmap           *Bitmap
+	// the modification count of the bitmap the iterator expects
+	modCount uint64
+	// the values from end on are not iterated over; end is
+	// 1<<32 unless the iterator comes from IteratorRange
+	end uint64
+	// the value last returned by Next, if it has not been removed yet
+	last    uint32
+	hasLast bool
 }
 
 // HasNext returns true if there are more integers to iterate over
 func (ii *intIterator) HasNext() bool {
-	return ii.pos < ii.highlowcontainer.size()
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	if ii.pos >= ii.highlowcontainer.size() {
+		return false
+	}
+	return ii.end > MaxUint32 || uint64(ii.PeekNext()) < ii.end
 }
 
 func (ii *intIterator) init() {
@@ -215,22 +279,193 @@ func (ii *intIterator) init() {
 
 // Next returns the next integer
 func (ii *intIterator) Next() uint32 {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
 	x := uint32(ii.iter.next()) | ii.hs
 	if !ii.iter.hasNext() {
 		ii.pos = ii.pos + 1
 		ii.init()
 	}
+	ii.last, ii.hasLast = x, true
 	return x
 }
 
+// PeekNext returns the next integer without consuming it
+func (ii *intIterator) PeekNext() uint32 {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	return uint32(ii.iter.peekNext()) | ii.hs
+}
+
+// Remove removes from the bitmap the integer last returned by Next.
+// The iterator then goes on from the integer after it, which it finds
+// again by a binary search over the keys.
+func (ii *intIterator) Remove() {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	if !ii.hasLast {
+		panic("roaring: Remove called without a value returned by Next")
+	}
+	ii.hasLast = false
+	ii.bitmap.Remove(ii.last)
+	ii.modCount = ii.highlowcontainer.modCount
+	if ii.last == MaxUint32 {
+		ii.pos = ii.highlowcontainer.size()
+		return
+	}
+	ii.pos = 0
+	ii.init()
+	ii.AdvanceIfNeeded(ii.last + 1)
+}
+
+// AdvanceIfNeeded skips the integers smaller than minval: the
+// containers are skipped by a binary search over their keys,
+// and the values within a container by its own iterator
+func (ii *intIterator) AdvanceIfNeeded(minval uint32) {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	size := ii.highlowcontainer.size()
+	if ii.pos >= size {
+		return
+	}
+	key := highbits(minval)
+	if ii.highlowcontainer.getKeyAtIndex(ii.pos) < key {
+		i := ii.highlowcontainer.binarySearch(int64(ii.pos+1), int64(size), key)
+		if i < 0 {
+			i = -i - 1
+		}
+		ii.pos = i
+		ii.init()
+		if ii.pos >= size {
+			return
+		}
+	}
+	if ii.highlowcontainer.getKeyAtIndex(ii.pos) == key {
+		ii.iter.advanceIfNeeded(lowbits(minval))
+		if !ii.iter.hasNext() {
+			ii.pos = ii.pos + 1
+			ii.init()
+		}
+	}
+}
+
 func newIntIterator(a *Bitmap) *intIterator {
 	p := new(intIterator)
 	p.pos = 0
 	p.highlowcontainer = &a.highlowcontainer
+	p.bitmap = a
+	p.modCount = a.highlowcontainer.modCount
+	p.end = 1 << 32
+	p.init()
+	return p
+}
+
+type intReverseIterator struct {
+	pos              int
+	hs               uint32
+	iter             reverseShortIterable
+	highlowcontainer *roaringArray
+	modCount         uint64
+}
+
+// HasNext returns true if there are more integers to iterate over
+func (ii *intReverseIterator) HasNext() bool {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	return ii.pos >= 0
+}
+
+func (ii *intReverseIterator) init() {
+	if ii.pos >= 0 {
+		ii.iter = ii.highlowcontainer.getContainerAtIndex(ii.pos).getReverseIterator()
+		ii.hs = uint32(ii.highlowcontainer.getKeyAtIndex(ii.pos)) << 16
+	}
+}
+
+// Next returns the next integer
+func (ii *intReverseIterator) Next() uint32 {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	x := uint32(ii.iter.next()) | ii.hs
+	if !ii.iter.hasNext() {
+		ii.pos = ii.pos - 1
+		ii.init()
+	}
+	return x
+}
+
+func newIntReverseIterator(a *Bitmap) *intReverseIterator {
+	p := new(intReverseIterator)
+	p.highlowcontainer = &a.highlowcontainer
+	p.modCount = a.highlowcontainer.modCount
+	p.pos = p.highlowcontainer.size() - 1
 	p.init()
 	return p
 }
 
+// ManyIntIterable allows you to iterate over the values in a Bitmap
+// in batches, which is much faster than one value at a time
+type ManyIntIterable interface {
+	// NextMany fills buf with the next values and returns how many it
+	// wrote. It only returns less than len(buf) at the end of the bitmap.
+	NextMany(buf []uint32) int
+	// Reset restarts the iteration over the values of a, without allocating
+	Reset(a *Bitmap)
+}
+
+type manyIntIterator struct {
+	pos              int
+	hs               uint32
+	iter             manyIterable
+	highlowcontainer *roaringArray
+	modCount         uint64
+	// the container iterators, reused from one container to the next
+	arrayIter  shortIterator
+	bitmapIter bitmapContainerManyIterator
+	runIter    runManyIterator16
+}
+
+func (ii *manyIntIterator) init() {
+	if ii.highlowcontainer.size() > ii.pos {
+		switch c := ii.highlowcontainer.getContainerAtIndex(ii.pos).(type) {
+		case *arrayContainer:
+			ii.arrayIter = shortIterator{c.content, 0}
+			ii.iter = &ii.arrayIter
+		case *bitmapContainer:
+			ii.bitmapIter.reset(c)
+			ii.iter = &ii.bitmapIter
+		case *runContainer16:
+			ii.runIter.reset(c)
+			ii.iter = &ii.runIter
+		}
+		ii.hs = uint32(ii.highlowcontainer.getKeyAtIndex(ii.pos)) << 16
+	}
+}
+
+// NextMany fills buf with the next integers and returns how many it wrote
+func (ii *manyIntIterator) NextMany(buf []uint32) int {
+	ii.highlowcontainer.checkUnmodified(ii.modCount)
+	n := 0
+	for n < len(buf) && ii.pos < ii.highlowcontainer.size() {
+		k := ii.iter.nextMany(ii.hs, buf[n:])
+		if n+k < len(buf) {
+			// the container is done
+			ii.pos = ii.pos + 1
+			ii.init()
+		}
+		n += k
+	}
+	return n
+}
+
+// Reset restarts the iteration over the integers of a
+func (ii *manyIntIterator) Reset(a *Bitmap) {
+	ii.pos = 0
+	ii.highlowcontainer = &a.highlowcontainer
+	ii.modCount = a.highlowcontainer.modCount
+	ii.init()
+}
+
+func newManyIntIterator(a *Bitmap) *manyIntIterator {
+	p := new(manyIntIterator)
+	p.Reset(a)
+	return p
+}
+
 // String creates a string representation of the Bitmap
 func (rb *Bitmap) String() string {
 	// inspired by https://github.com/fzandona/goroar/
@@ -262,6 +497,120 @@ func (rb *Bitmap) Iterator() IntIterable {
 	return newIntIterator(rb)
 }
 
+// PeekableIterator creates a new IntPeekable to iterate over the integers contained in the bitmap, in sorted order
+func (rb *Bitmap) PeekableIterator() IntPeekable {
+	return newIntIterator(rb)
+}
+
+// RemovableIterator creates a new IntRemovable to iterate over the integers contained in the bitmap, in sorted order,
+// removing some of them on the way
+func (rb *Bitmap) RemovableIterator() IntRemovable {
+	return newIntIterator(rb)
+}
+
+// Iterate calls cb on the integers contained in the bitmap, in sorted
+// order, until cb returns false. It does not allocate an iterator.
+// Iterate panics if cb modifies the bitmap and goes on, which is
+// checked before each integer is passed to cb.
+func (rb *Bitmap) Iterate(cb func(x uint32) bool) {
+	ra := &rb.highlowcontainer
+	modCount := ra.modCount
+	checked := func(x uint32) bool {
+		ra.checkUnmodified(modCount)
+		return cb(x)
+	}
+	for i, c := range ra.containers {
+		if !c.iterate(uint32(ra.keys[i])<<16, checked) {
+			return
+		}
+	}
+}
+
+// iterateRuns calls cb on the maximal runs [start, last] of consecutive
+// integers contained in the bitmap, in sorted order, until cb returns
+// false. Runs going across containers are merged.
+func (rb *Bitmap) iterateRuns(cb func(start, last uint32) bool) {
+	ra := &rb.highlowcontainer
+	modCount := ra.modCount
+	checked := func(start, last uint32) bool {
+		ra.checkUnmodified(modCount)
+		return cb(start, last)
+	}
+	pending := false
+	var pstart, plast uint32
+	for i, c := range ra.containers {
+		hs := uint32(ra.keys[i]) << 16
+		more := c.iterateRuns(func(start, last uint16) bool {
+			s, l := hs|uint32(start), hs|uint32(last)
+			if pending && s == plast+1 {
+				plast = l
+				return true
+			}
+			if pending && !checked(pstart, plast) {
+				return false
+			}
+			pstart, plast, pending = s, l, true
+			return true
+		})
+		if !more {
+			return
+		}
+	}
+	if pending {
+		checked(pstart, plast)
+	}
+}
+
+// ManyIterator creates a new ManyIntIterable to iterate over the integers contained in the bitmap, in sorted order
+func (rb *Bitmap) ManyIterator() ManyIntIterable {
+	return newManyIntIterator(rb)
+}
+
+// ReverseIterator creates a new IntIterable to iterate over the integers contained in the bitmap, in decreasing order
+func (rb *Bitmap) ReverseIterator() IntIterable {
+	return newIntReverseIterator(rb)
+}
+
+// ReverseIteratorFrom creates a new IntIterable to iterate over the
+// integers contained in the bitmap that are not larger than maxval,
+// in decreasing order
+func (rb *Bitmap) ReverseIteratorFrom(maxval uint32) IntIterable {
+	it := new(intReverseIterator)
+	it.highlowcontainer = &rb.highlowcontainer
+	it.modCount = rb.highlowcontainer.modCount
+	i := rb.highlowcontainer.getIndex(highbits(maxval))
+	if i < 0 {
+		// start from the container before the insertion point
+		it.pos = -i - 2
+		it.init()
+		return it
+	}
+	it.pos = i
+	it.init()
+	it.iter.advanceIfNeeded(lowbits(maxval))
+	if !it.iter.hasNext() {
+		it.pos = it.pos - 1
+		it.init()
+	}
+	return it
+}
+
+// IteratorRange creates a new IntPeekable to iterate over the integers
+// contained in the bitmap in [rangeStart, rangeEnd), in sorted order
+func (rb *Bitmap) IteratorRange(rangeStart, rangeEnd uint64) IntPeekable {
+	it := newIntIterator(rb)
+	if rangeEnd > 1<<32 {
+		rangeEnd = 1 << 32
+	}
+	if rangeStart >= rangeEnd {
+		it.pos = it.highlowcontainer.size()
+		return it
+	}
+	it.AdvanceIfNeeded(uint32(rangeStart))
+	it.end = rangeEnd
+	return it
+}
+
 // Clone creates a copy of the Bitmap
 func (rb *Bitmap) Clone() *Bitmap {
 	ptr := new(Bitmap)
@@ -307,46 +656,61 @@ func (rb *Bitmap) Add(x uint32) {
 	ra := &rb.highlowcontainer
 	i := ra.getIndex(hb)
 	if i >= 0 {
-		var c container
-		c = ra.getWritableContainerAtIndex(i).iaddReturnMinimized(lowbits(x))
+		card := ra.cardinalityBeforeUpdate()
+		c := ra.getWritableContainerAtIndex(i)
+		oldcard := c.getCardinality()
+		c = c.iaddReturnMinimized(lowbits(x))
 		rb.highlowcontainer.setContainerAtIndex(i, c)
+		ra.setCardinality(card + uint64(c.getCardinality()-oldcard))
 	} else {
+		card := ra.cardinalityBeforeUpdate()
 		newac := newArrayContainer()
 		rb.highlowcontainer.insertNewKeyValueAt(-i-1, hb, newac.iaddReturnMinimized(lowbits(x)))
+		ra.setCardinality(card + 1)
 	}
 }
 
-// add the integer x to the bitmap, return the container and its index
-func (rb *Bitmap) addwithptr(x uint32) (int, container) {
+// add the integer x to the bitmap, return the container and its index,
+// and the cardinality the container had before
+func (rb *Bitmap) addwithptr(x uint32) (int, container, int) {
 	hb := highbits(x)
 	ra := &rb.highlowcontainer
 	i := ra.getIndex(hb)
 	var c container
 	if i >= 0 {
-		c = ra.getWritableContainerAtIndex(i).iaddReturnMinimized(lowbits(x))
+		c = ra.getWritableContainerAtIndex(i)
+		before := c.getCardinality()
+		c = c.iaddReturnMinimized(lowbits(x))
 		rb.highlowcontainer.setContainerAtIndex(i, c)
-		return i, c
+		return i, c, before
 	}
 	newac := newArrayContainer()
 	c = newac.iaddReturnMinimized(lowbits(x))
 	rb.highlowcontainer.insertNewKeyValueAt(-i-1, hb, c)
-	return -i - 1, c
+	return -i - 1, c, 0
 }
 
 // CheckedAdd adds the integer x to the bitmap and return true  if it was added (false if the integer was already present)
 func (rb *Bitmap) CheckedAdd(x uint32) bool {
 	// TODO: add unit tests for this method
 	hb := highbits(x)
+	card := rb.highlowcontainer.cardinalityBeforeUpdate()
 	i := rb.highlowcontainer.getIndex(hb)
 	if i >= 0 {
 		C := rb.highlowcontainer.getWritableContainerAtIndex(i)
 		oldcard := C.getCardinality()
 		C = C.iaddReturnMinimized(lowbits(x))
 		rb.highlowcontainer.setContainerAtIndex(i, C)
-		return C.getCardinality() > oldcard
+		added := C.getCardinality() > oldcard
+		if added {
+			card++
+		}
+		rb.highlowcontainer.setCardinality(card)
+		return added
 	}
 	newac := newArrayContainer()
 	rb.highlowcontainer.insertNewKeyValueAt(-i-1, hb, newac.iaddReturnMinimized(lowbits(x)))
+	rb.highlowcontainer.setCardinality(card + 1)
 	return true
 
 }
@@ -361,11 +725,15 @@ func (rb *Bitmap) Remove(x uint32) {
 	hb := highbits(x)
 	i := rb.highlowcontainer.getIndex(hb)
 	if i >= 0 {
-		c := rb.highlowcontainer.getWritableContainerAtIndex(i).iremoveReturnMinimized(lowbits(x))
+		card := rb.highlowcontainer.cardinalityBeforeUpdate()
+		c := rb.highlowcontainer.getWritableContainerAtIndex(i)
+		oldcard := c.getCardinality()
+		c = c.iremoveReturnMinimized(lowbits(x))
 		rb.highlowcontainer.setContainerAtIndex(i, c)
-		if rb.highlowcontainer.getContainerAtIndex(i).getCardinality() == 0 {
+		if c.getCardinality() == 0 {
 			rb.highlowcontainer.removeAtIndex(i)
 		}
+		rb.highlowcontainer.setCardinality(card - uint64(oldcard-c.getCardinality()))
 	}
 }
 
@@ -375,15 +743,20 @@ func (rb *Bitmap) CheckedRemove(x uint32) bool {
 	hb := highbits(x)
 	i := rb.highlowcontainer.getIndex(hb)
 	if i >= 0 {
+		card := rb.highlowcontainer.cardinalityBeforeUpdate()
 		C := rb.highlowcontainer.getWritableContainerAtIndex(i)
 		oldcard := C.getCardinality()
 		C = C.iremoveReturnMinimized(lowbits(x))
 		rb.highlowcontainer.setContainerAtIndex(i, C)
+		removed := C.getCardinality() < oldcard
+		if removed {
+			card--
+		}
 		if rb.highlowcontainer.getContainerAtIndex(i).getCardinality() == 0 {
 			rb.highlowcontainer.removeAtIndex(i)
-			return true
 		}
-		return C.getCardinality() < oldcard
+		rb.highlowcontainer.setCardinality(card)
+		return removed
 	}
 	return false
 
@@ -394,16 +767,21 @@ func (rb *Bitmap) IsEmpty() bool {
 	return rb.highlowcontainer.size() == 0
 }
 
-// GetCardinality returns the number of integers contained in the bitmap
+// GetCardinality returns the number of integers contained in the bitmap.
+// The modifications of the bitmap keep it up to date, so that it is
+// usually not counted again.
 func (rb *Bitmap) GetCardinality() uint64 {
-	size := uint64(0)
-	for _, c := range rb.highlowcontainer.containers {
-		size += uint64(c.getCardinality())
+	if card, ok := rb.highlowcontainer.cachedCardinality(); ok {
+		if debugCardinality {
+			rb.highlowcontainer.checkCardinality(card)
+		}
+		return card
 	}
-	return size
+	return rb.highlowcontainer.countCardinality()
 }
 
-// Rank returns the number of integers that are smaller or equal to x (Rank(infinity) would be GetCardinality())
+// Rank returns the number of integers that are smaller or equal to x (Rank(infinity) would be GetCardinality()).
+// It sums the cardinalities of the containers before x, so it is linear in their number; see RankIndex
 func (rb *Bitmap) Rank(x uint32) uint64 {
 	size := uint64(0)
 	for i := 0; i < rb.highlowcontainer.size(); i++ {
@@ -420,7 +798,8 @@ func (rb *Bitmap) Rank(x uint32) uint64 {
 	return size
 }
 
-// Select returns the xth integer in the bitmap
+// Select returns the xth integer in the bitmap.
+// Like Rank, it is linear in the number of containers; see RankIndex
 func (rb *Bitmap) Select(x uint32) (uint32, error) {
 	if rb.GetCardinality() <= uint64(x) {
 		return 0, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, rb.GetCardinality())
@@ -439,6 +818,33 @@ func (rb *Bitmap) Select(x uint32) (uint32, error) {
 	return 0, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, rb.GetCardinality())
 }
 
+// SelectMany returns the integers of the bitmap at each of the ranks,
+// which must be sorted in increasing order. The containers are walked
+// once for all of the ranks.
+func (rb *Bitmap) SelectMany(ranks []uint32) ([]uint32, error) {
+	answer := make([]uint32, 0, len(ranks))
+	i, before := 0, uint64(0) // before counts the integers of the containers before the ith
+	for j, x := range ranks {
+		if j > 0 && x < ranks[j-1] {
+			return nil, fmt.Errorf("ranks are not sorted: %d comes after %d", x, ranks[j-1])
+		}
+		for ; i < rb.highlowcontainer.size(); i++ {
+			card := uint64(rb.highlowcontainer.getContainerAtIndex(i).getCardinality())
+			if uint64(x) < before+card {
+				break
+			}
+			before += card
+		}
+		if i == rb.highlowcontainer.size() {
+			return nil, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, before)
+		}
+		c := rb.highlowcontainer.getContainerAtIndex(i)
+		key := rb.highlowcontainer.getKeyAtIndex(i)
+		answer = append(answer, uint32(key)<<16+uint32(c.selectInt(uint16(uint64(x)-before))))
+	}
+	return answer, nil
+}
+
 // And computes the intersection between two bitmaps and stores the result in the current bitmap
 func (rb *Bitmap) And(x2 *Bitmap) {
 	pos1 := 0
@@ -487,6 +893,7 @@ main:
 		}
 	}
 	rb.highlowcontainer.resize(intersectionsize)
+	rb.highlowcontainer.setCardinality(rb.highlowcontainer.countCardinality())
 }
 
 // OrCardinality  returns the cardinality of the union between two bitmaps, bitmaps are not modified
@@ -651,8 +1058,7 @@ func (rb *Bitmap) Xor(x2 *Bitmap) {
 					break
 				}
 			} else if s1 > s2 {
-				c := x2.highlowcontainer.getWritableContainerAtIndex(pos2)
-				rb.highlowcontainer.insertNewKeyValueAt(pos1, x2.highlowcontainer.getKeyAtIndex(pos2), c)
+				rb.highlowcontainer.insertCopyAt(pos1, x2.highlowcontainer, pos2)
 				length1++
 				pos1++
 				pos2++
@@ -675,12 +1081,15 @@ func (rb *Bitmap) Xor(x2 *Bitmap) {
 	if pos1 == length1 {
 		rb.highlowcontainer.appendCopyMany(x2.highlowcontainer, pos2, length2)
 	}
+	rb.highlowcontainer.setCardinality(rb.highlowcontainer.countCardinality())
 }
 
 // Or computes the union between two bitmaps and stores the result in the current bitmap
 func (rb *Bitmap) Or(x2 *Bitmap) {
 	results := Or(rb, x2) // Todo: could be computed in-place for reduced memory usage
+	results.highlowcontainer.modCount = rb.highlowcontainer.modCount + 1
 	rb.highlowcontainer = results.highlowcontainer
+	rb.highlowcontainer.setCardinality(rb.highlowcontainer.countCardinality())
 }
 
 // AndNot computes the difference between two bitmaps and stores the result in the current bitmap
@@ -744,11 +1153,20 @@ main:
 		pos1++
 	}
 	rb.highlowcontainer.resize(intersectionsize)
+	rb.highlowcontainer.setCardinality(rb.highlowcontainer.countCardinality())
 }
 
 // Or computes the union between two bitmaps and returns the result
 func Or(x1, x2 *Bitmap) *Bitmap {
 	answer := NewBitmap()
+	or(answer, x1, x2, recycler{})
+	return answer
+}
+
+// or computes the union between two bitmaps into answer, which
+// must be empty, in containers taken from r
+func or(answer, x1, x2 *Bitmap, r recycler) {
+	answer.highlowcontainer.setCardinality(0)
 	pos1 := 0
 	pos2 := 0
 	length1 := x1.highlowcontainer.size()
@@ -760,14 +1178,14 @@ main:
 
 		for {
 			if s1 < s2 {
-				answer.highlowcontainer.appendCopy(x1.highlowcontainer, pos1)
+				r.appendCopy(&answer.highlowcontainer, x1.highlowcontainer, pos1)
 				pos1++
 				if pos1 == length1 {
 					break main
 				}
 				s1 = x1.highlowcontainer.getKeyAtIndex(pos1)
 			} else if s1 > s2 {
-				answer.highlowcontainer.appendCopy(x2.highlowcontainer, pos2)
+				r.appendCopy(&answer.highlowcontainer, x2.highlowcontainer, pos2)
 				pos2++
 				if pos2 == length2 {
 					break main
@@ -775,7 +1193,7 @@ main:
 				s2 = x2.highlowcontainer.getKeyAtIndex(pos2)
 			} else {
 
-				answer.highlowcontainer.appendContainer(s1, x1.highlowcontainer.getContainerAtIndex(pos1).or(x2.highlowcontainer.getContainerAtIndex(pos2)), false)
+				answer.highlowcontainer.appendContainer(s1, r.or(x1.highlowcontainer.getContainerAtIndex(pos1), x2.highlowcontainer.getContainerAtIndex(pos2)), false)
 				pos1++
 				pos2++
 				if (pos1 == length1) || (pos2 == length2) {
@@ -787,16 +1205,23 @@ main:
 		}
 	}
 	if pos1 == length1 {
-		answer.highlowcontainer.appendCopyMany(x2.highlowcontainer, pos2, length2)
+		r.appendCopyMany(&answer.highlowcontainer, x2.highlowcontainer, pos2, length2)
 	} else if pos2 == length2 {
-		answer.highlowcontainer.appendCopyMany(x1.highlowcontainer, pos1, length1)
+		r.appendCopyMany(&answer.highlowcontainer, x1.highlowcontainer, pos1, length1)
 	}
-	return answer
 }
 
 // And computes the intersection between two bitmaps and returns the result
 func And(x1, x2 *Bitmap) *Bitmap {
 	answer := NewBitmap()
+	and(answer, x1, x2, recycler{})
+	return answer
+}
+
+// and computes the intersection between two bitmaps into answer, which
+// must be empty, in containers taken from r
+func and(answer, x1, x2 *Bitmap, r recycler) {
+	answer.highlowcontainer.setCardinality(0)
 	pos1 := 0
 	pos2 := 0
 	length1 := x1.highlowcontainer.size()
@@ -807,11 +1232,11 @@ main:
 		s2 := x2.highlowcontainer.getKeyAtIndex(pos2)
 		for {
 			if s1 == s2 {
-				C := x1.highlowcontainer.getContainerAtIndex(pos1)
-				C = C.and(x2.highlowcontainer.getContainerAtIndex(pos2))
-
+				C := r.and(x1.highlowcontainer.getContainerAtIndex(pos1), x2.highlowcontainer.getContainerAtIndex(pos2))
 				if C.getCardinality() > 0 {
 					answer.highlowcontainer.appendContainer(s1, C, false)
+				} else {
+					r.discard(C)
 				}
 				pos1++
 				pos2++
@@ -835,12 +1260,19 @@ main:
 			}
 		}
 	}
-	return answer
 }
 
 // Xor computes the symmetric difference between two bitmaps and returns the result
 func Xor(x1, x2 *Bitmap) *Bitmap {
 	answer := NewBitmap()
+	xor(answer, x1, x2, recycler{})
+	return answer
+}
+
+// xor computes the symmetric difference between two bitmaps into
+// answer, which must be empty, in containers taken from r
+func xor(answer, x1, x2 *Bitmap, r recycler) {
+	answer.highlowcontainer.setCardinality(0)
 	pos1 := 0
 	pos2 := 0
 	length1 := x1.highlowcontainer.size()
@@ -850,15 +1282,17 @@ func Xor(x1, x2 *Bitmap) *Bitmap {
 			s1 := x1.highlowcontainer.getKeyAtIndex(pos1)
 			s2 := x2.highlowcontainer.getKeyAtIndex(pos2)
 			if s1 < s2 {
-				answer.highlowcontainer.appendCopy(x1.highlowcontainer, pos1)
+				r.appendCopy(&answer.highlowcontainer, x1.highlowcontainer, pos1)
 				pos1++
 			} else if s1 > s2 {
-				answer.highlowcontainer.appendCopy(x2.highlowcontainer, pos2)
+				r.appendCopy(&answer.highlowcontainer, x2.highlowcontainer, pos2)
 				pos2++
 			} else {
-				c := x1.highlowcontainer.getContainerAtIndex(pos1).xor(x2.highlowcontainer.getContainerAtIndex(pos2))
+				c := r.xor(x1.highlowcontainer.getContainerAtIndex(pos1), x2.highlowcontainer.getContainerAtIndex(pos2))
 				if c.getCardinality() > 0 {
 					answer.highlowcontainer.appendContainer(s1, c, false)
+				} else {
+					r.discard(c)
 				}
 				pos1++
 				pos2++
@@ -868,16 +1302,23 @@ func Xor(x1, x2 *Bitmap) *Bitmap {
 		}
 	}
 	if pos1 == length1 {
-		answer.highlowcontainer.appendCopyMany(x2.highlowcontainer, pos2, length2)
+		r.appendCopyMany(&answer.highlowcontainer, x2.highlowcontainer, pos2, length2)
 	} else if pos2 == length2 {
-		answer.highlowcontainer.appendCopyMany(x1.highlowcontainer, pos1, length1)
+		r.appendCopyMany(&answer.highlowcontainer, x1.highlowcontainer, pos1, length1)
 	}
-	return answer
 }
 
 // AndNot computes the difference between two bitmaps and returns the result
 func AndNot(x1, x2 *Bitmap) *Bitmap {
 	answer := NewBitmap()
+	andNot(answer, x1, x2, recycler{})
+	return answer
+}
+
+// andNot computes the difference between two bitmaps into answer, which
+// must be empty, in containers taken from r
+func andNot(answer, x1, x2 *Bitmap, r recycler) {
+	answer.highlowcontainer.setCardinality(0)
 	pos1 := 0
 	pos2 := 0
 	length1 := x1.highlowcontainer.size()
@@ -890,7 +1331,7 @@ main:
 			s2 := x2.highlowcontainer.getKeyAtIndex(pos2)
 			for {
 				if s1 < s2 {
-					answer.highlowcontainer.appendCopy(x1.highlowcontainer, pos1)
+					r.appendCopy(&answer.highlowcontainer, x1.highlowcontainer, pos1)
 					pos1++
 					if pos1 == length1 {
 						break main
@@ -899,9 +1340,11 @@ main:
 				} else if s1 == s2 {
 					c1 := x1.highlowcontainer.getContainerAtIndex(pos1)
 					c2 := x2.highlowcontainer.getContainerAtIndex(pos2)
-					diff := c1.andNot(c2)
+					diff := r.andNot(c1, c2)
 					if diff.getCardinality() > 0 {
 						answer.highlowcontainer.appendContainer(s1, diff, false)
+					} else {
+						r.discard(diff)
 					}
 					pos1++
 					pos2++
@@ -923,9 +1366,8 @@ main:
 		}
 	}
 	if pos2 == length2 {
-		answer.highlowcontainer.appendCopyMany(x1.highlowcontainer, pos1, length1)
+		r.appendCopyMany(&answer.highlowcontainer, x1.highlowcontainer, pos1, length1)
 	}
-	return answer
 }
 
 // AddMany add all of the values in dat
@@ -933,17 +1375,20 @@ func (rb *Bitmap) AddMany(dat []uint32) {
 	if len(dat) == 0 {
 		return
 	}
+	card := rb.highlowcontainer.cardinalityBeforeUpdate()
 	prev := dat[0]
-	idx, c := rb.addwithptr(prev)
+	idx, c, before := rb.addwithptr(prev)
 	for _, i := range dat[1:] {
 		if highbits(prev) == highbits(i) {
 			c = c.iaddReturnMinimized(lowbits(i))
 			rb.highlowcontainer.setContainerAtIndex(idx, c)
 		} else {
-			idx, c = rb.addwithptr(i)
+			card += uint64(c.getCardinality() - before)
+			idx, c, before = rb.addwithptr(i)
 		}
 		prev = i
 	}
+	rb.highlowcontainer.setCardinality(card + uint64(c.getCardinality()-before))
 }
 
 // BitmapOf generates a new bitmap filled with the specified integers
@@ -975,8 +1420,16 @@ func (rb *Bitmap) Flip(rangeStart, rangeEnd uint64) {
 	hbLast := highbits(uint32(rangeEnd - 1))
 	lbLast := lowbits(uint32(rangeEnd - 1))
 
+	// only the containers of the keys in [hbStart, hbLast] change
+	card := rb.highlowcontainer.cardinalityBeforeUpdate() - rb.highlowcontainer.rangeCardinality(hbStart, hbLast)
+	defer func() {
+		rb.highlowcontainer.setCardinality(card + rb.highlowcontainer.rangeCardinality(hbStart, hbLast))
+	}()
+
 	var max uint32 = maxLowBit
-	for hb := hbStart; hb <= hbLast; hb++ {
+	// hb must not be a uint16, or the loop never ends when hbLast == MaxUint16
+	for hb32 := uint32(hbStart); hb32 <= uint32(hbLast); hb32++ {
+		hb := uint16(hb32)
 		var containerStart uint32
 		if hb == hbStart {
 			containerStart = uint32(lbStart)
@@ -1020,14 +1473,22 @@ func (rb *Bitmap) AddRange(rangeStart, rangeEnd uint64) {
 	hbLast := uint32(highbits(uint32(rangeEnd - 1)))
 	lbLast := uint32(lowbits(uint32(rangeEnd - 1)))
 
+	// only the containers of the keys in [hbStart, hbLast] change
+	card := rb.highlowcontainer.cardinalityBeforeUpdate() - rb.highlowcontainer.rangeCardinality(uint16(hbStart), uint16(hbLast))
+	defer func() {
+		rb.highlowcontainer.setCardinality(card + rb.highlowcontainer.rangeCardinality(uint16(hbStart), uint16(hbLast)))
+	}()
+
 	var max uint32 = maxLowBit
-	for hb := uint16(hbStart); hb <= uint16(hbLast); hb++ {
+	// hb must not be a uint16, or the loop never ends when hbLast == MaxUint16
+	for hb32 := hbStart; hb32 <= hbLast; hb32++ {
+		hb := uint16(hb32)
 		containerStart := uint32(0)
-		if hb == uint16(hbStart) {
+		if hb32 == hbStart {
 			containerStart = lbStart
 		}
 		containerLast := max
-		if hb == uint16(hbLast) {
+		if hb32 == hbLast {
 			containerLast = lbLast
 		}
 
@@ -1056,6 +1517,12 @@ func (rb *Bitmap) RemoveRange(rangeStart, rangeEnd uint64) {
 	hbLast := uint32(highbits(uint32(rangeEnd - 1)))
 	lbLast := uint32(lowbits(uint32(rangeEnd - 1)))
 
+	// only the containers of the keys in [hbStart, hbLast] change
+	card := rb.highlowcontainer.cardinalityBeforeUpdate() - rb.highlowcontainer.rangeCardinality(uint16(hbStart), uint16(hbLast))
+	defer func() {
+		rb.highlowcontainer.setCardinality(card + rb.highlowcontainer.rangeCardinality(uint16(hbStart), uint16(hbLast)))
+	}()
+
 	var max uint32 = maxLowBit
 
 	if hbStart == hbLast {
@@ -1120,6 +1587,7 @@ func Flip(bm *Bitmap, rangeStart, rangeEnd uint64) *Bitmap {
 	}
 
 	answer := NewBitmap()
+	answer.highlowcontainer.setCardinality(0)
 	hbStart := highbits(uint32(rangeStart))
 	lbStart := lowbits(uint32(rangeStart))
 	hbLast := highbits(uint32(rangeEnd - 1))
@@ -1129,7 +1597,9 @@ func Flip(bm *Bitmap, rangeStart, rangeEnd uint64) *Bitmap {
 	answer.highlowcontainer.appendCopiesUntil(bm.highlowcontainer, hbStart)
 
 	var max uint32 = maxLowBit
-	for hb := hbStart; hb <= hbLast; hb++ {
+	// hb must not be a uint16, or the loop never ends when hbLast == MaxUint16
+	for hb32 := uint32(hbStart); hb32 <= uint32(hbLast); hb32++ {
+		hb := uint16(hb32)
 		var containerStart uint32
 		if hb == hbStart {
 			containerStart = uint32(lbStart)
@@ -1140,18 +1610,18 @@ func Flip(bm *Bitmap, rangeStart, rangeEnd uint64) *Bitmap {
 		}
 
 		i := bm.highlowcontainer.getIndex(hb)
-		j := answer.highlowcontainer.getIndex(hb)
 
+		// the keys of answer are all below hb, the containers are appended
 		if i >= 0 {
 			c := bm.highlowcontainer.getContainerAtIndex(i).not(int(containerStart), int(containerLast)+1)
 			if c.getCardinality() > 0 {
-				answer.highlowcontainer.insertNewKeyValueAt(-j-1, hb, c)
+				answer.highlowcontainer.appendContainer(hb, c, false)
 			}
 
 		} else { // *think* the range of ones must never be
 			// empty.
-			answer.highlowcontainer.insertNewKeyValueAt(-j-1, hb,
-				rangeOfOnes(int(containerStart), int(containerLast)))
+			answer.highlowcontainer.appendContainer(hb,
+				rangeOfOnes(int(containerStart), int(containerLast)), false)
 		}
 	}
 	// copy the containers after the active area.
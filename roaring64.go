@@ -0,0 +1,627 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Bitmap64 represents a compressed bitmap where you can add 64-bit integers.
+// The high 32 bits of an integer select a 32-bit Bitmap, which stores
+// the low 32 bits.
+type Bitmap64 struct {
+	// keys are the high 32 bits, in increasing order;
+	// bitmaps[i] holds the low 32 bits of the integers whose
+	// high bits are keys[i], and is never empty.
+	keys    []uint32
+	bitmaps []*Bitmap
+}
+
+func highbits64(x uint64) uint32 {
+	return uint32(x >> 32)
+}
+
+func lowbits64(x uint64) uint32 {
+	return uint32(x)
+}
+
+// NewBitmap64 creates a new empty Bitmap64
+func NewBitmap64() *Bitmap64 {
+	return &Bitmap64{}
+}
+
+// Bitmap64Of generates a new 64-bit bitmap filled with the specified integers
+func Bitmap64Of(dat ...uint64) *Bitmap64 {
+	ans := NewBitmap64()
+	ans.AddMany(dat)
+	return ans
+}
+
+// getIndex returns the index of key hb, or -(insertion point)-1 if it is absent
+func (rb *Bitmap64) getIndex(hb uint32) int {
+	low, high := 0, len(rb.keys)
+	for low < high {
+		middle := int(uint(low+high) >> 1)
+		if rb.keys[middle] < hb {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+	if low < len(rb.keys) && rb.keys[low] == hb {
+		return low
+	}
+	return -(low + 1)
+}
+
+// getOrCreateBitmap returns the bitmap for key hb, inserting an empty one if needed
+func (rb *Bitmap64) getOrCreateBitmap(hb uint32) *Bitmap {
+	i := rb.getIndex(hb)
+	if i >= 0 {
+		return rb.bitmaps[i]
+	}
+	i = -i - 1
+	bm := NewBitmap()
+	rb.keys = append(rb.keys, 0)
+	copy(rb.keys[i+1:], rb.keys[i:])
+	rb.keys[i] = hb
+	rb.bitmaps = append(rb.bitmaps, nil)
+	copy(rb.bitmaps[i+1:], rb.bitmaps[i:])
+	rb.bitmaps[i] = bm
+	return bm
+}
+
+func (rb *Bitmap64) removeAtIndex(i int) {
+	copy(rb.keys[i:], rb.keys[i+1:])
+	rb.keys = rb.keys[:len(rb.keys)-1]
+	copy(rb.bitmaps[i:], rb.bitmaps[i+1:])
+	rb.bitmaps[len(rb.bitmaps)-1] = nil
+	rb.bitmaps = rb.bitmaps[:len(rb.bitmaps)-1]
+}
+
+// removeEmpty drops the bitmaps that became empty
+func (rb *Bitmap64) removeEmpty() {
+	n := 0
+	for i, bm := range rb.bitmaps {
+		if !bm.IsEmpty() {
+			rb.keys[n] = rb.keys[i]
+			rb.bitmaps[n] = bm
+			n++
+		}
+	}
+	for i := n; i < len(rb.bitmaps); i++ {
+		rb.bitmaps[i] = nil
+	}
+	rb.keys = rb.keys[:n]
+	rb.bitmaps = rb.bitmaps[:n]
+}
+
+// Clear removes all content from the Bitmap64 and frees the memory
+func (rb *Bitmap64) Clear() {
+	rb.keys = nil
+	rb.bitmaps = nil
+}
+
+// IsEmpty returns true if the Bitmap64 is empty (it is faster than doing (GetCardinality() == 0))
+func (rb *Bitmap64) IsEmpty() bool {
+	return len(rb.keys) == 0
+}
+
+// GetCardinality returns the number of integers contained in the bitmap
+func (rb *Bitmap64) GetCardinality() uint64 {
+	size := uint64(0)
+	for _, bm := range rb.bitmaps {
+		size += bm.GetCardinality()
+	}
+	return size
+}
+
+// Contains returns true if the integer is contained in the bitmap
+func (rb *Bitmap64) Contains(x uint64) bool {
+	i := rb.getIndex(highbits64(x))
+	return i >= 0 && rb.bitmaps[i].Contains(lowbits64(x))
+}
+
+// Add the integer x to the bitmap
+func (rb *Bitmap64) Add(x uint64) {
+	rb.getOrCreateBitmap(highbits64(x)).Add(lowbits64(x))
+}
+
+// CheckedAdd adds the integer x to the bitmap and return true if it was added (false if the integer was already present)
+func (rb *Bitmap64) CheckedAdd(x uint64) bool {
+	return rb.getOrCreateBitmap(highbits64(x)).CheckedAdd(lowbits64(x))
+}
+
+// AddMany add all of the values in dat
+func (rb *Bitmap64) AddMany(dat []uint64) {
+	if len(dat) == 0 {
+		return
+	}
+	hb := highbits64(dat[0])
+	bm := rb.getOrCreateBitmap(hb)
+	for _, x := range dat {
+		if highbits64(x) != hb {
+			hb = highbits64(x)
+			bm = rb.getOrCreateBitmap(hb)
+		}
+		bm.Add(lowbits64(x))
+	}
+}
+
+// Remove the integer x from the bitmap
+func (rb *Bitmap64) Remove(x uint64) {
+	rb.CheckedRemove(x)
+}
+
+// CheckedRemove removes the integer x from the bitmap and return true if the integer was effectively remove (and false if the integer was not present)
+func (rb *Bitmap64) CheckedRemove(x uint64) bool {
+	i := rb.getIndex(highbits64(x))
+	if i < 0 {
+		return false
+	}
+	removed := rb.bitmaps[i].CheckedRemove(lowbits64(x))
+	if rb.bitmaps[i].IsEmpty() {
+		rb.removeAtIndex(i)
+	}
+	return removed
+}
+
+// AddRange adds the integers in [rangeStart, rangeEnd) to the bitmap.
+func (rb *Bitmap64) AddRange(rangeStart, rangeEnd uint64) {
+	if rangeStart >= rangeEnd {
+		return
+	}
+	hbStart, hbLast := highbits64(rangeStart), highbits64(rangeEnd-1)
+	// hb must not be a uint32, or the loop never ends when hbLast == MaxUint32
+	for hb := uint64(hbStart); hb <= uint64(hbLast); hb++ {
+		start := uint64(0)
+		if hb == uint64(hbStart) {
+			start = uint64(lowbits64(rangeStart))
+		}
+		end := uint64(MaxUint32) + 1
+		if hb == uint64(hbLast) {
+			end = uint64(lowbits64(rangeEnd-1)) + 1
+		}
+		rb.getOrCreateBitmap(uint32(hb)).AddRange(start, end)
+	}
+}
+
+// RemoveRange removes the integers in [rangeStart, rangeEnd) from the bitmap.
+func (rb *Bitmap64) RemoveRange(rangeStart, rangeEnd uint64) {
+	if rangeStart >= rangeEnd {
+		return
+	}
+	hbStart, hbLast := highbits64(rangeStart), highbits64(rangeEnd-1)
+	for i, hb := range rb.keys {
+		if hb < hbStart {
+			continue
+		}
+		if hb > hbLast {
+			break
+		}
+		start := uint64(0)
+		if hb == hbStart {
+			start = uint64(lowbits64(rangeStart))
+		}
+		end := uint64(MaxUint32) + 1
+		if hb == hbLast {
+			end = uint64(lowbits64(rangeEnd-1)) + 1
+		}
+		rb.bitmaps[i].RemoveRange(start, end)
+	}
+	rb.removeEmpty()
+}
+
+// Rank returns the number of integers that are smaller or equal to x (Rank(infinity) would be GetCardinality())
+func (rb *Bitmap64) Rank(x uint64) uint64 {
+	size := uint64(0)
+	for i, hb := range rb.keys {
+		if hb > highbits64(x) {
+			return size
+		}
+		if hb < highbits64(x) {
+			size += rb.bitmaps[i].GetCardinality()
+		} else {
+			return size + rb.bitmaps[i].Rank(lowbits64(x))
+		}
+	}
+	return size
+}
+
+// Select returns the xth integer in the bitmap
+func (rb *Bitmap64) Select(x uint64) (uint64, error) {
+	remaining := x
+	for i, bm := range rb.bitmaps {
+		card := bm.GetCardinality()
+		if remaining >= card {
+			remaining -= card
+			continue
+		}
+		low, err := bm.Select(uint32(remaining))
+		if err != nil {
+			return 0, err
+		}
+		return uint64(rb.keys[i])<<32 | uint64(low), nil
+	}
+	return 0, fmt.Errorf("can't find %dth integer in a bitmap with only %d items", x, rb.GetCardinality())
+}
+
+// Minimum get the smallest value stored in this roaring bitmap, assumes that it is not empty
+func (rb *Bitmap64) Minimum() uint64 {
+	return uint64(rb.keys[0])<<32 | uint64(rb.bitmaps[0].Minimum())
+}
+
+// Maximum get the largest value stored in this roaring bitmap, assumes that it is not empty
+func (rb *Bitmap64) Maximum() uint64 {
+	last := len(rb.keys) - 1
+	return uint64(rb.keys[last])<<32 | uint64(rb.bitmaps[last].Maximum())
+}
+
+// ToArray creates a new slice containing all of the integers stored in the Bitmap64 in sorted order
+func (rb *Bitmap64) ToArray() []uint64 {
+	array := make([]uint64, 0, rb.GetCardinality())
+	for i, bm := range rb.bitmaps {
+		hs := uint64(rb.keys[i]) << 32
+		for _, x := range bm.ToArray() {
+			array = append(array, hs|uint64(x))
+		}
+	}
+	return array
+}
+
+// String creates a string representation of the Bitmap64
+func (rb *Bitmap64) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	i := rb.Iterator()
+	counter := 0
+	for i.HasNext() {
+		if counter > 0 {
+			buffer.WriteString(",")
+		}
+		counter = counter + 1
+		// to avoid exhausting the memory
+		if counter > 0x40000 {
+			buffer.WriteString("...")
+			break
+		}
+		buffer.WriteString(strconv.FormatUint(i.Next(), 10))
+	}
+	buffer.WriteString("}")
+	return buffer.String()
+}
+
+// Clone creates a copy of the Bitmap64
+func (rb *Bitmap64) Clone() *Bitmap64 {
+	ptr := &Bitmap64{
+		keys:    make([]uint32, len(rb.keys)),
+		bitmaps: make([]*Bitmap, len(rb.bitmaps)),
+	}
+	copy(ptr.keys, rb.keys)
+	for i, bm := range rb.bitmaps {
+		ptr.bitmaps[i] = bm.Clone()
+	}
+	return ptr
+}
+
+// Equals returns true if the two bitmaps contain the same integers
+func (rb *Bitmap64) Equals(o interface{}) bool {
+	srb, ok := o.(*Bitmap64)
+	if !ok || len(srb.keys) != len(rb.keys) {
+		return false
+	}
+	for i, hb := range rb.keys {
+		if srb.keys[i] != hb || !srb.bitmaps[i].Equals(rb.bitmaps[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RunOptimize attempts to further compress the runs of consecutive values found in the bitmap
+func (rb *Bitmap64) RunOptimize() {
+	for _, bm := range rb.bitmaps {
+		bm.RunOptimize()
+	}
+}
+
+// GetSizeInBytes estimates the memory usage of the Bitmap64
+func (rb *Bitmap64) GetSizeInBytes() uint64 {
+	size := uint64(8)
+	for _, bm := range rb.bitmaps {
+		size += 4 + bm.GetSizeInBytes()
+	}
+	return size
+}
+
+// IntIterable64 allows you to iterate over the values in a Bitmap64
+type IntIterable64 interface {
+	HasNext() bool
+	Next() uint64
+}
+
+type intIterator64 struct {
+	pos  int
+	hs   uint64
+	iter IntIterable
+	rb   *Bitmap64
+}
+
+// HasNext returns true if there are more integers to iterate over
+func (ii *intIterator64) HasNext() bool {
+	return ii.pos < len(ii.rb.keys)
+}
+
+func (ii *intIterator64) init() {
+	if len(ii.rb.keys) > ii.pos {
+		ii.iter = ii.rb.bitmaps[ii.pos].Iterator()
+		ii.hs = uint64(ii.rb.keys[ii.pos]) << 32
+	}
+}
+
+// Next returns the next integer
+func (ii *intIterator64) Next() uint64 {
+	x := uint64(ii.iter.Next()) | ii.hs
+	if !ii.iter.HasNext() {
+		ii.pos = ii.pos + 1
+		ii.init()
+	}
+	return x
+}
+
+// Iterator creates a new IntIterable64 to iterate over the integers contained in the bitmap, in sorted order
+func (rb *Bitmap64) Iterator() IntIterable64 {
+	p := &intIterator64{rb: rb}
+	p.init()
+	return p
+}
+
+// And computes the intersection between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) And(x2 *Bitmap64) {
+	n := 0
+	for i, hb := range rb.keys {
+		j := x2.getIndex(hb)
+		if j < 0 {
+			continue
+		}
+		rb.bitmaps[i].And(x2.bitmaps[j])
+		rb.keys[n] = hb
+		rb.bitmaps[n] = rb.bitmaps[i]
+		n++
+	}
+	for i := n; i < len(rb.bitmaps); i++ {
+		rb.bitmaps[i] = nil
+	}
+	rb.keys = rb.keys[:n]
+	rb.bitmaps = rb.bitmaps[:n]
+	rb.removeEmpty()
+}
+
+// Or computes the union between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) Or(x2 *Bitmap64) {
+	for j, hb := range x2.keys {
+		rb.getOrCreateBitmap(hb).Or(x2.bitmaps[j])
+	}
+}
+
+// Xor computes the symmetric difference between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) Xor(x2 *Bitmap64) {
+	for j, hb := range x2.keys {
+		rb.getOrCreateBitmap(hb).Xor(x2.bitmaps[j])
+	}
+	rb.removeEmpty()
+}
+
+// AndNot computes the difference between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) AndNot(x2 *Bitmap64) {
+	for i, hb := range rb.keys {
+		if j := x2.getIndex(hb); j >= 0 {
+			rb.bitmaps[i].AndNot(x2.bitmaps[j])
+		}
+	}
+	rb.removeEmpty()
+}
+
+// And64 computes the intersection between two 64-bit bitmaps and returns the result
+func And64(x1, x2 *Bitmap64) *Bitmap64 {
+	answer := NewBitmap64()
+	for i, hb := range x1.keys {
+		if j := x2.getIndex(hb); j >= 0 {
+			bm := And(x1.bitmaps[i], x2.bitmaps[j])
+			if !bm.IsEmpty() {
+				answer.keys = append(answer.keys, hb)
+				answer.bitmaps = append(answer.bitmaps, bm)
+			}
+		}
+	}
+	return answer
+}
+
+// Or64 computes the union between two 64-bit bitmaps and returns the result
+func Or64(x1, x2 *Bitmap64) *Bitmap64 {
+	answer := x1.Clone()
+	answer.Or(x2)
+	return answer
+}
+
+// Xor64 computes the symmetric difference between two 64-bit bitmaps and returns the result
+func Xor64(x1, x2 *Bitmap64) *Bitmap64 {
+	answer := x1.Clone()
+	answer.Xor(x2)
+	return answer
+}
+
+// AndNot64 computes the difference between two 64-bit bitmaps and returns the result
+func AndNot64(x1, x2 *Bitmap64) *Bitmap64 {
+	answer := NewBitmap64()
+	for i, hb := range x1.keys {
+		bm := x1.bitmaps[i]
+		if j := x2.getIndex(hb); j >= 0 {
+			bm = AndNot(bm, x2.bitmaps[j])
+		} else {
+			bm = bm.Clone()
+		}
+		if !bm.IsEmpty() {
+			answer.keys = append(answer.keys, hb)
+			answer.bitmaps = append(answer.bitmaps, bm)
+		}
+	}
+	return answer
+}
+
+// FastAnd64 computes the intersection between many 64-bit bitmaps quickly
+func FastAnd64(bitmaps ...*Bitmap64) *Bitmap64 {
+	if len(bitmaps) == 0 {
+		return NewBitmap64()
+	} else if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
+	}
+	answer := NewBitmap64()
+	inners := make([]*Bitmap, len(bitmaps))
+keys:
+	for i, hb := range bitmaps[0].keys {
+		inners[0] = bitmaps[0].bitmaps[i]
+		for k, x := range bitmaps[1:] {
+			j := x.getIndex(hb)
+			if j < 0 {
+				continue keys
+			}
+			inners[k+1] = x.bitmaps[j]
+		}
+		bm := FastAnd(inners...)
+		if !bm.IsEmpty() {
+			answer.keys = append(answer.keys, hb)
+			answer.bitmaps = append(answer.bitmaps, bm)
+		}
+	}
+	return answer
+}
+
+// FastOr64 computes the union between many 64-bit bitmaps quickly, as opposed to having to call Or repeatedly.
+func FastOr64(bitmaps ...*Bitmap64) *Bitmap64 {
+	if len(bitmaps) == 0 {
+		return NewBitmap64()
+	} else if len(bitmaps) == 1 {
+		return bitmaps[0].Clone()
+	}
+	// group the inner bitmaps by key, then compute each union with FastOr
+	byKey := make(map[uint32][]*Bitmap)
+	for _, x := range bitmaps {
+		for i, hb := range x.keys {
+			byKey[hb] = append(byKey[hb], x.bitmaps[i])
+		}
+	}
+	answer := &Bitmap64{keys: make([]uint32, 0, len(byKey))}
+	for hb := range byKey {
+		answer.keys = append(answer.keys, hb)
+	}
+	sort.Sort(uint32Slice(answer.keys))
+	answer.bitmaps = make([]*Bitmap, len(answer.keys))
+	for i, hb := range answer.keys {
+		answer.bitmaps[i] = FastOr(byKey[hb]...)
+	}
+	return answer
+}
+
+// GetSerializedSizeInBytes computes the serialized size in bytes
+// of the Bitmap64. It should correspond to the
+// number of bytes written when invoking WriteTo.
+func (rb *Bitmap64) GetSerializedSizeInBytes() uint64 {
+	size := uint64(8)
+	for _, bm := range rb.bitmaps {
+		size += 4 + bm.GetSerializedSizeInBytes()
+	}
+	return size
+}
+
+// WriteTo writes a serialized version of this bitmap to stream.
+// The format is the portable 64-bit format also used by the Java
+// (Roaring64NavigableMap) and C (roaring64) implementations:
+// the number of 32-bit bitmaps as a little-endian uint64, then for
+// each of them, in increasing order, its high 32 bits as a
+// little-endian uint32 followed by the bitmap in the portable
+// 32-bit format documented at https://github.com/RoaringBitmap/RoaringFormatSpec
+func (rb *Bitmap64) WriteTo(stream io.Writer) (int64, error) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(rb.keys)))
+	n, err := stream.Write(buf[:])
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	for i, hb := range rb.keys {
+		binary.LittleEndian.PutUint32(buf[:4], hb)
+		n, err := stream.Write(buf[:4])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+		nb, err := rb.bitmaps[i].WriteTo(stream)
+		written += nb
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ToBytes returns an array of bytes corresponding to what is written
+// when calling WriteTo
+func (rb *Bitmap64) ToBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := rb.WriteTo(&buf)
+	return buf.Bytes(), err
+}
+
+// ReadFrom reads a serialized version of this bitmap from stream,
+// in the portable 64-bit format written by WriteTo.
+// The bitmap is cleared first.
+func (rb *Bitmap64) ReadFrom(stream io.Reader) (int64, error) {
+	rb.Clear()
+	var buf [8]byte
+	n, err := io.ReadFull(stream, buf[:])
+	read := int64(n)
+	if err != nil {
+		return read, fmt.Errorf("error in Bitmap64.ReadFrom: could not read the number of bitmaps: %s", err)
+	}
+	size := binary.LittleEndian.Uint64(buf[:])
+	if size > MaxUint32+1 {
+		return read, fmt.Errorf("error in Bitmap64.ReadFrom: it is logically impossible to have more than (1<<32) bitmaps, got %d", size)
+	}
+	for i := uint64(0); i < size; i++ {
+		n, err := io.ReadFull(stream, buf[:4])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("error in Bitmap64.ReadFrom: could not read the key of bitmap %d: %s", i, err)
+		}
+		hb := binary.LittleEndian.Uint32(buf[:4])
+		if len(rb.keys) > 0 && rb.keys[len(rb.keys)-1] >= hb {
+			return read, fmt.Errorf("error in Bitmap64.ReadFrom: keys are not in sorted order (%d >= %d)", rb.keys[len(rb.keys)-1], hb)
+		}
+		bm := NewBitmap()
+		nb, err := bm.ReadFrom(stream)
+		read += nb
+		if err != nil {
+			return read, err
+		}
+		if !bm.IsEmpty() {
+			rb.keys = append(rb.keys, hb)
+			rb.bitmaps = append(rb.bitmaps, bm)
+		}
+	}
+	return read, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface for the bitmap
+func (rb *Bitmap64) MarshalBinary() ([]byte, error) {
+	return rb.ToBytes()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface for the bitmap
+func (rb *Bitmap64) UnmarshalBinary(data []byte) error {
+	_, err := rb.ReadFrom(bytes.NewReader(data))
+	return err
+}
@@ -0,0 +1,273 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// randomBitmap64 returns a Bitmap64 and the sorted values it holds,
+// spread over a few high keys so that containers of all kinds show up
+func randomBitmap64(r *rand.Rand, n int) (*Bitmap64, []uint64) {
+	highs := []uint64{0, 1, 7, MaxUint32}
+	set := make(map[uint64]bool)
+	for i := 0; i < n; i++ {
+		hb := highs[r.Intn(len(highs))]
+		set[hb<<32|uint64(r.Intn(1<<18))] = true
+	}
+	vals := make([]uint64, 0, len(set))
+	for x := range set {
+		vals = append(vals, x)
+	}
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	return Bitmap64Of(vals...), vals
+}
+
+func TestBitmap64Basic(t *testing.T) {
+
+	Convey("Bitmap64 should store integers across the whole 64-bit range", t, func() {
+		rb := NewBitmap64()
+		So(rb.IsEmpty(), ShouldBeTrue)
+		vals := []uint64{0, 1, MaxUint32, MaxUint32 + 1, 1 << 40, 1<<63 + 5, ^uint64(0)}
+		for _, x := range vals {
+			So(rb.CheckedAdd(x), ShouldBeTrue)
+			So(rb.CheckedAdd(x), ShouldBeFalse)
+		}
+		So(rb.GetCardinality(), ShouldEqual, len(vals))
+		So(rb.ToArray(), ShouldResemble, vals)
+		So(rb.Minimum(), ShouldEqual, 0)
+		So(rb.Maximum(), ShouldEqual, ^uint64(0))
+		So(rb.String(), ShouldEqual, "{0,1,4294967295,4294967296,1099511627776,9223372036854775813,18446744073709551615}")
+
+		for i, x := range vals {
+			So(rb.Contains(x), ShouldBeTrue)
+			So(rb.Contains(x+3), ShouldBeFalse)
+			So(rb.Rank(x), ShouldEqual, i+1)
+			s, err := rb.Select(uint64(i))
+			So(err, ShouldBeNil)
+			So(s, ShouldEqual, x)
+		}
+		_, err := rb.Select(uint64(len(vals)))
+		So(err, ShouldNotBeNil)
+
+		got := []uint64{}
+		for it := rb.Iterator(); it.HasNext(); {
+			got = append(got, it.Next())
+		}
+		So(got, ShouldResemble, vals)
+
+		c := rb.Clone()
+		So(c.Equals(rb), ShouldBeTrue)
+		So(c.CheckedRemove(1<<40), ShouldBeTrue)
+		So(c.CheckedRemove(1<<40), ShouldBeFalse)
+		So(c.Equals(rb), ShouldBeFalse)
+		So(rb.Contains(1<<40), ShouldBeTrue)
+
+		// removing the only value of a key drops the key
+		So(len(c.keys), ShouldEqual, len(rb.keys)-1)
+		rb.Clear()
+		So(rb.IsEmpty(), ShouldBeTrue)
+	})
+
+	Convey("Bitmap64 ranges should cross the 32-bit boundaries", t, func() {
+		rb := NewBitmap64()
+		rb.AddRange(MaxUint32-9, MaxUint32+11)
+		So(rb.GetCardinality(), ShouldEqual, 20)
+		So(rb.Contains(MaxUint32), ShouldBeTrue)
+		So(rb.Contains(MaxUint32+10), ShouldBeTrue)
+		So(rb.Contains(MaxUint32+11), ShouldBeFalse)
+
+		// the last key of both the 64-bit and the inner 32-bit bitmaps
+		top := NewBitmap64()
+		top.AddRange(^uint64(0)-(1<<33), ^uint64(0))
+		So(top.GetCardinality(), ShouldEqual, uint64(1<<33))
+		So(top.Maximum(), ShouldEqual, ^uint64(0)-1)
+
+		rb.AddRange(3<<32, 5<<32)
+		So(rb.GetCardinality(), ShouldEqual, uint64(20+2<<32))
+		rb.RemoveRange(MaxUint32-4, 4<<32+1)
+		So(rb.GetCardinality(), ShouldEqual, uint64(5+MaxUint32))
+		So(rb.Contains(4<<32), ShouldBeFalse)
+		So(rb.Contains(4<<32+1), ShouldBeTrue)
+		So(len(rb.keys), ShouldEqual, 2)
+		rb.RemoveRange(0, ^uint64(0))
+		So(rb.IsEmpty(), ShouldBeTrue)
+	})
+}
+
+func TestBitmap64Operations(t *testing.T) {
+
+	Convey("Bitmap64 set operations should match a brute force computation", t, func() {
+		r := rand.New(rand.NewSource(1234))
+		for trial := 0; trial < 5; trial++ {
+			x1, v1 := randomBitmap64(r, 20000)
+			x2, v2 := randomBitmap64(r, 20000)
+			x3, v3 := randomBitmap64(r, 20000)
+			in := func(vals []uint64) map[uint64]bool {
+				m := make(map[uint64]bool)
+				for _, x := range vals {
+					m[x] = true
+				}
+				return m
+			}
+			m1, m2, m3 := in(v1), in(v2), in(v3)
+			expect := func(keep func(x uint64) bool) []uint64 {
+				ans := []uint64{}
+				for _, vals := range [][]uint64{v1, v2, v3} {
+					for _, x := range vals {
+						if keep(x) {
+							ans = append(ans, x)
+						}
+					}
+				}
+				sort.Slice(ans, func(i, j int) bool { return ans[i] < ans[j] })
+				return Bitmap64Of(ans...).ToArray()
+			}
+
+			So(And64(x1, x2).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] && m2[x] }))
+			So(Or64(x1, x2).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] || m2[x] }))
+			So(Xor64(x1, x2).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] != m2[x] }))
+			So(AndNot64(x1, x2).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] && !m2[x] }))
+			So(FastAnd64(x1, x2, x3).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] && m2[x] && m3[x] }))
+			So(FastOr64(x1, x2, x3).ToArray(), ShouldResemble, expect(func(x uint64) bool { return m1[x] || m2[x] || m3[x] }))
+
+			// the in-place versions agree with the others, and leave x2 alone
+			c2 := x2.Clone()
+			for _, op := range []struct {
+				inplace func(a, b *Bitmap64)
+				fresh   func(a, b *Bitmap64) *Bitmap64
+			}{
+				{(*Bitmap64).And, And64},
+				{(*Bitmap64).Or, Or64},
+				{(*Bitmap64).Xor, Xor64},
+				{(*Bitmap64).AndNot, AndNot64},
+			} {
+				a := x1.Clone()
+				op.inplace(a, x2)
+				So(a.Equals(op.fresh(x1, x2)), ShouldBeTrue)
+				for _, bm := range a.bitmaps {
+					So(bm.IsEmpty(), ShouldBeFalse)
+				}
+				a.AddRange(0, 1<<33)
+				So(x2.Equals(c2), ShouldBeTrue)
+			}
+		}
+		So(FastOr64().IsEmpty(), ShouldBeTrue)
+		So(FastAnd64().IsEmpty(), ShouldBeTrue)
+	})
+}
+
+func TestBitmap64Serialization(t *testing.T) {
+
+	Convey("Bitmap64 should use the portable 64-bit format", t, func() {
+		rb := Bitmap64Of(1, 2, 1<<32|3, MaxUint32<<32|4)
+		rb.AddRange(5<<32, 5<<32+100000)
+		rb.RunOptimize()
+
+		var expected bytes.Buffer
+		binary.Write(&expected, binary.LittleEndian, uint64(4))
+		for _, hb := range []uint32{0, 1, 5, MaxUint32} {
+			binary.Write(&expected, binary.LittleEndian, hb)
+			inner := NewBitmap()
+			for _, x := range rb.ToArray() {
+				if highbits64(x) == hb {
+					inner.Add(lowbits64(x))
+				}
+			}
+			inner.RunOptimize()
+			inner.WriteTo(&expected)
+		}
+
+		buf, err := rb.ToBytes()
+		So(err, ShouldBeNil)
+		So(buf, ShouldResemble, expected.Bytes())
+		So(rb.GetSerializedSizeInBytes(), ShouldEqual, len(buf))
+
+		newrb := NewBitmap64()
+		newrb.Add(42) // ReadFrom starts from an empty bitmap
+		n, err := newrb.ReadFrom(bytes.NewReader(buf))
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, len(buf))
+		So(newrb.Equals(rb), ShouldBeTrue)
+
+		data, err := rb.MarshalBinary()
+		So(err, ShouldBeNil)
+		newrb = NewBitmap64()
+		So(newrb.UnmarshalBinary(data), ShouldBeNil)
+		So(newrb.Equals(rb), ShouldBeTrue)
+
+		empty, err := NewBitmap64().ToBytes()
+		So(err, ShouldBeNil)
+		So(empty, ShouldResemble, make([]byte, 8))
+		So(newrb.UnmarshalBinary(empty), ShouldBeNil)
+		So(newrb.IsEmpty(), ShouldBeTrue)
+	})
+
+	Convey("Bitmap64 should read and rewrite the portable files of other implementations", t, func() {
+		// written by CRoaring's portable serializer, one 32-bit bitmap
+		// per key behind the 64-bit header
+		high := NewBitmap()
+		for j := uint32(0); j < 1<<16; j += 3 {
+			high.Add(j)
+		}
+		high.AddRange(1<<32-100000, 1<<32)
+		spread := NewBitmap64()
+		for hb := uint64(0); hb < 10; hb++ {
+			for j := uint64(0); j < 10; j++ {
+				spread.Add(hb<<32 | j<<16 | hb)
+			}
+		}
+		files := map[string]*Bitmap64{
+			"testdata/64mapemptyinput.bin": NewBitmap64(),
+			"testdata/64map32bitvals.bin":  NewBitmap64(),
+			"testdata/64mapspreadvals.bin": spread,
+			"testdata/64maphighvals.bin":   NewBitmap64(),
+		}
+		files["testdata/64map32bitvals.bin"].AddMany([]uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+		files["testdata/64map32bitvals.bin"].AddRange(100000, 200000)
+		for hb := uint64(MaxUint32 - 2); hb <= MaxUint32; hb++ {
+			high.Iterate(func(x uint32) bool {
+				files["testdata/64maphighvals.bin"].Add(hb<<32 | uint64(x))
+				return true
+			})
+		}
+
+		for fn, expected := range files {
+			by, err := ioutil.ReadFile(fn)
+			So(err, ShouldBeNil)
+
+			rb := NewBitmap64()
+			n, err := rb.ReadFrom(bytes.NewReader(by))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, len(by))
+			So(rb.Equals(expected), ShouldBeTrue)
+
+			out, err := rb.ToBytes()
+			So(err, ShouldBeNil)
+			So(out, ShouldResemble, by)
+			So(rb.GetSerializedSizeInBytes(), ShouldEqual, len(by))
+		}
+	})
+
+	Convey("Bitmap64 should reject malformed input", t, func() {
+		buf, err := Bitmap64Of(1, 1<<32|2).ToBytes()
+		So(err, ShouldBeNil)
+		for i := 0; i < len(buf); i++ {
+			So(NewBitmap64().UnmarshalBinary(buf[:i]), ShouldNotBeNil)
+		}
+
+		// keys out of order
+		var unsorted bytes.Buffer
+		binary.Write(&unsorted, binary.LittleEndian, uint64(2))
+		for _, hb := range []uint32{1, 0} {
+			binary.Write(&unsorted, binary.LittleEndian, hb)
+			BitmapOf(1).WriteTo(&unsorted)
+		}
+		So(NewBitmap64().UnmarshalBinary(unsorted.Bytes()), ShouldNotBeNil)
+	})
+}
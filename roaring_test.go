@@ -1824,7 +1824,7 @@ func TestStats(t *testing.T) {
 			BitmapContainerBytes:  0,
 
 			RunContainers:      1,
-			RunContainerBytes:  52,
+			RunContainerBytes:  44,
 			RunContainerValues: 60000,
 		}
 		rr := NewBitmap()
@@ -1857,3 +1857,292 @@ func TestFlipVerySmall(t *testing.T) {
 		So(rbcard, ShouldEqual, 9)
 	})
 }
+
+func TestRangesUpToMaxUint32(t *testing.T) {
+	Convey("AddRange and Flip should handle ranges ending at MaxUint32", t, func() {
+		rb := NewBitmap()
+		rb.AddRange(MaxUint32-70000, MaxUint32+1)
+		So(rb.GetCardinality(), ShouldEqual, 70001)
+		So(rb.Contains(MaxUint32), ShouldBeTrue)
+
+		rb.Flip(MaxUint32-70000, MaxUint32+1)
+		So(rb.IsEmpty(), ShouldBeTrue)
+
+		fb := Flip(rb, MaxUint32-5, MaxUint32+1)
+		So(fb.GetCardinality(), ShouldEqual, 6)
+		So(fb.Maximum(), ShouldEqual, uint32(MaxUint32))
+	})
+}
+
+// iteratorTestBitmap holds array, bitmap and run containers,
+// with runs of every length around the word boundaries
+func iteratorTestBitmap() *Bitmap {
+	rb := BitmapOf(0, 3, 64, 65, 1<<16-1)
+	for i := uint32(1 << 16); i < 2<<16; i += 5 {
+		rb.Add(i)
+	}
+	for i := uint32(3 << 16); i < 4<<16; i += 100 {
+		rb.AddRange(uint64(i), uint64(i+uint32(i%63)+1))
+	}
+	rb.AddRange(MaxUint32-100, MaxUint32+1)
+	rb.RunOptimize()
+	return rb
+}
+
+func TestPeekableIterator(t *testing.T) {
+	rb := iteratorTestBitmap()
+	values := rb.ToArray()
+
+	Convey("PeekNext should return what Next returns", t, func() {
+		it := rb.PeekableIterator()
+		for _, v := range values {
+			So(it.HasNext(), ShouldBeTrue)
+			So(it.PeekNext(), ShouldEqual, v)
+			So(it.Next(), ShouldEqual, v)
+		}
+		So(it.HasNext(), ShouldBeFalse)
+	})
+
+	Convey("AdvanceIfNeeded should skip to the first value not smaller than its argument", t, func() {
+		r := rand.New(rand.NewSource(11))
+		for _, step := range []uint32{1, 7, 150, 5000, 100000} {
+			it := rb.PeekableIterator()
+			i := 0
+			for min := uint32(0); ; min += step + uint32(r.Intn(int(step))) {
+				if r.Intn(3) == 0 && it.HasNext() {
+					// interleave some plain iteration
+					So(it.Next(), ShouldEqual, values[i])
+					i++
+				}
+				it.AdvanceIfNeeded(min)
+				for i < len(values) && values[i] < min {
+					i++
+				}
+				if i == len(values) {
+					So(it.HasNext(), ShouldBeFalse)
+					break
+				}
+				So(it.HasNext(), ShouldBeTrue)
+				So(it.PeekNext(), ShouldEqual, values[i])
+				if min > MaxUint32-step*2 {
+					break
+				}
+			}
+		}
+
+		it := rb.PeekableIterator()
+		it.AdvanceIfNeeded(MaxUint32)
+		So(it.Next(), ShouldEqual, uint32(MaxUint32))
+		So(it.HasNext(), ShouldBeFalse)
+		it.AdvanceIfNeeded(0)
+		So(it.HasNext(), ShouldBeFalse)
+		So(NewBitmap().PeekableIterator().HasNext(), ShouldBeFalse)
+	})
+
+	Convey("IteratorRange should iterate over the values in the range", t, func() {
+		for _, r := range [][2]uint64{{0, 0}, {5, 2}, {0, 1}, {1, 65}, {64, 1 << 16}, {65, 3<<16 + 500},
+			{1<<16 + 3, 3<<16 + 101}, {3<<16 + 150, 1 << 32}, {MaxUint32, 1 << 40}, {1 << 33, 1 << 34}} {
+			var expect []uint32
+			for _, v := range values {
+				if uint64(v) >= r[0] && uint64(v) < r[1] {
+					expect = append(expect, v)
+				}
+			}
+			var got []uint32
+			for it := rb.IteratorRange(r[0], r[1]); it.HasNext(); {
+				got = append(got, it.Next())
+			}
+			So(got, ShouldResemble, expect)
+		}
+	})
+}
+
+func TestReverseIterator(t *testing.T) {
+	rb := iteratorTestBitmap()
+	values := rb.ToArray()
+
+	Convey("ReverseIterator should return the values in decreasing order", t, func() {
+		var got []uint32
+		for it := rb.ReverseIterator(); it.HasNext(); {
+			got = append(got, it.Next())
+		}
+		So(len(got), ShouldEqual, len(values))
+		for i, v := range got {
+			So(v, ShouldEqual, values[len(values)-1-i])
+		}
+		So(NewBitmap().ReverseIterator().HasNext(), ShouldBeFalse)
+	})
+
+	Convey("ReverseIteratorFrom should start at the largest value not larger than its argument", t, func() {
+		r := rand.New(rand.NewSource(12))
+		starts := []uint32{0, 2, 3, 4, 63, 64, 66, 1<<16 - 1, 1 << 16, 2<<16 + 7, 3<<16 + 99, 3<<16 + 100, MaxUint32}
+		for i := 0; i < 200; i++ {
+			starts = append(starts, values[r.Intn(len(values))]+uint32(r.Intn(3))-1)
+		}
+		for _, start := range starts {
+			// the values not larger than start, in decreasing order
+			i := len(values) - 1
+			for i >= 0 && values[i] > start {
+				i--
+			}
+			it := rb.ReverseIteratorFrom(start)
+			for n := 0; n < 150 && i >= 0; n++ {
+				So(it.HasNext(), ShouldBeTrue)
+				So(it.Next(), ShouldEqual, values[i])
+				i--
+			}
+			if i < 0 {
+				So(it.HasNext(), ShouldBeFalse)
+			}
+		}
+		So(NewBitmap().ReverseIteratorFrom(10).HasNext(), ShouldBeFalse)
+		So(BitmapOf(11).ReverseIteratorFrom(10).HasNext(), ShouldBeFalse)
+	})
+}
+
+func TestManyIterator(t *testing.T) {
+	rb := iteratorTestBitmap()
+	values := rb.ToArray()
+
+	Convey("NextMany should fill the buffer with the values in order", t, func() {
+		it := rb.ManyIterator()
+		for _, size := range []int{1, 3, 64, 65, 1000, 70000, len(values) + 1} {
+			it.Reset(rb)
+			buf := make([]uint32, size)
+			var got []uint32
+			for {
+				n := it.NextMany(buf)
+				got = append(got, buf[:n]...)
+				if n < size {
+					break
+				}
+			}
+			So(it.NextMany(buf), ShouldEqual, 0)
+			So(got, ShouldResemble, values)
+		}
+		So(it.NextMany(nil), ShouldEqual, 0)
+	})
+
+	Convey("Reset should switch to another bitmap without allocating", t, func() {
+		it := NewBitmap().ManyIterator()
+		buf := make([]uint32, 10)
+		So(it.NextMany(buf), ShouldEqual, 0)
+		other := BitmapOf(5, 1<<20)
+		it.Reset(other)
+		So(buf[:it.NextMany(buf)], ShouldResemble, []uint32{5, 1 << 20})
+
+		allocs := testing.AllocsPerRun(10, func() {
+			it.Reset(rb)
+			for it.NextMany(buf) > 0 {
+			}
+		})
+		So(allocs, ShouldEqual, 0)
+	})
+}
+
+func TestIterate(t *testing.T) {
+	Convey("Iterate should call its callback on the values in order", t, func() {
+		rb := iteratorTestBitmap()
+		var got []uint32
+		rb.Iterate(func(x uint32) bool {
+			got = append(got, x)
+			return true
+		})
+		So(got, ShouldResemble, rb.ToArray())
+
+		// stopping in each kind of container
+		for _, stop := range []uint32{0, 65, 1<<16 + 10, 3<<16 + 101, MaxUint32} {
+			got = got[:0]
+			rb.Iterate(func(x uint32) bool {
+				got = append(got, x)
+				return x < stop
+			})
+			So(got[len(got)-1], ShouldEqual, stop)
+		}
+	})
+}
+
+func TestIteratorModification(t *testing.T) {
+	Convey("iterators should panic when their bitmap is modified", t, func() {
+		rb := iteratorTestBitmap()
+		it := rb.Iterator()
+		it.Next()
+		rb.Add(17)
+		So(func() { it.HasNext() }, ShouldPanicWith, "roaring: bitmap modified during iteration")
+		So(func() { it.Next() }, ShouldPanic)
+
+		rev := rb.ReverseIterator()
+		rb.RemoveRange(0, 1<<16)
+		So(func() { rev.Next() }, ShouldPanic)
+
+		many := rb.ManyIterator()
+		rb.Or(BitmapOf(5))
+		So(func() { many.NextMany(make([]uint32, 10)) }, ShouldPanic)
+		many.Reset(rb)
+		So(many.NextMany(make([]uint32, 10)), ShouldEqual, 10)
+
+		peek := rb.PeekableIterator()
+		rb.Clear()
+		So(func() { peek.PeekNext() }, ShouldPanic)
+		So(func() { peek.AdvanceIfNeeded(10) }, ShouldPanic)
+
+		rb = iteratorTestBitmap()
+		So(func() {
+			rb.Iterate(func(x uint32) bool {
+				rb.Remove(x)
+				return true
+			})
+		}, ShouldPanic)
+		// the modification is caught before the next integer of the same container
+		rb = iteratorTestBitmap()
+		calls := 0
+		So(func() {
+			rb.Iterate(func(x uint32) bool {
+				calls++
+				rb.Add(x + 1)
+				return true
+			})
+		}, ShouldPanic)
+		So(calls, ShouldEqual, 1)
+		// stopping right after a modification is fine
+		rb.Iterate(func(x uint32) bool {
+			rb.Add(x + 1)
+			return false
+		})
+
+		// reading, or cloning with copy-on-write, is not a modification
+		rb.SetCopyOnWrite(true)
+		it = rb.Iterator()
+		rb.Contains(3)
+		rb.Clone()
+		So(it.HasNext(), ShouldBeTrue)
+	})
+
+	Convey("Remove should delete the value last returned by Next", t, func() {
+		rb := iteratorTestBitmap()
+		expect := rb.Clone()
+		it := rb.RemovableIterator()
+		So(func() { it.Remove() }, ShouldPanic)
+		var got []uint32
+		for i := 0; it.HasNext(); i++ {
+			x := it.Next()
+			got = append(got, x)
+			if i%3 != 1 {
+				it.Remove()
+				expect.Remove(x)
+			}
+		}
+		So(got, ShouldResemble, iteratorTestBitmap().ToArray())
+		So(rb.Equals(expect), ShouldBeTrue)
+		So(func() { it.Remove() }, ShouldNotPanic)
+		So(func() { it.Remove() }, ShouldPanic)
+
+		// emptying the bitmap, container by container
+		it = rb.RemovableIterator()
+		for it.HasNext() {
+			it.Next()
+			it.Remove()
+		}
+		So(rb.IsEmpty(), ShouldBeTrue)
+	})
+}
@@ -1,11 +1,11 @@
 package roaring
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sync/atomic"
 
 	snappy "github.com/glycerine/go-unsnap-stream"
 	"github.com/tinylib/msgp/msgp"
@@ -38,6 +38,13 @@ type container interface {
 	inot(firstOfRange, endx int) container // i stands for inplace, range is [firstOfRange,endx)
 	xor(r container) container
 	getShortIterator() shortIterable
+	getReverseIterator() reverseShortIterable
+	// iterate calls cb on the values of the container, or'ed with hs,
+	// until cb returns false; it returns false if cb did
+	iterate(hs uint32, cb func(x uint32) bool) bool
+	// iterateRuns calls cb on the maximal runs of consecutive values
+	// of the container, until cb returns false; it returns false if cb did
+	iterateRuns(cb func(start, last uint16) bool) bool
 	contains(i uint16) bool
 	maximum() uint16
 	minimum() uint16
@@ -62,6 +69,9 @@ type container interface {
 	serializedSizeInBytes() int
 	readFrom(io.Reader) (int, error)
 	writeTo(io.Writer) (int, error)
+	appendTo(dst []byte) []byte // appends the bytes that writeTo would write
+	validate() error            // checks the invariants of the container
+	sharesCount() *int32        // the number of bitmaps sharing the container, beyond the first
 
 	numberOfRuns() int
 	toEfficientContainer() container
@@ -101,6 +111,21 @@ type roaringArray struct {
 	needCopyOnWrite []bool
 	copyOnWrite     bool
 
+	// modCount counts the modifications of the array, so that
+	// iterators can tell when the bitmap changes under them
+	modCount uint64 `msg:"-"`
+
+	// cardinality caches the number of integers in the array. It is up
+	// to date if cardinalityValid, and if the array has not been modified
+	// since: the modifications that do not update it invalidate it.
+	cardinality         uint64 `msg:"-"`
+	cardinalityModCount uint64 `msg:"-"`
+	cardinalityValid    bool   `msg:"-"`
+
+	// index finds the keys in constant time once there are
+	// keyIndexThreshold of them; it is nil below
+	index *keyIndex `msg:"-"`
+
 	// conserz is used at serialization time
 	// to serialize containers. Otherwise empty.
 	conserz []containerSerz
@@ -125,15 +150,29 @@ func newRoaringArray() *roaringArray {
 //    (possibly all) elements of ra.containers in-place with space
 //    optimized versions.
 func (ra *roaringArray) runOptimize() {
+	ra.modCount++
 	for i := range ra.containers {
 		ra.containers[i] = ra.containers[i].toEfficientContainer()
 	}
 }
 
+// appendContainer adds value at the end of ra. A cached cardinality that
+// is up to date is kept so by adding that of value, so that the results
+// built by appending containers to an empty array start out counted; a
+// lazy container, whose cardinality is not known yet, leaves it stale.
 func (ra *roaringArray) appendContainer(key uint16, value container, mustCopyOnWrite bool) {
+	card, counted := ra.cachedCardinality()
+	ra.modCount++
 	ra.keys = append(ra.keys, key)
 	ra.containers = append(ra.containers, value)
 	ra.needCopyOnWrite = append(ra.needCopyOnWrite, mustCopyOnWrite)
+	ra.keyAdded(key)
+	if !counted {
+		return
+	}
+	if n := value.getCardinality(); n != invalidCardinality {
+		ra.cardinality, ra.cardinalityModCount = card+uint64(n), ra.modCount
+	}
 }
 
 func (ra *roaringArray) appendWithoutCopy(sa roaringArray, startingindex int) {
@@ -147,7 +186,21 @@ func (ra *roaringArray) appendCopy(sa roaringArray, startingindex int) {
 		// since there is no copy-on-write, we need to clone the container (this is important)
 		ra.appendContainer(sa.keys[startingindex], sa.containers[startingindex].clone(), copyonwrite)
 	} else {
-		ra.appendContainer(sa.keys[startingindex], sa.containers[startingindex], copyonwrite)
+		ra.appendContainer(sa.keys[startingindex], shareContainer(sa.containers[startingindex]), copyonwrite)
+		if !sa.needsCopyOnWrite(startingindex) {
+			sa.setNeedsCopyOnWrite(startingindex)
+		}
+	}
+}
+
+// insertCopyAt inserts the container of sa at startingindex at index i, sharing it as appendCopy does
+func (ra *roaringArray) insertCopyAt(i int, sa roaringArray, startingindex int) {
+	copyonwrite := (ra.copyOnWrite && sa.copyOnWrite) || sa.needsCopyOnWrite(startingindex)
+	if !copyonwrite {
+		ra.insertNewKeyValueAt(i, sa.keys[startingindex], sa.containers[startingindex].clone())
+	} else {
+		ra.insertNewKeyValueAt(i, sa.keys[startingindex], shareContainer(sa.containers[startingindex]))
+		ra.setNeedsCopyOnWrite(i)
 		if !sa.needsCopyOnWrite(startingindex) {
 			sa.setNeedsCopyOnWrite(startingindex)
 		}
@@ -176,7 +229,7 @@ func (ra *roaringArray) appendCopiesUntil(sa roaringArray, stoppingKey uint16) {
 		}
 		thiscopyonewrite := copyonwrite || sa.needsCopyOnWrite(i)
 		if thiscopyonewrite {
-			ra.appendContainer(sa.keys[i], sa.containers[i], thiscopyonewrite)
+			ra.appendContainer(sa.keys[i], shareContainer(sa.containers[i]), thiscopyonewrite)
 			if !sa.needsCopyOnWrite(i) {
 				sa.setNeedsCopyOnWrite(i)
 			}
@@ -203,7 +256,7 @@ func (ra *roaringArray) appendCopiesAfter(sa roaringArray, beforeStart uint16) {
 	for i := startLocation; i < sa.size(); i++ {
 		thiscopyonewrite := copyonwrite || sa.needsCopyOnWrite(i)
 		if thiscopyonewrite {
-			ra.appendContainer(sa.keys[i], sa.containers[i], thiscopyonewrite)
+			ra.appendContainer(sa.keys[i], shareContainer(sa.containers[i]), thiscopyonewrite)
 			if !sa.needsCopyOnWrite(i) {
 				sa.setNeedsCopyOnWrite(i)
 			}
@@ -230,6 +283,13 @@ func (ra *roaringArray) removeIndexRange(begin, end int) {
 }
 
 func (ra *roaringArray) resize(newsize int) {
+	ra.truncate(newsize)
+	ra.rebuildKeyIndex()
+}
+
+// truncate is resize, leaving the key index to its caller
+func (ra *roaringArray) truncate(newsize int) {
+	ra.modCount++
 	for k := newsize; k < len(ra.containers); k++ {
 		ra.containers[k] = nil
 	}
@@ -240,7 +300,7 @@ func (ra *roaringArray) resize(newsize int) {
 }
 
 func (ra *roaringArray) clear() {
-	*ra = roaringArray{}
+	*ra = roaringArray{modCount: ra.modCount + 1}
 }
 
 func (ra *roaringArray) clone() *roaringArray {
@@ -253,7 +313,9 @@ func (ra *roaringArray) clone() *roaringArray {
 		sa.keys = make([]uint16, len(ra.keys))
 		copy(sa.keys, ra.keys)
 		sa.containers = make([]container, len(ra.containers))
-		copy(sa.containers, ra.containers)
+		for i, c := range ra.containers {
+			sa.containers[i] = shareContainer(c)
+		}
 		sa.needCopyOnWrite = make([]bool, len(ra.needCopyOnWrite))
 
 		ra.markAllAsNeedingCopyOnWrite()
@@ -273,9 +335,69 @@ func (ra *roaringArray) clone() *roaringArray {
 
 		sa.needCopyOnWrite = make([]bool, len(ra.needCopyOnWrite))
 	}
+	if card, ok := ra.cachedCardinality(); ok {
+		sa.setCardinality(card)
+	}
+	if ra.index != nil {
+		index := *ra.index
+		sa.index = &index
+	}
 	return &sa
 }
 
+// countCardinality sums the cardinalities of the containers
+func (ra *roaringArray) countCardinality() uint64 {
+	size := uint64(0)
+	for _, c := range ra.containers {
+		size += uint64(c.getCardinality())
+	}
+	return size
+}
+
+// cachedCardinality returns the cached cardinality, and whether it is up to date
+func (ra *roaringArray) cachedCardinality() (uint64, bool) {
+	return ra.cardinality, ra.cardinalityValid && ra.cardinalityModCount == ra.modCount
+}
+
+// cardinalityBeforeUpdate returns the cardinality of the array, about to
+// be updated by a modification. It is counted if it is not cached: only
+// modifications fill the cache, so that reading never writes.
+func (ra *roaringArray) cardinalityBeforeUpdate() uint64 {
+	if card, ok := ra.cachedCardinality(); ok {
+		return card
+	}
+	return ra.countCardinality()
+}
+
+// setCardinality caches card as the cardinality of the array as it is now
+func (ra *roaringArray) setCardinality(card uint64) {
+	if debugCardinality {
+		ra.checkCardinality(card)
+	}
+	ra.cardinality, ra.cardinalityModCount, ra.cardinalityValid = card, ra.modCount, true
+}
+
+// checkCardinality panics if card is not the cardinality of the array
+func (ra *roaringArray) checkCardinality(card uint64) {
+	if count := ra.countCardinality(); count != card {
+		panic(fmt.Sprintf("roaring: cached cardinality %d, but %d integers counted", card, count))
+	}
+}
+
+// rangeCardinality returns the number of integers in the containers
+// whose keys are in [first, last]
+func (ra *roaringArray) rangeCardinality(first, last uint16) uint64 {
+	i := ra.getIndex(first)
+	if i < 0 {
+		i = -i - 1
+	}
+	size := uint64(0)
+	for ; i < len(ra.keys) && ra.keys[i] <= last; i++ {
+		size += uint64(ra.containers[i].getCardinality())
+	}
+	return size
+}
+
 // unused function:
 //func (ra *roaringArray) containsKey(x uint16) bool {
 //	return (ra.binarySearch(0, int64(len(ra.keys)), x) >= 0)
@@ -295,18 +417,55 @@ func (ra *roaringArray) getContainerAtIndex(i int) container {
 
 func (ra *roaringArray) getWritableContainerAtIndex(i int) container {
 	if ra.needCopyOnWrite[i] {
-		ra.containers[i] = ra.containers[i].clone()
+		ra.containers[i] = writableContainer(ra.containers[i])
 		ra.needCopyOnWrite[i] = false
 	}
 	return ra.containers[i]
 }
 
+// Copy-on-write: a container may be shared by several bitmaps, which
+// mark it with needCopyOnWrite. The container counts the bitmaps sharing
+// it beyond the first in its shares field. A share is taken by whoever
+// owns the bitmap the container is shared from, and it is given back
+// atomically by a bitmap that copies the container before modifying it,
+// so that the last bitmap left can modify the container in place. As
+// shares are never given back too early, bitmaps sharing containers can
+// be modified concurrently by different goroutines.
+
+// shareContainer takes a share of c, which is about to be added to
+// another bitmap, and returns c
+func shareContainer(c container) container {
+	if rc, ok := c.(*runContainer16); ok {
+		// fill the cardinality cache now: once shared,
+		// the container must not be written by readers
+		rc.cardinality()
+	}
+	atomic.AddInt32(c.sharesCount(), 1)
+	return c
+}
+
+// writableContainer returns a container with the values of c that its
+// caller, one of the bitmaps sharing c, can modify: c itself if no other
+// bitmap shares it any more, otherwise a copy of c. In that case, the
+// share of the caller is given back once the copy is made.
+func writableContainer(c container) container {
+	if atomic.LoadInt32(c.sharesCount()) == 0 {
+		return c
+	}
+	answer := c.clone()
+	atomic.AddInt32(c.sharesCount(), -1)
+	return answer
+}
+
 func (ra *roaringArray) getIndex(x uint16) int {
 	// before the binary search, we optimize for frequent cases
 	size := len(ra.keys)
 	if (size == 0) || (ra.keys[size-1] == x) {
 		return size - 1
 	}
+	if ra.index != nil {
+		return ra.index.find(x)
+	}
 	return int(ra.binarySearch(0, int64(size), x))
 }
 
@@ -315,6 +474,7 @@ func (ra *roaringArray) getKeyAtIndex(i int) uint16 {
 }
 
 func (ra *roaringArray) insertNewKeyValueAt(i int, key uint16, value container) {
+	ra.modCount++
 	ra.keys = append(ra.keys, 0)
 	ra.containers = append(ra.containers, nil)
 
@@ -327,6 +487,7 @@ func (ra *roaringArray) insertNewKeyValueAt(i int, key uint16, value container)
 	ra.needCopyOnWrite = append(ra.needCopyOnWrite, false)
 	copy(ra.needCopyOnWrite[i+1:], ra.needCopyOnWrite[i:])
 	ra.needCopyOnWrite[i] = false
+	ra.keyAdded(key)
 }
 
 func (ra *roaringArray) remove(key uint16) bool {
@@ -339,24 +500,44 @@ func (ra *roaringArray) remove(key uint16) bool {
 }
 
 func (ra *roaringArray) removeAtIndex(i int) {
+	if ra.index != nil {
+		ra.index.remove(ra.keys[i])
+	}
 	copy(ra.keys[i:], ra.keys[i+1:])
 	copy(ra.containers[i:], ra.containers[i+1:])
 
 	copy(ra.needCopyOnWrite[i:], ra.needCopyOnWrite[i+1:])
 
-	ra.resize(len(ra.keys) - 1)
+	ra.truncate(len(ra.keys) - 1)
+	if len(ra.keys) < keyIndexThreshold/2 {
+		ra.index = nil
+	}
 }
 
 func (ra *roaringArray) setContainerAtIndex(i int, c container) {
+	ra.modCount++
 	ra.containers[i] = c
 }
 
 func (ra *roaringArray) replaceKeyAndContainerAtIndex(i int, key uint16, c container, mustCopyOnWrite bool) {
+	ra.modCount++
+	if ra.keys[i] != key {
+		// the callers compact the keys, and resize the array once done
+		ra.index = nil
+	}
 	ra.keys[i] = key
 	ra.containers[i] = c
 	ra.needCopyOnWrite[i] = mustCopyOnWrite
 }
 
+// checkUnmodified panics if the array was modified since its
+// modification count was modCount
+func (ra *roaringArray) checkUnmodified(modCount uint64) {
+	if ra.modCount != modCount {
+		panic("roaring: bitmap modified during iteration")
+	}
+}
+
 func (ra *roaringArray) size() int {
 	return len(ra.keys)
 }
@@ -432,102 +613,94 @@ func (ra *roaringArray) serializedSizeInBytes() uint64 {
 	return answer
 }
 
+// appendHeader appends the cookie, the descriptive header and,
+// when present, the offset header to dst. The containers follow.
 //
 // spec: https://github.com/RoaringBitmap/RoaringFormatSpec
 //
-func (ra *roaringArray) toBytes() ([]byte, error) {
-	stream := &bytes.Buffer{}
+func (ra *roaringArray) appendHeader(dst []byte) []byte {
+	var buf [8]byte
 	hasRun := ra.hasRunCompression()
-	isRunSizeInBytes := 0
-	cookieSize := 8
-	if hasRun {
-		cookieSize = 4
-		isRunSizeInBytes = (len(ra.keys) + 7) / 8
-	}
-	descriptiveHeaderSize := 4 * len(ra.keys)
-	preambleSize := cookieSize + isRunSizeInBytes + descriptiveHeaderSize
-
-	buf := make([]byte, preambleSize+4*len(ra.keys))
-
-	nw := 0
-
 	if hasRun {
 		binary.LittleEndian.PutUint16(buf[0:], uint16(serialCookie))
-		nw += 2
 		binary.LittleEndian.PutUint16(buf[2:], uint16(len(ra.keys)-1))
-		nw += 2
-
-		// compute isRun bitmap
-		var ir []byte
+		dst = append(dst, buf[:4]...)
 
-		isRun := newBitmapContainer()
+		// isRun bitset, built in place
+		isRun := len(dst)
+		for i := 0; i < (len(ra.keys)+7)/8; i++ {
+			dst = append(dst, 0)
+		}
 		for i, c := range ra.containers {
-			switch c.(type) {
-			case *runContainer16:
-				isRun.iadd(uint16(i))
+			if _, ok := c.(*runContainer16); ok {
+				dst[isRun+i/8] |= 1 << uint(i%8)
 			}
 		}
-		// convert to little endian
-		ir = isRun.asLittleEndianByteSlice()[:isRunSizeInBytes]
-		nw += copy(buf[nw:], ir)
 	} else {
 		binary.LittleEndian.PutUint32(buf[0:], uint32(serialCookieNoRunContainer))
-		nw += 4
 		binary.LittleEndian.PutUint32(buf[4:], uint32(len(ra.keys)))
-		nw += 4
+		dst = append(dst, buf[:8]...)
 	}
 
 	// descriptive header
 	for i, key := range ra.keys {
-		binary.LittleEndian.PutUint16(buf[nw:], uint16(key))
-		nw += 2
-		c := ra.containers[i]
-		binary.LittleEndian.PutUint16(buf[nw:], uint16(c.getCardinality()-1))
-		nw += 2
+		binary.LittleEndian.PutUint16(buf[0:], key)
+		binary.LittleEndian.PutUint16(buf[2:], uint16(ra.containers[i].getCardinality()-1))
+		dst = append(dst, buf[:4]...)
 	}
 
-	startOffset := int64(preambleSize + 4*len(ra.keys))
 	if !hasRun || (len(ra.keys) >= noOffsetThreshold) {
 		// offset header
+		startOffset := uint32(ra.headerSize())
 		for _, c := range ra.containers {
-			binary.LittleEndian.PutUint32(buf[nw:], uint32(startOffset))
-			nw += 4
-			switch rc := c.(type) {
-			case *runContainer16:
-				startOffset += 2 + int64(len(rc.iv))*4
-			default:
-				startOffset += int64(getSizeInBytesFromCardinality(c.getCardinality()))
-			}
+			binary.LittleEndian.PutUint32(buf[0:], startOffset)
+			dst = append(dst, buf[:4]...)
+			startOffset += uint32(c.serializedSizeInBytes())
 		}
 	}
+	return dst
+}
 
-	_, err := stream.Write(buf[:nw])
-	if err != nil {
-		return nil, err
+// appendBinary appends the serialized bitmap to dst, growing dst at
+// most once.
+func (ra *roaringArray) appendBinary(dst []byte) []byte {
+	size := int(ra.serializedSizeInBytes())
+	if cap(dst)-len(dst) < size {
+		grown := make([]byte, len(dst), len(dst)+size)
+		copy(grown, dst)
+		dst = grown
 	}
-	for i, c := range ra.containers {
-		_ = i
-		_, err := c.writeTo(stream)
-		if err != nil {
-			return nil, err
-		}
+	dst = ra.appendHeader(dst)
+	for _, c := range ra.containers {
+		dst = c.appendTo(dst)
 	}
-	return stream.Bytes(), nil
+	return dst
 }
 
+func (ra *roaringArray) toBytes() ([]byte, error) {
+	return ra.appendBinary(nil), nil
+}
+
+// writeTo streams the header and then each container to out,
+// without building the whole serialized bitmap in memory.
 //
 // spec: https://github.com/RoaringBitmap/RoaringFormatSpec
 //
 func (ra *roaringArray) writeTo(out io.Writer) (int64, error) {
-	by, err := ra.toBytes()
+	header := ra.appendHeader(make([]byte, 0, ra.headerSize()))
+	n, err := out.Write(header)
+	written := int64(n)
 	if err != nil {
-		return 0, err
+		return written, err
 	}
-	n, err := out.Write(by)
-	if err == nil && n < len(by) {
-		err = io.ErrShortWrite
+	for _, c := range ra.containers {
+		n, err := c.writeTo(out)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
 	}
-	return int64(n), err
+	return written, nil
 }
 
 func (ra *roaringArray) readFrom(stream io.Reader) (int64, error) {
@@ -613,6 +786,90 @@ func (ra *roaringArray) readFrom(stream io.Reader) (int64, error) {
 	return int64(pos), nil
 }
 
+// fromBuffer points ra at the containers serialized in buf, using the
+// same format as readFrom, without copying the container contents.
+// Every container is marked as needing copy-on-write so that buf
+// is never written to; buf must not be modified while ra uses it.
+func (ra *roaringArray) fromBuffer(buf []byte) (int64, error) {
+	pos := 0
+	if len(buf) < 8 {
+		return 0, fmt.Errorf("error in roaringArray.fromBuffer: buffer too small, expecting at least 8 bytes, was %d", len(buf))
+	}
+	cookie := binary.LittleEndian.Uint32(buf)
+	pos += 4
+	var size uint32
+	haveRunContainers := false
+	var isRun []byte
+	if cookie&0x0000FFFF == serialCookie {
+		haveRunContainers = true
+		size = uint32(uint16(cookie>>16) + 1)
+		isRunSize := (int(size) + 7) / 8
+		if pos+isRunSize > len(buf) {
+			return 0, fmt.Errorf("error in roaringArray.fromBuffer: could not read the runContainer bit flags of length %v bytes", isRunSize)
+		}
+		isRun = buf[pos : pos+isRunSize]
+		pos += isRunSize
+	} else if cookie == serialCookieNoRunContainer {
+		size = binary.LittleEndian.Uint32(buf[pos:])
+		pos += 4
+	} else {
+		return 0, fmt.Errorf("error in roaringArray.fromBuffer: did not find expected serialCookie in header")
+	}
+	if size > 1<<16 {
+		return 0, fmt.Errorf("error in roaringArray.fromBuffer: it is logically impossible to have more than (1<<16) containers, got %d", size)
+	}
+	// descriptive header
+	if pos+4*int(size) > len(buf) {
+		return 0, fmt.Errorf("error in roaringArray.fromBuffer: buffer too small for the descriptive header of %d containers", size)
+	}
+	keycard := byteSliceAsUint16Slice(buf[pos : pos+4*int(size)])
+	pos += 4 * int(size)
+	// offset header
+	if !haveRunContainers || size >= noOffsetThreshold {
+		pos += 4 * int(size) // we never skip ahead so this data can be ignored
+	}
+
+	ra.keys = make([]uint16, 0, size)
+	ra.containers = make([]container, 0, size)
+	ra.needCopyOnWrite = make([]bool, 0, size)
+	ra.index = nil
+	for i := 0; i < int(size); i++ {
+		key := keycard[2*i]
+		card := int(keycard[2*i+1]) + 1
+		var c container
+		if haveRunContainers && isRun[i/8]&(1<<uint(i%8)) != 0 {
+			if pos+2 > len(buf) {
+				return 0, fmt.Errorf("error in roaringArray.fromBuffer: buffer too small for the run count of container %d", i)
+			}
+			numRuns := int(binary.LittleEndian.Uint16(buf[pos:]))
+			pos += 2
+			if pos+4*numRuns > len(buf) {
+				return 0, fmt.Errorf("error in roaringArray.fromBuffer: buffer too small for the %d runs of container %d", numRuns, i)
+			}
+			// the cardinality is counted from the runs when needed, not taken on trust
+			c = &runContainer16{iv: byteSliceAsInterval16Slice(buf[pos : pos+4*numRuns])}
+			pos += 4 * numRuns
+		} else {
+			nb := getSizeInBytesFromCardinality(card)
+			if pos+nb > len(buf) {
+				return 0, fmt.Errorf("error in roaringArray.fromBuffer: buffer too small for the %d bytes of container %d", nb, i)
+			}
+			if card > arrayDefaultMaxSize {
+				c = &bitmapContainer{
+					cardinality: card,
+					bitmap:      byteSliceAsUint64Slice(buf[pos : pos+nb]),
+				}
+			} else {
+				c = &arrayContainer{content: byteSliceAsUint16Slice(buf[pos : pos+nb])}
+			}
+			pos += nb
+		}
+		// the buffer holds a share, so the container is never modified
+		ra.appendContainer(key, shareContainer(c), true)
+	}
+	return int64(pos), nil
+}
+
 func (ra *roaringArray) hasRunCompression() bool {
 	for _, c := range ra.containers {
 		switch c.(type) {
@@ -660,11 +917,14 @@ func (ra *roaringArray) writeToMsgpack(stream io.Writer) error {
 }
 
 func (ra *roaringArray) readFromMsgpack(stream io.Reader) error {
+	ra.modCount++
+	ra.index = nil
 	r := snappy.NewReader(stream)
 	err := msgp.Decode(r, ra)
 	if err != nil {
 		return err
 	}
+	ra.rebuildKeyIndex()
 
 	if len(ra.containers) != len(ra.keys) {
 		ra.containers = make([]container, len(ra.keys))
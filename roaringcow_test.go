@@ -6,6 +6,7 @@ import (
 	"log"
 	"math/rand"
 	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -1841,7 +1842,7 @@ func TestStatsCOW(t *testing.T) {
 			BitmapContainerBytes:  0,
 
 			RunContainers:      1,
-			RunContainerBytes:  52,
+			RunContainerBytes:  44,
 			RunContainerValues: 60000,
 		}
 		rr := NewBitmap()
@@ -1877,3 +1878,107 @@ func TestFlipVerySmallCOW(t *testing.T) {
 		So(rbcard, ShouldEqual, 9)
 	})
 }
+
+func TestCOWLastOwnerModifiesInPlace(t *testing.T) {
+	Convey("the last bitmap sharing a container should not copy it", t, func() {
+		rb1 := NewBitmap()
+		rb1.SetCopyOnWrite(true)
+		rb1.AddRange(0, 100)
+		c := rb1.highlowcontainer.containers[0]
+
+		rb2 := rb1.Clone()
+		So(rb2.highlowcontainer.containers[0], ShouldEqual, c)
+		So(*c.sharesCount(), ShouldEqual, 1)
+
+		rb2.Add(1000)
+		So(rb2.highlowcontainer.containers[0], ShouldNotEqual, c)
+		So(*c.sharesCount(), ShouldEqual, 0)
+
+		rb1.Add(2000)
+		So(rb1.highlowcontainer.containers[0], ShouldEqual, c)
+		So(rb1.GetCardinality(), ShouldEqual, 101)
+		So(rb2.GetCardinality(), ShouldEqual, 101)
+		So(rb1.Contains(1000), ShouldBeFalse)
+		So(rb2.Contains(2000), ShouldBeFalse)
+	})
+}
+
+func TestXorDoesNotAliasContainers(t *testing.T) {
+	Convey("in-place Xor should not leave the two bitmaps writing to one container", t, func() {
+		for _, cow := range []bool{false, true} {
+			rb1 := BitmapOf(1 << 17)
+			rb2 := BitmapOf(1, 2, 3)
+			rb1.SetCopyOnWrite(cow)
+			rb2.SetCopyOnWrite(cow)
+
+			rb1.Xor(rb2)
+			So(rb1.GetCardinality(), ShouldEqual, 4)
+			rb1.Add(4)
+			rb2.Add(5)
+			So(rb1.Contains(5), ShouldBeFalse)
+			So(rb2.Contains(4), ShouldBeFalse)
+			So(rb1.GetCardinality(), ShouldEqual, 5)
+			So(rb2.GetCardinality(), ShouldEqual, 4)
+		}
+	})
+}
+
+// run with go test -race -run TestConcurrent
+func TestConcurrentCOWClones(t *testing.T) {
+	Convey("clones sharing containers can be modified by different goroutines", t, func() {
+		base := NewBitmap()
+		base.SetCopyOnWrite(true)
+		for i := uint32(0); i < 1<<20; i += 3 {
+			base.Add(i)
+		}
+		base.AddRange(1<<21, 1<<21+100000)
+		base.RunOptimize()
+		expect := base.Clone()
+		// read by all the goroutines, its runs meet those of base
+		runs := NewBitmap()
+		for i := uint64(1 << 21); i < 1<<21+200000; i += 5000 {
+			runs.AddRange(i, i+1000)
+		}
+		runs.RunOptimize()
+
+		const clones = 8
+		results := make([]*Bitmap, clones)
+		var wg sync.WaitGroup
+		for g := 0; g < clones; g++ {
+			rb := base.Clone()
+			wg.Add(1)
+			go func(g int, rb *Bitmap) {
+				defer wg.Done()
+				// clones of clones share the containers too
+				rb = rb.Clone()
+				rb.GetCardinality()
+				for i := uint32(g); i < 1<<20; i += 7 {
+					rb.Add(i)
+				}
+				rb.RemoveRange(uint64(g)<<17, uint64(g+1)<<17)
+				rb.Flip(1<<21+uint64(g)*1000, 1<<21+uint64(g+1)*1000)
+				rb.Or(BitmapOf(uint32(g) << 25))
+				rb.Or(runs)
+				And(rb, runs)
+				results[g] = rb
+			}(g, rb)
+		}
+		base.AddRange(0, 1<<20)
+		wg.Wait()
+
+		So(base.GetCardinality(), ShouldEqual, 1<<20+100000)
+		for g, rb := range results {
+			want := expect.Clone()
+			for i := uint32(g); i < 1<<20; i += 7 {
+				want.Add(i)
+			}
+			want.RemoveRange(uint64(g)<<17, uint64(g+1)<<17)
+			want.Flip(1<<21+uint64(g)*1000, 1<<21+uint64(g+1)*1000)
+			want.Add(uint32(g) << 25)
+			want.Or(runs)
+			So(rb.Equals(want), ShouldBeTrue)
+			So(rb.Validate(), ShouldBeNil)
+		}
+		So(expect.Validate(), ShouldBeNil)
+	})
+}
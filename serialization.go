@@ -16,11 +16,23 @@ func (b *runContainer16) writeTo(stream io.Writer) (int, error) {
 	binary.LittleEndian.PutUint16(buf[0:], uint16(len(b.iv)))
 	for i, v := range b.iv {
 		binary.LittleEndian.PutUint16(buf[2+i*4:], v.start)
-		binary.LittleEndian.PutUint16(buf[2+2+i*4:], v.last-v.start)
+		binary.LittleEndian.PutUint16(buf[2+2+i*4:], v.length)
 	}
 	return stream.Write(buf)
 }
 
+func (b *runContainer16) appendTo(dst []byte) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint16(buf[0:], uint16(len(b.iv)))
+	dst = append(dst, buf[:2]...)
+	for _, v := range b.iv {
+		binary.LittleEndian.PutUint16(buf[0:], v.start)
+		binary.LittleEndian.PutUint16(buf[2:], v.length)
+		dst = append(dst, buf[:]...)
+	}
+	return dst
+}
+
 func (b *runContainer32) writeToMsgpack(stream io.Writer) (int, error) {
 	bts, err := b.MarshalMsg(nil)
 	if err != nil {
@@ -70,11 +82,11 @@ func (b *runContainer16) readFrom(stream io.Reader) (int, error) {
 	}
 	nr := int(numRuns)
 	for i := 0; i < nr; i++ {
-		if i > 0 && b.iv[i-1].last >= encRun[i*2] {
-			panic(fmt.Errorf("error: stored runContainer had runs that were not in sorted order!! (b.iv[i-1=%v].last = %v >= encRun[i=%v] = %v)", i-1, b.iv[i-1].last, i, encRun[i*2]))
+		if i > 0 && b.iv[i-1].last() >= encRun[i*2] {
+			panic(fmt.Errorf("error: stored runContainer had runs that were not in sorted order!! (b.iv[i-1=%v].last() = %v >= encRun[i=%v] = %v)", i-1, b.iv[i-1].last(), i, encRun[i*2]))
 		}
-		b.iv = append(b.iv, interval16{start: encRun[i*2], last: encRun[i*2] + encRun[i*2+1]})
+		b.iv = append(b.iv, interval16{start: encRun[i*2], length: encRun[i*2+1]})
 		b.card += int64(encRun[i*2+1]) + 1
 	}
-	return 0, err
+	return 2 + 4*nr, err
 }
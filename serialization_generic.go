@@ -17,6 +17,15 @@ func (b *arrayContainer) writeTo(stream io.Writer) (int, error) {
 	return stream.Write(buf)
 }
 
+func (b *arrayContainer) appendTo(dst []byte) []byte {
+	var buf [2]byte
+	for _, v := range b.content {
+		binary.LittleEndian.PutUint16(buf[:], v)
+		dst = append(dst, buf[:]...)
+	}
+	return dst
+}
+
 func (b *arrayContainer) readFrom(stream io.Reader) (int, error) {
 	err := binary.Read(stream, binary.LittleEndian, b.content)
 	if err != nil {
@@ -42,6 +51,15 @@ func (b *bitmapContainer) writeTo(stream io.Writer) (int, error) {
 	return stream.Write(buf)
 }
 
+func (b *bitmapContainer) appendTo(dst []byte) []byte {
+	var buf [8]byte
+	for _, v := range b.bitmap {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		dst = append(dst, buf[:]...)
+	}
+	return dst
+}
+
 func (b *bitmapContainer) readFrom(stream io.Reader) (int, error) {
 	err := binary.Read(stream, binary.LittleEndian, b.bitmap)
 	if err != nil {
@@ -57,3 +75,39 @@ func (bc *bitmapContainer) asLittleEndianByteSlice() []byte {
 	}
 	return by
 }
+
+func byteSliceAsUint16Slice(slice []byte) []uint16 {
+	if len(slice)%2 != 0 {
+		panic("slice size should be divisible by 2")
+	}
+	b := make([]uint16, len(slice)/2)
+	for i := range b {
+		b[i] = binary.LittleEndian.Uint16(slice[2*i:])
+	}
+	return b
+}
+
+func byteSliceAsUint64Slice(slice []byte) []uint64 {
+	if len(slice)%8 != 0 {
+		panic("slice size should be divisible by 8")
+	}
+	b := make([]uint64, len(slice)/8)
+	for i := range b {
+		b[i] = binary.LittleEndian.Uint64(slice[8*i:])
+	}
+	return b
+}
+
+func byteSliceAsInterval16Slice(slice []byte) []interval16 {
+	if len(slice)%4 != 0 {
+		panic("slice size should be divisible by 4")
+	}
+	b := make([]interval16, len(slice)/4)
+	for i := range b {
+		b[i] = interval16{
+			start:  binary.LittleEndian.Uint16(slice[4*i:]),
+			length: binary.LittleEndian.Uint16(slice[4*i+2:]),
+		}
+	}
+	return b
+}
@@ -18,6 +18,14 @@ func (bc *bitmapContainer) writeTo(stream io.Writer) (int, error) {
 	return stream.Write(buf)
 }
 
+func (ac *arrayContainer) appendTo(dst []byte) []byte {
+	return append(dst, uint16SliceAsByteSlice(ac.content)...)
+}
+
+func (bc *bitmapContainer) appendTo(dst []byte) []byte {
+	return append(dst, uint64SliceAsByteSlice(bc.bitmap)...)
+}
+
 // readFrom reads an arrayContainer from stream.
 // PRE-REQUISITE: you must size the arrayContainer correctly (allocate b.content)
 // *before* you call readFrom. We can't guess the size in the stream
@@ -61,3 +69,51 @@ func uint16SliceAsByteSlice(slice []uint16) []byte {
 func (bc *bitmapContainer) asLittleEndianByteSlice() []byte {
 	return uint64SliceAsByteSlice(bc.bitmap)
 }
+
+func byteSliceAsUint16Slice(slice []byte) []uint16 {
+	if len(slice)%2 != 0 {
+		panic("slice size should be divisible by 2")
+	}
+	// make a new slice header
+	header := *(*reflect.SliceHeader)(unsafe.Pointer(&slice))
+
+	// update its capacity and length, so that appending
+	// never writes past the end of slice
+	header.Len /= 2
+	header.Cap = header.Len
+
+	// return it
+	return *(*[]uint16)(unsafe.Pointer(&header))
+}
+
+func byteSliceAsUint64Slice(slice []byte) []uint64 {
+	if len(slice)%8 != 0 {
+		panic("slice size should be divisible by 8")
+	}
+	// make a new slice header
+	header := *(*reflect.SliceHeader)(unsafe.Pointer(&slice))
+
+	// update its capacity and length, so that appending
+	// never writes past the end of slice
+	header.Len /= 8
+	header.Cap = header.Len
+
+	// return it
+	return *(*[]uint64)(unsafe.Pointer(&header))
+}
+
+func byteSliceAsInterval16Slice(slice []byte) []interval16 {
+	if len(slice)%4 != 0 {
+		panic("slice size should be divisible by 4")
+	}
+	// make a new slice header
+	header := *(*reflect.SliceHeader)(unsafe.Pointer(&slice))
+
+	// update its capacity and length, so that appending
+	// never writes past the end of slice
+	header.Len /= 4
+	header.Cap = header.Len
+
+	// return it
+	return *(*[]interval16)(unsafe.Pointer(&header))
+}
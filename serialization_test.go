@@ -4,6 +4,7 @@ package roaring
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"fmt"
 	"io/ioutil"
@@ -121,10 +122,13 @@ func TestSerializationReadRunsFromFile039(t *testing.T) {
 	}
 
 	newrb := NewBitmap()
-	_, err = newrb.ReadFrom(bytes.NewBuffer(by))
+	n, err := newrb.ReadFrom(bytes.NewBuffer(by))
 	if err != nil {
 		t.Errorf("Failed reading %s: %s", fn, err)
 	}
+	if n != int64(len(by)) {
+		t.Errorf("ReadFrom reported %d bytes read, but %s holds %d", n, fn, len(by))
+	}
 }
 
 func TestSerializationBasic4WriteAndReadFile040(t *testing.T) {
@@ -678,3 +682,168 @@ func TestSerializationRunContainer32Msgpack050(t *testing.T) {
 
 	})
 }
+
+func TestSerializationFromBuffer053(t *testing.T) {
+
+	Convey("FromBuffer should give a view equal to the serialized bitmap that never writes to the buffer", t, func() {
+
+		rb := NewBitmap()
+		rb.AddMany([]uint32{1, 2, 3, 1000, 60000})    // array
+		for i := uint32(1 << 16); i < 3<<16; i += 3 { // bitmaps
+			rb.Add(i)
+		}
+		rb.AddRange(3<<16, 3<<16+5000) // run
+		rb.Add(5 << 16)
+		rb.RunOptimize()
+		So(rb.HasRunCompression(), ShouldBeTrue)
+
+		for _, withRuns := range []bool{true, false} {
+			orig := rb.Clone()
+			if !withRuns {
+				orig = BitmapOf(rb.ToArray()...)
+			}
+			buf, err := orig.ToBytes()
+			So(err, ShouldBeNil)
+			saved := make([]byte, len(buf))
+			copy(saved, buf)
+
+			view, err := FromBuffer(buf)
+			So(err, ShouldBeNil)
+			So(view.Equals(orig), ShouldBeTrue)
+			So(view.GetCardinality(), ShouldEqual, orig.GetCardinality())
+			So(view.Contains(60000), ShouldBeTrue)
+			So(view.Contains(3<<16+4999), ShouldBeTrue)
+			So(view.Contains(3<<16+5000), ShouldBeFalse)
+
+			other := BitmapOf(2, 1<<16, 3<<16+10)
+			and := And(view, other)
+			So(and.GetCardinality(), ShouldEqual, 3)
+
+			view.Add(4)
+			view.Remove(1000)
+			view.RemoveRange(1<<16, 1<<16+300)
+			view.Flip(3<<16, 3<<16+10)
+			view.Or(other)
+			So(bytes.Equal(buf, saved), ShouldBeTrue)
+			So(view.Contains(4), ShouldBeTrue)
+			So(view.Contains(1000), ShouldBeFalse)
+
+			again, err := FromBuffer(buf)
+			So(err, ShouldBeNil)
+			So(again.Equals(orig), ShouldBeTrue)
+		}
+	})
+
+	Convey("FromBuffer should reject malformed buffers", t, func() {
+		buf, err := BitmapOf(1, 2, 3, 1<<20).ToBytes()
+		So(err, ShouldBeNil)
+
+		for i := 0; i < len(buf); i++ {
+			_, err = FromBuffer(buf[:i])
+			So(err, ShouldNotBeNil)
+		}
+
+		bad := make([]byte, len(buf))
+		copy(bad, buf)
+		bad[1] ^= 0xff
+		_, err = FromBuffer(bad)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("FromBuffer should count run containers from their runs, not from the header", t, func() {
+		rb := NewBitmap()
+		rb.AddRange(0, 5000)
+		rb.RunOptimize()
+		buf, err := rb.ToBytes()
+		So(err, ShouldBeNil)
+
+		// cookie, run flags, then the key and the cardinality minus one
+		binary.LittleEndian.PutUint16(buf[7:], 9)
+		view, err := FromBuffer(buf)
+		So(err, ShouldBeNil)
+		So(view.GetCardinality(), ShouldEqual, 5000)
+	})
+}
+
+func TestSerializedSizeWithBitmapContainers054(t *testing.T) {
+	rb := NewBitmap()
+	for i := uint32(0); i < 200000; i += 3 {
+		rb.Add(i)
+	}
+	by, err := rb.ToBytes()
+	if err != nil {
+		t.Fatalf("Failed writing: %v", err)
+	}
+	if uint64(len(by)) != rb.GetSerializedSizeInBytes() {
+		t.Errorf("GetSerializedSizeInBytes is %d, but %d bytes were written", rb.GetSerializedSizeInBytes(), len(by))
+	}
+}
+
+// recordingWriter remembers the size of the largest write, and fails after failAfter bytes if set
+type recordingWriter struct {
+	bytes.Buffer
+	largest   int
+	failAfter int
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.failAfter > 0 && w.Len()+len(p) > w.failAfter {
+		return 0, fmt.Errorf("writer is full")
+	}
+	if len(p) > w.largest {
+		w.largest = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestSerializationStreamingAndAppend055(t *testing.T) {
+
+	Convey("WriteTo should stream containers and AppendBinary should write the same bytes", t, func() {
+		bitmaps := []*Bitmap{NewBitmap(), BitmapOf(1, 2, 3, 1<<20)}
+		rb := NewBitmap()
+		for k := uint64(0); k < 20; k++ {
+			rb.AddRange(k<<16, k<<16+30000) // runs
+			for i := k<<16 + 30000; i < (k+1)<<16; i += 3 {
+				rb.Add(uint32(i)) // bitmaps
+			}
+		}
+		rb.RunOptimize()
+		bitmaps = append(bitmaps, rb)
+
+		for _, bm := range bitmaps {
+			expected, err := bm.ToBytes()
+			So(err, ShouldBeNil)
+			So(len(expected), ShouldEqual, bm.GetSerializedSizeInBytes())
+
+			w := &recordingWriter{}
+			n, err := bm.WriteTo(w)
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, len(expected))
+			So(w.Bytes(), ShouldResemble, expected)
+			if len(expected) > 1<<14 {
+				So(w.largest, ShouldBeLessThanOrEqualTo, 1<<13)
+			}
+
+			prefix := []byte("prefix")
+			out, err := bm.AppendBinary(prefix)
+			So(err, ShouldBeNil)
+			So(out[:len(prefix)], ShouldResemble, prefix)
+			So(out[len(prefix):], ShouldResemble, expected)
+
+			back, err := FromBuffer(out[len(prefix):])
+			So(err, ShouldBeNil)
+			So(back.Equals(bm), ShouldBeTrue)
+
+			dst := make([]byte, 0, bm.GetSerializedSizeInBytes())
+			allocs := testing.AllocsPerRun(10, func() {
+				dst, _ = bm.AppendBinary(dst[:0])
+			})
+			So(dst, ShouldResemble, expected)
+			So(allocs, ShouldEqual, 0)
+		}
+
+		w := &recordingWriter{failAfter: 100}
+		_, err := rb.WriteTo(w)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -3,6 +3,17 @@ package roaring
 type shortIterable interface {
 	hasNext() bool
 	next() uint16
+	// peekNext returns the value next would return, without consuming it
+	peekNext() uint16
+	// advanceIfNeeded skips the values smaller than minval
+	advanceIfNeeded(minval uint16)
+}
+
+// manyIterable decodes the values of a container in chunks
+type manyIterable interface {
+	// nextMany writes the next values, or'ed with hs, to buf and returns
+	// how many it wrote; fewer than len(buf) means the container is done
+	nextMany(hs uint32, buf []uint32) int
 }
 
 type shortIterator struct {
@@ -19,3 +30,60 @@ func (si *shortIterator) next() uint16 {
 	si.loc++
 	return a
 }
+
+func (si *shortIterator) peekNext() uint16 {
+	return si.slice[si.loc]
+}
+
+func (si *shortIterator) advanceIfNeeded(minval uint16) {
+	if si.hasNext() && si.slice[si.loc] < minval {
+		si.loc = advanceUntil(si.slice, si.loc, len(si.slice), minval)
+	}
+}
+
+func (si *shortIterator) nextMany(hs uint32, buf []uint32) int {
+	n := len(si.slice) - si.loc
+	if n > len(buf) {
+		n = len(buf)
+	}
+	for i, v := range si.slice[si.loc : si.loc+n] {
+		buf[i] = hs | uint32(v)
+	}
+	si.loc += n
+	return n
+}
+
+// reverseShortIterable iterates over the values of a container in
+// decreasing order
+type reverseShortIterable interface {
+	hasNext() bool
+	next() uint16
+	// advanceIfNeeded skips the values larger than maxval
+	advanceIfNeeded(maxval uint16)
+}
+
+type reverseIterator struct {
+	slice []uint16
+	loc   int
+}
+
+func (si *reverseIterator) hasNext() bool {
+	return si.loc >= 0
+}
+
+func (si *reverseIterator) next() uint16 {
+	a := si.slice[si.loc]
+	si.loc--
+	return a
+}
+
+func (si *reverseIterator) advanceIfNeeded(maxval uint16) {
+	if si.hasNext() && si.slice[si.loc] > maxval {
+		i := binarySearch(si.slice[:si.loc], maxval)
+		if i < 0 {
+			// the value before the insertion point
+			i = -i - 2
+		}
+		si.loc = i
+	}
+}
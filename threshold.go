@@ -0,0 +1,172 @@
+package roaring
+
+import (
+	"container/heap"
+	"math/bits"
+)
+
+// Threshold computes the values that appear in at least k of the
+// bitmaps. The bitmaps are walked key by key with a priority queue,
+// and only the keys held by at least k bitmaps are counted. Threshold(1, ...)
+// is the union of the bitmaps, Threshold(len(bitmaps), ...) is their
+// intersection, and a k larger than the number of bitmaps gives an
+// empty bitmap. A k of zero or less is treated as 1.
+func Threshold(k int, bitmaps ...*Bitmap) *Bitmap {
+	if k <= 1 {
+		return FastOr(bitmaps...)
+	}
+	if k > len(bitmaps) {
+		return NewBitmap()
+	}
+	if k == len(bitmaps) {
+		return FastAnd(bitmaps...)
+	}
+
+	pq := make(containerPriorityQueue, 0, len(bitmaps))
+	for _, bm := range bitmaps {
+		if !bm.IsEmpty() {
+			pq = append(pq, &containeritem{bm, 0, len(pq)})
+		}
+	}
+	heap.Init(&pq)
+
+	answer := NewBitmap()
+	answer.highlowcontainer.setCardinality(0)
+	counter := newThresholdCounter(len(pq))
+	var popped []*containeritem
+	for pq.Len() >= k {
+		// pop all the containers of the smallest key
+		key := pq[0].value.highlowcontainer.getKeyAtIndex(pq[0].keyindex)
+		popped = popped[:0]
+		for pq.Len() > 0 && pq[0].value.highlowcontainer.getKeyAtIndex(pq[0].keyindex) == key {
+			popped = append(popped, heap.Pop(&pq).(*containeritem))
+		}
+
+		if len(popped) >= k {
+			counter.reset()
+			for _, it := range popped {
+				counter.add(it.value.highlowcontainer.getContainerAtIndex(it.keyindex))
+			}
+			if c := counter.atLeast(k); c != nil {
+				answer.highlowcontainer.appendContainer(key, c, false)
+			}
+		}
+
+		for _, it := range popped {
+			it.keyindex++
+			if it.keyindex < it.value.highlowcontainer.size() {
+				heap.Push(&pq, it)
+			}
+		}
+	}
+	return answer
+}
+
+// thresholdCounter counts, for each of the 1<<16 values of a key, how
+// many containers hold it. The counts are bit-sliced: bit i of the count
+// of value v is bit v of slices[i].
+type thresholdCounter struct {
+	slices  [][]uint64
+	scratch []uint64 // for the runs being added; kept zeroed between uses
+	// the range of words that hold non-zero counts
+	minword, maxword int
+}
+
+// newThresholdCounter returns a counter able to count up to n containers
+func newThresholdCounter(n int) *thresholdCounter {
+	tc := &thresholdCounter{
+		slices:  make([][]uint64, bits.Len(uint(n))),
+		scratch: make([]uint64, 1<<16/64),
+	}
+	for i := range tc.slices {
+		tc.slices[i] = make([]uint64, 1<<16/64)
+	}
+	tc.minword, tc.maxword = len(tc.scratch), -1
+	return tc
+}
+
+func (tc *thresholdCounter) reset() {
+	for _, slice := range tc.slices {
+		for w := tc.minword; w <= tc.maxword; w++ {
+			slice[w] = 0
+		}
+	}
+	tc.minword, tc.maxword = len(tc.scratch), -1
+}
+
+// add increments the counts of the values of c
+func (tc *thresholdCounter) add(c container) {
+	first, last := int(c.minimum())/64, int(c.maximum())/64
+	if first < tc.minword {
+		tc.minword = first
+	}
+	if last > tc.maxword {
+		tc.maxword = last
+	}
+	switch x := c.(type) {
+	case *arrayContainer:
+		for _, v := range x.content {
+			w, bit := v/64, uint64(1)<<(v%64)
+			// ripple the carry up the slices
+			for _, slice := range tc.slices {
+				slice[w] ^= bit
+				if slice[w]&bit != 0 {
+					break
+				}
+			}
+		}
+	case *bitmapContainer:
+		tc.addWords(x.bitmap, first, last)
+	case *runContainer16:
+		for _, iv := range x.iv {
+			setBitmapRange(tc.scratch, int(iv.start), int(iv.last())+1)
+		}
+		tc.addWords(tc.scratch, first, last)
+		for w := first; w <= last; w++ {
+			tc.scratch[w] = 0
+		}
+	}
+}
+
+// addWords increments the counts of the values set in words[first:last+1]
+func (tc *thresholdCounter) addWords(words []uint64, first, last int) {
+	for w := first; w <= last; w++ {
+		carry := words[w]
+		for _, slice := range tc.slices {
+			if carry == 0 {
+				break
+			}
+			slice[w], carry = slice[w]^carry, slice[w]&carry
+		}
+	}
+}
+
+// atLeast returns the container of the values counted at least k
+// times, or nil if there is none
+func (tc *thresholdCounter) atLeast(k int) container {
+	if tc.maxword < 0 {
+		return nil
+	}
+	bc := newBitmapContainer()
+	for w := tc.minword; w <= tc.maxword; w++ {
+		// compare the counts with k from the most significant bit down
+		gt, eq := uint64(0), ^uint64(0)
+		for i := len(tc.slices) - 1; i >= 0; i-- {
+			if k&(1<<uint(i)) != 0 {
+				eq &= tc.slices[i][w]
+			} else {
+				gt |= eq & tc.slices[i][w]
+				eq &^= tc.slices[i][w]
+			}
+		}
+		bc.bitmap[w] = gt | eq
+	}
+	bc.computeCardinality()
+	if bc.cardinality == 0 {
+		return nil
+	}
+	if bc.cardinality <= arrayDefaultMaxSize {
+		return bc.toArrayContainer()
+	}
+	return bc
+}
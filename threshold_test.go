@@ -0,0 +1,57 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestThreshold(t *testing.T) {
+
+	Convey("Threshold should keep the values held by at least k bitmaps", t, func() {
+		rb1 := BitmapOf(1, 2, 3, 1<<16)
+		rb2 := BitmapOf(2, 3, 4, 2<<16)
+		rb3 := BitmapOf(3, 4, 5, 1<<16)
+		So(Threshold(1, rb1, rb2, rb3).ToArray(), ShouldResemble, []uint32{1, 2, 3, 4, 5, 1 << 16, 2 << 16})
+		So(Threshold(2, rb1, rb2, rb3).ToArray(), ShouldResemble, []uint32{2, 3, 4, 1 << 16})
+		So(Threshold(3, rb1, rb2, rb3).ToArray(), ShouldResemble, []uint32{3})
+		So(Threshold(4, rb1, rb2, rb3).IsEmpty(), ShouldBeTrue)
+		So(Threshold(0, rb1).Equals(rb1), ShouldBeTrue)
+		So(Threshold(2).IsEmpty(), ShouldBeTrue)
+		So(Threshold(2, rb1, NewBitmap(), NewBitmap()).IsEmpty(), ShouldBeTrue)
+	})
+
+	Convey("Threshold should match a brute force count", t, func() {
+		r := rand.New(rand.NewSource(10))
+		for _, n := range []int{3, 5, 12} {
+			bitmaps := randomBitmapsForAggregation(r, n)
+			// a few full containers, to get counts up to n
+			for _, bm := range bitmaps[:n/2] {
+				bm.AddRange(5<<16, 6<<16)
+			}
+			clones := make([]*Bitmap, n)
+			counts := make(map[uint32]int)
+			for i, bm := range bitmaps {
+				clones[i] = bm.Clone()
+				for it := bm.Iterator(); it.HasNext(); {
+					counts[it.Next()]++
+				}
+			}
+			for k := 1; k <= n+1; k++ {
+				expect := NewBitmap()
+				for x, count := range counts {
+					if count >= k {
+						expect.Add(x)
+					}
+				}
+				got := Threshold(k, bitmaps...)
+				So(got.Equals(expect), ShouldBeTrue)
+				So(got.Validate(), ShouldBeNil)
+			}
+			for i, bm := range bitmaps {
+				So(bm.Equals(clones[i]), ShouldBeTrue)
+			}
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package roaring
+
+import (
+	"sync/atomic"
+)
+
+// Transaction groups modifications of a Bitmap so that they can be
+// undone together. Before a container is first written, the transaction
+// takes a share of it: the bitmap then copies the container before
+// modifying it, and the transaction keeps the original to restore it on
+// Rollback. Only the touched containers are thus ever copied.
+//
+// While a transaction is open, its bitmap must be modified only through
+// the transaction. A transaction must not be used once committed or
+// rolled back.
+type Transaction struct {
+	rb   *Bitmap
+	undo []undoRecord
+	// levels[0] is the transaction itself, the others its savepoints
+	levels []txLevel
+}
+
+// undoRecord holds the containers of the keys in [first, last] as they
+// were before they were written
+type undoRecord struct {
+	first, last uint16
+	keys        []uint16
+	containers  []container
+}
+
+// txLevel holds the undo records of a savepoint, starting at
+// undo[start], and the keys they have saved one by one
+type txLevel struct {
+	start int
+	saved map[uint16]struct{}
+}
+
+// Savepoint marks a point of a transaction that it can be rolled back to
+type Savepoint int
+
+// Begin starts a transaction on the bitmap
+func (rb *Bitmap) Begin() *Transaction {
+	return &Transaction{
+		rb:     rb,
+		levels: []txLevel{{saved: make(map[uint16]struct{})}},
+	}
+}
+
+// save records the containers of the keys in [first, last], unless
+// they have already been saved since the last savepoint
+func (tx *Transaction) save(first, last uint16) {
+	level := &tx.levels[len(tx.levels)-1]
+	if first == last {
+		if _, ok := level.saved[first]; ok {
+			return
+		}
+		level.saved[first] = struct{}{}
+	}
+	ra := &tx.rb.highlowcontainer
+	i := ra.getIndex(first)
+	if i < 0 {
+		i = -i - 1
+	}
+	rec := undoRecord{first: first, last: last}
+	for ; i < len(ra.keys) && ra.keys[i] <= last; i++ {
+		rec.keys = append(rec.keys, ra.keys[i])
+		rec.containers = append(rec.containers, shareContainer(ra.containers[i]))
+		ra.needCopyOnWrite[i] = true
+	}
+	tx.undo = append(tx.undo, rec)
+}
+
+// saveRange records the containers touched by the range [rangeStart, rangeEnd)
+func (tx *Transaction) saveRange(rangeStart, rangeEnd uint64) {
+	if rangeStart < rangeEnd {
+		tx.save(highbits(uint32(rangeStart)), highbits(uint32(rangeEnd-1)))
+	}
+}
+
+// restore puts back the containers saved by rec, handing them the
+// share that rec holds
+func (tx *Transaction) restore(rec *undoRecord) {
+	ra := &tx.rb.highlowcontainer
+	begin := ra.getIndex(rec.first)
+	if begin < 0 {
+		begin = -begin - 1
+	}
+	end := begin
+	for j := 0; end < len(ra.keys) && ra.keys[end] <= rec.last; end++ {
+		for j < len(rec.keys) && rec.keys[j] < ra.keys[end] {
+			j++
+		}
+		if j < len(rec.keys) && rec.containers[j] == ra.containers[end] {
+			// the bitmap never wrote it: both shares are now one
+			atomic.AddInt32(rec.containers[j].sharesCount(), -1)
+		}
+	}
+	n := len(ra.keys) - (end - begin) + len(rec.keys)
+	keys := make([]uint16, 0, n)
+	containers := make([]container, 0, n)
+	needCopyOnWrite := make([]bool, 0, n)
+	keys = append(append(append(keys, ra.keys[:begin]...), rec.keys...), ra.keys[end:]...)
+	containers = append(append(append(containers, ra.containers[:begin]...), rec.containers...), ra.containers[end:]...)
+	needCopyOnWrite = append(needCopyOnWrite, ra.needCopyOnWrite[:begin]...)
+	for range rec.keys {
+		needCopyOnWrite = append(needCopyOnWrite, true)
+	}
+	needCopyOnWrite = append(needCopyOnWrite, ra.needCopyOnWrite[end:]...)
+	ra.keys, ra.containers, ra.needCopyOnWrite = keys, containers, needCopyOnWrite
+	ra.modCount++
+	ra.rebuildKeyIndex()
+}
+
+// Add adds the integer x to the bitmap
+func (tx *Transaction) Add(x uint32) {
+	tx.save(highbits(x), highbits(x))
+	tx.rb.Add(x)
+}
+
+// AddMany adds all of the values in dat to the bitmap
+func (tx *Transaction) AddMany(dat []uint32) {
+	for i, x := range dat {
+		if i == 0 || highbits(x) != highbits(dat[i-1]) {
+			tx.save(highbits(x), highbits(x))
+		}
+	}
+	tx.rb.AddMany(dat)
+}
+
+// Remove removes the integer x from the bitmap
+func (tx *Transaction) Remove(x uint32) {
+	tx.save(highbits(x), highbits(x))
+	tx.rb.Remove(x)
+}
+
+// AddRange adds the integers in [rangeStart, rangeEnd) to the bitmap
+func (tx *Transaction) AddRange(rangeStart, rangeEnd uint64) {
+	tx.saveRange(rangeStart, rangeEnd)
+	tx.rb.AddRange(rangeStart, rangeEnd)
+}
+
+// RemoveRange removes the integers in [rangeStart, rangeEnd) from the bitmap
+func (tx *Transaction) RemoveRange(rangeStart, rangeEnd uint64) {
+	tx.saveRange(rangeStart, rangeEnd)
+	tx.rb.RemoveRange(rangeStart, rangeEnd)
+}
+
+// Flip negates the bits in the range [rangeStart, rangeEnd) of the bitmap
+func (tx *Transaction) Flip(rangeStart, rangeEnd uint64) {
+	tx.saveRange(rangeStart, rangeEnd)
+	tx.rb.Flip(rangeStart, rangeEnd)
+}
+
+// Savepoint marks the current state of the bitmap, so that RollbackTo
+// can later undo the modifications made after it. Savepoints nest: rolling
+// back to a savepoint also drops the savepoints taken after it.
+func (tx *Transaction) Savepoint() Savepoint {
+	tx.levels = append(tx.levels, txLevel{start: len(tx.undo), saved: make(map[uint16]struct{})})
+	return Savepoint(len(tx.levels) - 1)
+}
+
+// RollbackTo undoes the modifications made since sp was taken. The
+// savepoint remains, and can be rolled back to again.
+func (tx *Transaction) RollbackTo(sp Savepoint) {
+	if sp <= 0 || int(sp) >= len(tx.levels) {
+		panic("invalid savepoint")
+	}
+	tx.rollbackTo(int(sp))
+}
+
+func (tx *Transaction) rollbackTo(level int) {
+	start := tx.levels[level].start
+	for i := len(tx.undo) - 1; i >= start; i-- {
+		tx.restore(&tx.undo[i])
+	}
+	tx.undo = tx.undo[:start]
+	tx.levels = tx.levels[:level+1]
+	tx.levels[level].saved = make(map[uint16]struct{})
+}
+
+// Rollback undoes all of the modifications made in the transaction
+func (tx *Transaction) Rollback() {
+	tx.rollbackTo(0)
+	tx.rb = nil
+}
+
+// Commit keeps the modifications made in the transaction. The shares
+// of the containers the bitmap never wrote are given back, so that it
+// can write them in place again.
+func (tx *Transaction) Commit() {
+	ra := &tx.rb.highlowcontainer
+	for _, rec := range tx.undo {
+		for j, key := range rec.keys {
+			i := ra.getIndex(key)
+			if i >= 0 && ra.containers[i] == rec.containers[j] {
+				atomic.AddInt32(rec.containers[j].sharesCount(), -1)
+			}
+		}
+	}
+	tx.undo = nil
+	tx.rb = nil
+}
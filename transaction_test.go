@@ -0,0 +1,129 @@
+package roaring
+
+import (
+	"math/rand"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// randomTransactionOps applies n random modifications both to tx and to expect
+func randomTransactionOps(r *rand.Rand, tx *Transaction, expect *Bitmap, n int) {
+	for i := 0; i < n; i++ {
+		x := uint64(r.Int63n(8 << 16))
+		y := x + uint64(r.Int63n(3<<16))
+		switch r.Intn(6) {
+		case 0:
+			tx.Add(uint32(x))
+			expect.Add(uint32(x))
+		case 1:
+			tx.Remove(uint32(x))
+			expect.Remove(uint32(x))
+		case 2:
+			dat := []uint32{uint32(x), uint32(x + 1), uint32(y)}
+			tx.AddMany(dat)
+			expect.AddMany(dat)
+		case 3:
+			tx.AddRange(x, y)
+			expect.AddRange(x, y)
+		case 4:
+			tx.RemoveRange(x, y)
+			expect.RemoveRange(x, y)
+		case 5:
+			tx.Flip(x, y)
+			expect.Flip(x, y)
+		}
+	}
+}
+
+func TestTransaction(t *testing.T) {
+
+	Convey("rollback should restore the bitmap", t, func() {
+		rb := iteratorTestBitmap()
+		before := rb.Clone()
+		tx := rb.Begin()
+		tx.Add(17)
+		tx.RemoveRange(0, 1<<16)
+		tx.Flip(10<<16, 12<<16)
+		tx.Remove(1<<16 + 4)
+		So(rb.Contains(10<<16), ShouldBeTrue)
+		tx.Rollback()
+		So(rb.Equals(before), ShouldBeTrue)
+		So(rb.Contains(10<<16), ShouldBeFalse)
+	})
+
+	Convey("commit should keep the modifications", t, func() {
+		rb := iteratorTestBitmap()
+		expect := rb.Clone()
+		tx := rb.Begin()
+		tx.Add(17)
+		tx.Flip(10<<16, 12<<16)
+		tx.Commit()
+		expect.Add(17)
+		expect.Flip(10<<16, 12<<16)
+		So(rb.Equals(expect), ShouldBeTrue)
+		// the containers that were only saved are written in place again
+		for i, c := range rb.highlowcontainer.containers {
+			So(*c.sharesCount(), ShouldEqual, 0)
+			So(rb.highlowcontainer.getWritableContainerAtIndex(i), ShouldEqual, c)
+		}
+	})
+
+	Convey("savepoints should nest", t, func() {
+		rb := iteratorTestBitmap()
+		tx := rb.Begin()
+		tx.Add(17)
+		sp1 := tx.Savepoint()
+		tx.Add(18)
+		at1 := rb.Clone()
+		sp2 := tx.Savepoint()
+		tx.RemoveRange(0, MaxUint32+1)
+		So(rb.IsEmpty(), ShouldBeTrue)
+		tx.RollbackTo(sp2)
+		So(rb.Equals(at1), ShouldBeTrue)
+		tx.Add(19)
+		tx.RollbackTo(sp1)
+		So(rb.Contains(17), ShouldBeTrue)
+		So(rb.Contains(18), ShouldBeFalse)
+		So(func() { tx.RollbackTo(sp2) }, ShouldPanic)
+		tx.Commit()
+		So(rb.Contains(17), ShouldBeTrue)
+	})
+
+	Convey("random transactions should roll back to each savepoint", t, func() {
+		r := rand.New(rand.NewSource(18))
+		for trial := 0; trial < 20; trial++ {
+			rb := NewBitmap()
+			for i := 0; i < 1000; i++ {
+				x := uint64(r.Int63n(8 << 16))
+				rb.AddRange(x, x+uint64(r.Intn(5000)))
+			}
+			rb.RunOptimize()
+			before := rb.Clone()
+			shared := rb.Clone() // shares its containers with rb
+			tx := rb.Begin()
+			expect := before.Clone()
+			randomTransactionOps(r, tx, expect, 10)
+			var sps []Savepoint
+			var states []*Bitmap
+			for level := 0; level < 3; level++ {
+				sps = append(sps, tx.Savepoint())
+				states = append(states, expect.Clone())
+				randomTransactionOps(r, tx, expect, 10)
+				So(rb.Equals(expect), ShouldBeTrue)
+			}
+			for level := 2; level >= 0; level-- {
+				tx.RollbackTo(sps[level])
+				So(rb.Equals(states[level]), ShouldBeTrue)
+			}
+			if trial%2 == 0 {
+				tx.Rollback()
+				So(rb.Equals(before), ShouldBeTrue)
+			} else {
+				tx.Commit()
+				So(rb.Equals(states[0]), ShouldBeTrue)
+			}
+			So(shared.Equals(before), ShouldBeTrue)
+		}
+	})
+}
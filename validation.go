@@ -0,0 +1,270 @@
+package roaring
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// InvalidBitmapError is returned when a serialized bitmap, or a
+// bitmap in memory, breaks one of the invariants of the format.
+type InvalidBitmapError struct {
+	// Container is the index of the offending container,
+	// or -1 if the problem is in the header.
+	Container int
+	// Key holds the high 16 bits of the offending container.
+	Key uint16
+	// Reason describes the problem.
+	Reason string
+}
+
+func (e *InvalidBitmapError) Error() string {
+	if e.Container < 0 {
+		return "invalid bitmap: " + e.Reason
+	}
+	return fmt.Sprintf("invalid bitmap: container %d (key %d): %s", e.Container, e.Key, e.Reason)
+}
+
+// LimitError is returned by ReadFromWithLimits when the input
+// goes beyond one of the ReadLimits.
+type LimitError struct {
+	// Limit is the name of the field of ReadLimits that was exceeded.
+	Limit string
+	// Max is the value of that limit.
+	Max int64
+	// Value is what the input required.
+	Value int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("bitmap exceeds limit %s: %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// ReadLimits bounds the resources used by ReadFromWithLimits.
+// A zero field means no limit beyond those of the format itself.
+type ReadLimits struct {
+	// MaxContainers is the largest number of containers accepted.
+	MaxContainers int
+	// MaxBytes is the largest number of bytes read from the stream.
+	MaxBytes int64
+}
+
+// ReadFromWithLimits reads a serialized version of this bitmap from
+// stream, like ReadFrom, but does not trust its input: it fails rather
+// than allocate more than the limits allow, and checks that the keys are
+// strictly increasing, that the cardinalities in the header match the
+// containers, that array containers are sorted and that runs are sorted
+// and do not overlap. Structural problems are reported as
+// *InvalidBitmapError and exceeded limits as *LimitError. The bitmap
+// is cleared first, and left empty if an error is returned.
+func (rb *Bitmap) ReadFromWithLimits(stream io.Reader, limits ReadLimits) (int64, error) {
+	rb.Clear()
+	n, err := rb.highlowcontainer.readFromWithLimits(stream, limits)
+	if err != nil {
+		rb.Clear()
+	}
+	return n, err
+}
+
+// Validate checks the invariants of the bitmap: keys are strictly
+// increasing, containers are not empty, array containers are sorted,
+// bitmap containers have a correct cardinality and runs are sorted and
+// do not overlap. Containers whose type is not the most compact one, as
+// left behind by some in-place operations, are valid. It returns an *InvalidBitmapError
+// describing the first problem found, or nil.
+func (rb *Bitmap) Validate() error {
+	return rb.highlowcontainer.validate()
+}
+
+func (ra *roaringArray) validate() error {
+	if len(ra.keys) != len(ra.containers) || len(ra.keys) != len(ra.needCopyOnWrite) {
+		return &InvalidBitmapError{Container: -1, Reason: fmt.Sprintf("%d keys for %d containers and %d copy-on-write flags",
+			len(ra.keys), len(ra.containers), len(ra.needCopyOnWrite))}
+	}
+	for i, c := range ra.containers {
+		if i > 0 && ra.keys[i-1] >= ra.keys[i] {
+			return &InvalidBitmapError{Container: i, Key: ra.keys[i],
+				Reason: fmt.Sprintf("key is not greater than the previous key %d", ra.keys[i-1])}
+		}
+		if err := c.validate(); err != nil {
+			return &InvalidBitmapError{Container: i, Key: ra.keys[i], Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (ac *arrayContainer) validate() error {
+	if len(ac.content) == 0 {
+		return fmt.Errorf("array container is empty")
+	}
+	for i := 1; i < len(ac.content); i++ {
+		if ac.content[i-1] >= ac.content[i] {
+			return fmt.Errorf("array container values are not strictly increasing at position %d (%d >= %d)",
+				i, ac.content[i-1], ac.content[i])
+		}
+	}
+	return nil
+}
+
+func (bc *bitmapContainer) validate() error {
+	if len(bc.bitmap) != (1<<16)/64 {
+		return fmt.Errorf("bitmap container has %d words instead of %d", len(bc.bitmap), (1<<16)/64)
+	}
+	card := int(popcntSlice(bc.bitmap))
+	if card != bc.cardinality {
+		return fmt.Errorf("bitmap container cardinality is %d but it holds %d values", bc.cardinality, card)
+	}
+	return nil
+}
+
+func (rc *runContainer16) validate() error {
+	if len(rc.iv) == 0 {
+		return fmt.Errorf("run container is empty")
+	}
+	card := int64(0)
+	for i, iv := range rc.iv {
+		if int(iv.start)+int(iv.length) > MaxUint16 {
+			return fmt.Errorf("run %d starting at %d with length %d goes beyond %d", i, iv.start, iv.length, MaxUint16)
+		}
+		if i > 0 && rc.iv[i-1].last() >= iv.start {
+			return fmt.Errorf("run %d starting at %d does not come after the end %d of the previous run",
+				i, iv.start, rc.iv[i-1].last())
+		}
+		card += iv.runlen()
+	}
+	if rc.card > 0 && rc.card != card {
+		return fmt.Errorf("run container cardinality is %d but it holds %d values", rc.card, card)
+	}
+	return nil
+}
+
+// readFromWithLimits is the validating counterpart of readFrom.
+// Every size read from stream is checked against the limits before
+// anything is allocated for it.
+func (ra *roaringArray) readFromWithLimits(stream io.Reader, limits ReadLimits) (int64, error) {
+	pos := int64(0)
+	// read fills buf from stream, unless that would go beyond limits.MaxBytes
+	read := func(buf []byte) error {
+		if limits.MaxBytes > 0 && pos+int64(len(buf)) > limits.MaxBytes {
+			return &LimitError{Limit: "MaxBytes", Max: limits.MaxBytes, Value: pos + int64(len(buf))}
+		}
+		n, err := io.ReadFull(stream, buf)
+		pos += int64(n)
+		return err
+	}
+	headerError := func(format string, args ...interface{}) error {
+		return &InvalidBitmapError{Container: -1, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	var buf [8]byte
+	if err := read(buf[:4]); err != nil {
+		return pos, err
+	}
+	cookie := binary.LittleEndian.Uint32(buf[:])
+	var size uint32
+	var isRun []byte
+	if cookie&0x0000FFFF == serialCookie {
+		size = uint32(uint16(cookie>>16) + 1)
+		isRun = make([]byte, (size+7)/8)
+		if err := read(isRun); err != nil {
+			return pos, err
+		}
+	} else if cookie == serialCookieNoRunContainer {
+		if err := read(buf[:4]); err != nil {
+			return pos, err
+		}
+		size = binary.LittleEndian.Uint32(buf[:])
+		if size > 1<<16 {
+			return pos, headerError("it is logically impossible to have more than (1<<16) containers, got %d", size)
+		}
+	} else {
+		return pos, headerError("did not find expected serialCookie in header")
+	}
+	if limits.MaxContainers > 0 && int(size) > limits.MaxContainers {
+		return pos, &LimitError{Limit: "MaxContainers", Max: int64(limits.MaxContainers), Value: int64(size)}
+	}
+
+	// descriptive header
+	keycard := make([]byte, 4*size)
+	if err := read(keycard); err != nil {
+		return pos, err
+	}
+	for i := 1; i < int(size); i++ {
+		prev, key := binary.LittleEndian.Uint16(keycard[4*i-4:]), binary.LittleEndian.Uint16(keycard[4*i:])
+		if prev >= key {
+			return pos, &InvalidBitmapError{Container: i, Key: key,
+				Reason: fmt.Sprintf("key is not greater than the previous key %d", prev)}
+		}
+	}
+
+	// offset header
+	var offsets []byte
+	if isRun == nil || size >= noOffsetThreshold {
+		offsets = make([]byte, 4*size)
+		if err := read(offsets); err != nil {
+			return pos, err
+		}
+	}
+
+	ra.keys = make([]uint16, 0, size)
+	ra.containers = make([]container, 0, size)
+	ra.needCopyOnWrite = make([]bool, 0, size)
+	ra.index = nil
+	for i := 0; i < int(size); i++ {
+		key := binary.LittleEndian.Uint16(keycard[4*i:])
+		card := int(binary.LittleEndian.Uint16(keycard[4*i+2:])) + 1
+		containerError := func(format string, args ...interface{}) error {
+			return &InvalidBitmapError{Container: i, Key: key, Reason: fmt.Sprintf(format, args...)}
+		}
+		if offsets != nil {
+			if offset := int64(binary.LittleEndian.Uint32(offsets[4*i:])); offset != pos {
+				return pos, containerError("offset is %d but the container starts at %d", offset, pos)
+			}
+		}
+
+		var c container
+		if isRun != nil && isRun[i/8]&(1<<uint(i%8)) != 0 {
+			if err := read(buf[:2]); err != nil {
+				return pos, err
+			}
+			by := make([]byte, 4*int(binary.LittleEndian.Uint16(buf[:])))
+			if err := read(by); err != nil {
+				return pos, err
+			}
+			rc := &runContainer16{iv: make([]interval16, len(by)/4)}
+			for j := range rc.iv {
+				rc.iv[j] = interval16{
+					start:  binary.LittleEndian.Uint16(by[4*j:]),
+					length: binary.LittleEndian.Uint16(by[4*j+2:]),
+				}
+			}
+			c = rc
+		} else {
+			by := make([]byte, getSizeInBytesFromCardinality(card))
+			if err := read(by); err != nil {
+				return pos, err
+			}
+			if card > arrayDefaultMaxSize {
+				bc := &bitmapContainer{cardinality: card, bitmap: make([]uint64, len(by)/8)}
+				for j := range bc.bitmap {
+					bc.bitmap[j] = binary.LittleEndian.Uint64(by[8*j:])
+				}
+				c = bc
+			} else {
+				ac := &arrayContainer{content: make([]uint16, card)}
+				for j := range ac.content {
+					ac.content[j] = binary.LittleEndian.Uint16(by[2*j:])
+				}
+				c = ac
+			}
+		}
+		if err := c.validate(); err != nil {
+			return pos, containerError("%s", err)
+		}
+		if c.getCardinality() != card {
+			return pos, containerError("header cardinality is %d but the container holds %d values", card, c.getCardinality())
+		}
+		ra.appendContainer(key, c, false)
+	}
+	return pos, nil
+}
@@ -0,0 +1,185 @@
+package roaring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serializedForTest(rb *Bitmap) []byte {
+	buf, err := rb.ToBytes()
+	if err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+func TestReadFromWithLimits(t *testing.T) {
+
+	Convey("ReadFromWithLimits should read what WriteTo wrote", t, func() {
+		rb := BitmapOf(1, 2, 3, 1<<16|7)
+		rb.AddRange(2<<16, 2<<16+10000)
+		for i := uint32(3 << 16); i < 4<<16; i += 3 {
+			rb.Add(i)
+		}
+		for _, runs := range []bool{false, true} {
+			if runs {
+				rb.RunOptimize()
+			}
+			buf := serializedForTest(rb)
+			newrb := BitmapOf(42)
+			n, err := newrb.ReadFromWithLimits(bytes.NewReader(buf), ReadLimits{MaxContainers: 4, MaxBytes: int64(len(buf))})
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, len(buf))
+			So(newrb.Equals(rb), ShouldBeTrue)
+			So(newrb.Validate(), ShouldBeNil)
+		}
+
+		n, err := NewBitmap().ReadFromWithLimits(bytes.NewReader(serializedForTest(NewBitmap())), ReadLimits{})
+		So(err, ShouldBeNil)
+		So(n, ShouldEqual, 8)
+	})
+
+	Convey("ReadFromWithLimits should enforce the limits", t, func() {
+		buf := serializedForTest(BitmapOf(1, 1<<16, 2<<16))
+		newrb := BitmapOf(42)
+		_, err := newrb.ReadFromWithLimits(bytes.NewReader(buf), ReadLimits{MaxContainers: 2})
+		So(err, ShouldResemble, &LimitError{Limit: "MaxContainers", Max: 2, Value: 3})
+		So(newrb.IsEmpty(), ShouldBeTrue)
+
+		_, err = newrb.ReadFromWithLimits(bytes.NewReader(buf), ReadLimits{MaxBytes: int64(len(buf) - 1)})
+		So(err, ShouldResemble, &LimitError{Limit: "MaxBytes", Max: int64(len(buf) - 1), Value: int64(len(buf))})
+		So(newrb.IsEmpty(), ShouldBeTrue)
+
+		// the number of containers is checked before anything is allocated for them
+		var huge bytes.Buffer
+		binary.Write(&huge, binary.LittleEndian, uint32(serialCookieNoRunContainer))
+		binary.Write(&huge, binary.LittleEndian, uint32(60000))
+		_, err = newrb.ReadFromWithLimits(bytes.NewReader(huge.Bytes()), ReadLimits{MaxContainers: 100})
+		So(err, ShouldResemble, &LimitError{Limit: "MaxContainers", Max: 100, Value: 60000})
+	})
+
+	Convey("ReadFromWithLimits should reject malformed input", t, func() {
+		check := func(buf []byte, container int, key uint16) {
+			newrb := BitmapOf(42)
+			_, err := newrb.ReadFromWithLimits(bytes.NewReader(buf), ReadLimits{})
+			So(err, ShouldNotBeNil)
+			ierr, ok := err.(*InvalidBitmapError)
+			So(ok, ShouldBeTrue)
+			So(ierr.Container, ShouldEqual, container)
+			So(ierr.Key, ShouldEqual, key)
+			So(newrb.IsEmpty(), ShouldBeTrue)
+		}
+
+		// cookie, size, key/card pairs and offsets, then the containers
+		buf := serializedForTest(BitmapOf(1, 2, 3, 1<<16|4))
+		bad := append([]byte(nil), buf...)
+		bad[0] ^= 0xff
+		check(bad, -1, 0)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint32(bad[4:], 1<<16+1)
+		check(bad, -1, 0)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[8:], 5) // keys 5, 1
+		check(bad, 1, 1)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint32(bad[20:], 100) // offset of the second container
+		check(bad, 1, 1)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[26:], 0) // values 1, 0, 3
+		check(bad, 0, 0)
+
+		for i := 0; i < len(buf); i++ {
+			_, err := NewBitmap().ReadFromWithLimits(bytes.NewReader(buf[:i]), ReadLimits{})
+			So(err, ShouldNotBeNil)
+		}
+
+		// a bitmap container whose cardinality does not match its content
+		rb := NewBitmap()
+		for i := uint32(0); i < 5000; i++ {
+			rb.Add(1<<16 | 2*i)
+		}
+		buf = serializedForTest(rb)
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[10:], 5999)
+		check(bad, 0, 1)
+
+		// cookie and isRun bitset, key/card pair, then numRuns and (start, length) pairs
+		rb = NewBitmap()
+		rb.AddRange(10, 1000)
+		rb.AddRange(2000, 3000)
+		rb.RunOptimize()
+		buf = serializedForTest(rb)
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[7:], 2000) // header cardinality
+		check(bad, 0, 0)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[15:], 500) // second run starts within the first
+		check(bad, 0, 0)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[15:], MaxUint16-5) // second run goes beyond 65535
+		check(bad, 0, 0)
+
+		bad = append([]byte(nil), buf...)
+		binary.LittleEndian.PutUint16(bad[9:], 0) // no runs at all
+		check(bad, 0, 0)
+	})
+}
+
+func TestValidate(t *testing.T) {
+
+	Convey("Validate should accept the bitmaps produced by the operations", t, func() {
+		a := NewBitmap()
+		for i := uint32(0); i < 30000; i += 3 {
+			a.Add(i) // a bitmap container
+		}
+		b := NewBitmap()
+		b.AddRange(20000, 50000)
+		b.RunOptimize()
+		// FastOr keeps the first container of a, then ors the run of b into it
+		union := FastOr(a, BitmapOf(1<<20), b)
+		So(union.Validate(), ShouldBeNil)
+		So(union.GetCardinality(), ShouldEqual, Or(Or(a, b), BitmapOf(1<<20)).GetCardinality())
+
+		for _, rb := range []*Bitmap{NewBitmap(), a, b, And(a, b), Xor(a, b), AndNot(a, b), HeapOr(a, b), Flip(a, 10, 100000)} {
+			So(rb.Validate(), ShouldBeNil)
+		}
+		c := a.Clone()
+		c.AndNot(b)
+		So(c.Validate(), ShouldBeNil)
+	})
+
+	Convey("Validate should report corrupted bitmaps", t, func() {
+		rb := BitmapOf(1, 2, 3, 1<<16|4)
+		rb.highlowcontainer.containers[0].(*arrayContainer).content[1] = 5
+		So(rb.Validate(), ShouldResemble, &InvalidBitmapError{Container: 0, Key: 0,
+			Reason: "array container values are not strictly increasing at position 2 (5 >= 3)"})
+
+		rb = BitmapOf(1, 1<<16|4)
+		rb.highlowcontainer.keys[1] = 0
+		err := rb.Validate()
+		So(err, ShouldNotBeNil)
+		So(err.(*InvalidBitmapError).Container, ShouldEqual, 1)
+
+		rb = NewBitmap()
+		for i := uint32(0); i < 10000; i += 2 {
+			rb.Add(i)
+		}
+		rb.highlowcontainer.containers[0].(*bitmapContainer).cardinality++
+		So(rb.Validate(), ShouldNotBeNil)
+
+		rb = NewBitmap()
+		rb.AddRange(0, 10000)
+		rb.RunOptimize()
+		rb.highlowcontainer.containers[0].(*runContainer16).iv = nil
+		So(rb.Validate(), ShouldResemble, &InvalidBitmapError{Container: 0, Key: 0, Reason: "run container is empty"})
+	})
+}